@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/user/gocrawler/pkg/crawler"
+)
+
+// Prints one line per URL, "crawl" or "skip (reason)", then the totals
+func printDryRun(w io.Writer, decisions []crawler.Decision) {
+	skipped := make(map[string]int)
+	crawled := 0
+	for _, decision := range decisions {
+		kind := "link"
+		if decision.Seed {
+			kind = "seed"
+		}
+		if decision.Reason == "" {
+			crawled++
+			fmt.Fprintf(w, "crawl\t%s\t%s\n", kind, decision.URL)
+			continue
+		}
+		skipped[decision.Reason]++
+		fmt.Fprintf(w, "skip (%s)\t%s\t%s\n", decision.Reason, kind, decision.URL)
+	}
+
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	fmt.Fprintf(w, "\nDry run: %d of %d URLs would be crawled", crawled, len(decisions))
+	for i, reason := range reasons {
+		separator := ", "
+		if i == 0 {
+			separator = "; skipped: "
+		}
+		fmt.Fprintf(w, "%s%d %s", separator, skipped[reason], reason)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/user/gocrawler/pkg/estimate"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Prints the cost estimate of the planned crawl. Sources are sitemaps (files
+// or URLs ending in .xml/.xml.gz, or any http(s) URL) and json/ndjson output
+// of earlier crawls, which also provide page sizes.
+func printEstimate(plan estimate.Plan, sources []string, userAgent string) error {
+	var in estimate.Input
+	var err error
+	in.URLs, in.PageSizes, err = loadKnownURLs(sources, plan.Timeout, userAgent)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Dry run: nothing will be fetched")
+	estimate.Compute(plan, in).Print(os.Stdout)
+	return nil
+}
+
+// Reads page URLs from sitemaps and earlier crawl output, along with the
+// sizes of pages the output recorded
+func loadKnownURLs(sources []string, timeout time.Duration, userAgent string) ([]string, []int64, error) {
+	var urls []string
+	var sizes []int64
+	client := &http.Client{Timeout: timeout}
+
+	for _, source := range sources {
+		lower := strings.ToLower(source)
+		if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") ||
+			strings.HasSuffix(lower, ".xml") || strings.HasSuffix(lower, ".xml.gz") {
+			sitemapURLs, err := estimate.LoadSitemap(source, client, userAgent)
+			if err != nil {
+				return nil, nil, err
+			}
+			urls = append(urls, sitemapURLs...)
+			continue
+		}
+
+		records, err := storage.ReadRecords(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, record := range records {
+			urls = append(urls, record.URL)
+			if record.Size > 0 {
+				sizes = append(sizes, record.Size)
+			}
+		}
+	}
+	return urls, sizes, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/user/gocrawler/pkg/extracteval"
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// Scores the parser's content extraction against a directory of fixtures:
+// page.html files, each with the content it should yield in page.txt
+func runExtractEval(args []string) {
+	fs := flag.NewFlagSet("extract-eval", flag.ExitOnError)
+	newsOnly := fs.Bool("news", false, "Extract news article content, as with crawl -news")
+	var selectFlags stringList
+	fs.Var(&selectFlags, "select", "CSS selector field as with crawl -select; content=selector replaces the built-in extraction (repeatable)")
+	baseURL := fs.String("base-url", "https://example.com/", "URL the fixtures are parsed as if fetched from")
+	minF1 := fs.Float64("min-f1", 0, "Exit with status 1 when the mean F1 is below this, e.g. to catch regressions in CI")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler extract-eval [flags] <fixtures dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var selectors []parser.Selector
+	for _, spec := range selectFlags {
+		selector, err := parser.ParseSelector(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid -select: %v\n", err)
+			os.Exit(1)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	fixtures, unscored, err := extracteval.LoadFixtures(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, path := range unscored {
+		fmt.Fprintf(os.Stderr, "Warning: %s has no expected .txt, skipping\n", path)
+	}
+	if len(fixtures) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no fixtures in %s\n", fs.Arg(0))
+		os.Exit(1)
+	}
+
+	opts := parser.Options{NewsOnly: *newsOnly, Selectors: selectors}
+	report := extracteval.Run(fixtures, func(html string) (string, error) {
+		result, err := parser.ParseWithOptions(context.Background(), html, *baseURL, opts)
+		if err != nil {
+			return "", err
+		}
+		return result.Content, nil
+	})
+	report.Print(os.Stdout)
+
+	if report.Failed > 0 || report.Macro.F1 < *minF1 {
+		os.Exit(1)
+	}
+}
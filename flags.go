@@ -0,0 +1,378 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/gocrawler/pkg/crawler"
+	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/recipe"
+	"github.com/user/gocrawler/pkg/urllist"
+)
+
+// Accepts Go duration strings ("500ms", "30s") as well as bare integers,
+// which are read as seconds for backward compatibility
+type durationValue time.Duration
+
+func durationFlag(fs *flag.FlagSet, name string, value time.Duration, usage string) *time.Duration {
+	d := value
+	fs.Var((*durationValue)(&d), name, usage)
+	return &d
+}
+
+func (d *durationValue) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *durationValue) Set(s string) error {
+	if seconds, err := strconv.Atoi(s); err == nil {
+		*d = durationValue(time.Duration(seconds) * time.Second)
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = durationValue(parsed)
+	return nil
+}
+
+// -delay-jitter: an extra delay of up to a duration ("2s"), a range the
+// delay is picked from ("1s-3s"), or a percentage of the delay either way
+// ("30%")
+type jitterValue struct {
+	extra    time.Duration
+	min, max time.Duration
+	fraction float64
+}
+
+func (j *jitterValue) String() string {
+	switch {
+	case j.fraction > 0:
+		return strconv.FormatFloat(j.fraction*100, 'f', -1, 64) + "%"
+	case j.max > 0:
+		return j.min.String() + "-" + j.max.String()
+	default:
+		return j.extra.String()
+	}
+}
+
+func (j *jitterValue) Set(s string) error {
+	*j = jitterValue{}
+	if percent, ok := strings.CutSuffix(s, "%"); ok {
+		value, err := strconv.ParseFloat(percent, 64)
+		if err != nil || value < 0 || value > 100 {
+			return fmt.Errorf("invalid percentage %q (want 0%% to 100%%)", s)
+		}
+		j.fraction = value / 100
+		return nil
+	}
+
+	if low, high, ok := strings.Cut(s, "-"); ok {
+		var min, max durationValue
+		if err := min.Set(low); err != nil {
+			return err
+		}
+		if err := max.Set(high); err != nil {
+			return err
+		}
+		if min > max {
+			return fmt.Errorf("invalid range %q: %v is above %v", s, time.Duration(min), time.Duration(max))
+		}
+		j.min, j.max = time.Duration(min), time.Duration(max)
+		return nil
+	}
+
+	var extra durationValue
+	if err := extra.Set(s); err != nil {
+		return err
+	}
+	j.extra = time.Duration(extra)
+	return nil
+}
+
+// Accepts byte sizes with optional binary units ("512KB", "5MB", "1.5GB");
+// bare integers are read as bytes
+type sizeValue int64
+
+func sizeFlag(fs *flag.FlagSet, name string, value int64, usage string) *int64 {
+	n := value
+	fs.Var((*sizeValue)(&n), name, usage)
+	return &n
+}
+
+func (s *sizeValue) String() string {
+	return formatSize(int64(*s))
+}
+
+func (s *sizeValue) Set(str string) error {
+	n, err := parseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = sizeValue(n)
+	return nil
+}
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+func parseSize(str string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(str))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(upper, unit.suffix) {
+			multiplier = unit.multiplier
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(upper, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%dKB", n>>10)
+	default:
+		return strconv.FormatInt(n, 10)
+	}
+}
+
+// A flag that may be repeated, collecting every value
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// Builds the per-host credential map from -auth and -auth-token values
+func parseCredentials(basic, tokens []string) (map[string]crawler.Credentials, error) {
+	credentials := make(map[string]crawler.Credentials)
+
+	for _, entry := range basic {
+		host, userInfo, ok := strings.Cut(entry, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("expected host=user:password, got %q", entry)
+		}
+		username, password, _ := strings.Cut(userInfo, ":")
+		credentials[strings.ToLower(host)] = crawler.Credentials{Username: username, Password: password}
+	}
+
+	for _, entry := range tokens {
+		host, token, ok := strings.Cut(entry, "=")
+		if !ok || host == "" || token == "" {
+			return nil, fmt.Errorf("expected host=token, got %q", entry)
+		}
+		credentials[strings.ToLower(host)] = crawler.Credentials{Token: token}
+	}
+
+	return credentials, nil
+}
+
+// Combines -rotate-agent values with the lines of the agents file, skipping
+// blank lines and # comments
+func loadUserAgents(agents []string, filename string) ([]string, error) {
+	userAgents := append([]string(nil), agents...)
+	if filename == "" {
+		return userAgents, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			userAgents = append(userAgents, line)
+		}
+	}
+	return userAgents, nil
+}
+
+// Checks the -from address and -crawl-info-url sent to the sites being crawled
+func validateIdentity(from, infoURL string) error {
+	if from != "" {
+		if _, err := mail.ParseAddress(from); err != nil {
+			return fmt.Errorf("-from %q: %w", from, err)
+		}
+	}
+	if infoURL != "" {
+		u, err := url.Parse(infoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("-crawl-info-url %q: expected an absolute http(s) URL", infoURL)
+		}
+	}
+	return nil
+}
+
+// Flags whose values must never be written to run metadata
+var secretFlags = map[string]bool{
+	"auth":          true,
+	"auth-token":    true,
+	"anonymize-key": true,
+}
+
+// Captures the effective value of every flag for the run metadata
+func configSnapshot(fs *flag.FlagSet) map[string]string {
+	snapshot := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretFlags[f.Name] && value != "" {
+			value = "<redacted>"
+		}
+		snapshot[f.Name] = value
+	})
+	return snapshot
+}
+
+func newRunID() string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return time.Now().UTC().Format("20060102T150405Z")
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
+
+// Splits a comma-separated flag value. Returns nil when the flag was not
+// given, so callers can fall back to defaults, and an empty list when it was
+// explicitly set to "".
+func listFlagValue(fs *flag.FlagSet, name, value string) []string {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	if !set {
+		return nil
+	}
+
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Reads the settings file given to -reload-file
+func readReloadFile(path string) (crawler.Reload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return crawler.Reload{}, fmt.Errorf("failed to read reload file: %w", err)
+	}
+	return crawler.ParseReload(data)
+}
+
+// Flags a recipe may not set, since running a shared recipe file must not
+// run commands or send crawl data elsewhere; they still work on the command
+// line
+var recipeRefusedFlags = map[string]bool{
+	"exec":          true,
+	"script":        true,
+	"sink":          true,
+	"quota-webhook": true,
+}
+
+// Flags naming files or directories to write, which a recipe may only set
+// to paths inside the working directory
+var recipePathFlags = map[string]bool{
+	"output":         true,
+	"edges":          true,
+	"seo-audit":      true,
+	"redirect-audit": true,
+	"host-info":      true,
+	"alias-map":      true,
+	"pagerank":       true,
+	"rejected-log":   true,
+	"raw-html-dir":   true,
+	"save-assets":    true,
+	"http-cache":     true,
+	"robots-cache":   true,
+	"record":         true,
+	"seen-db":        true,
+}
+
+// Sets the flags a recipe gives values, except those given on the command
+// line
+func applyRecipe(fs *flag.FlagSet, r *recipe.Recipe) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for _, setting := range r.Settings() {
+		if explicit[setting.Flag] {
+			continue
+		}
+		if fs.Lookup(setting.Flag) == nil {
+			return fmt.Errorf("unknown flag -%s", setting.Flag)
+		}
+		if recipeRefusedFlags[setting.Flag] {
+			return fmt.Errorf("-%s can only be given on the command line", setting.Flag)
+		}
+		if recipePathFlags[setting.Flag] && setting.Value != "" && !filepath.IsLocal(setting.Value) {
+			return fmt.Errorf("-%s %q: recipes may only write inside the working directory", setting.Flag, setting.Value)
+		}
+		if err := fs.Set(setting.Flag, setting.Value); err != nil {
+			return fmt.Errorf("-%s: %w", setting.Flag, err)
+		}
+	}
+	return nil
+}
+
+// Loads an -allowlist or -denylist file, if given, and adds a recipe's
+// entries to it; nil when there are neither
+func loadURLList(filename string, entries []string) (frontier.URLMatcher, error) {
+	if filename == "" && len(entries) == 0 {
+		return nil, nil
+	}
+
+	list := urllist.New()
+	if filename != "" {
+		var err error
+		if list, err = urllist.Load(filename); err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range entries {
+		if err := list.Add(entry); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
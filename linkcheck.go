@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Crawls a site saving an error record for every link that fails, then lists
+// them. Exits with status 1 when there are broken links, for use in CI.
+func runLinkcheck(args []string) {
+	fs := flag.NewFlagSet("linkcheck", flag.ExitOnError)
+	depth := fs.Int("depth", 3, "Maximum link depth to check")
+	maxPages := fs.Int("max", 1000, "Maximum number of pages to check")
+	workers := fs.Int("workers", 4, "Number of concurrent workers")
+	delay := durationFlag(fs, "delay", time.Second, "Delay between requests to the same host (e.g. 500ms, 2s; bare numbers are seconds)")
+	external := fs.Bool("external", true, "Also check links to other sites, without crawling them")
+	output := fs.String("output", "linkcheck.ndjson", "NDJSON file to save every checked page to, broken ones with their error")
+	userAgent := fs.String("agent", "GoCrawler/1.0", "User-Agent string")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler linkcheck [flags] <url>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	crawlArgs := []string{
+		"-seed", fs.Arg(0),
+		"-depth", strconv.Itoa(*depth),
+		"-max", strconv.Itoa(*maxPages),
+		"-workers", strconv.Itoa(*workers),
+		"-delay", delay.String(),
+		"-agent", *userAgent,
+		"-extract-links",
+		"-extract", "links",
+		"-error-records",
+		"-format", "ndjson",
+		"-output", *output,
+	}
+	if *external {
+		crawlArgs = append(crawlArgs, "-external-depth", "1")
+	}
+	runCrawl("crawl", crawlArgs)
+
+	records, err := storage.ReadRecords(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	broken := 0
+	for _, record := range records {
+		if record.Error == "" {
+			continue
+		}
+		if broken == 0 {
+			fmt.Println("\nBroken links (linked from, link, error):")
+		}
+		broken++
+		from := record.ParentURL
+		if from == "" {
+			from = "(seed)"
+		}
+		fmt.Printf("%s\t%s\t%s\n", from, record.URL, record.Error)
+	}
+
+	fmt.Printf("%d broken links in %d checked pages\n", broken, len(records))
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
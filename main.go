@@ -1,86 +1,931 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
+	"github.com/user/gocrawler/pkg/cassette"
 	"github.com/user/gocrawler/pkg/crawler"
+	"github.com/user/gocrawler/pkg/dedup"
+	"github.com/user/gocrawler/pkg/estimate"
 	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/httpcache"
+	"github.com/user/gocrawler/pkg/keywords"
+	"github.com/user/gocrawler/pkg/parser"
+	"github.com/user/gocrawler/pkg/queryparams"
+	"github.com/user/gocrawler/pkg/recipe"
+	"github.com/user/gocrawler/pkg/robotstxt"
+	"github.com/user/gocrawler/pkg/script"
+	"github.com/user/gocrawler/pkg/seeds"
+	"github.com/user/gocrawler/pkg/seendb"
+	"github.com/user/gocrawler/pkg/sitekey"
 	"github.com/user/gocrawler/pkg/storage"
+	"github.com/user/gocrawler/pkg/tagging"
+	"github.com/user/gocrawler/pkg/urlvalidate"
 )
 
+// Overridden at build time with -ldflags "-X main.version=..."
+var version = "1.0.0"
+
 func main() {
-	seedURL := flag.String("seed", "", "Seed URL to start crawling from (required)")
-	outputFile := flag.String("output", "results.json", "Output file name")
-	outputFormat := flag.String("format", "json", "Output format: json or csv")
-	workerCount := flag.Int("workers", 2, "Number of concurrent workers")
-	depth := flag.Int("depth", 1, "Maximum crawl depth")
-	delay := flag.Int("delay", 1, "Delay between requests in seconds")
-	timeout := flag.Int("timeout", 10, "Request timeout in seconds")
-	respectRobots := flag.Bool("robots", true, "Respect robots.txt")
-	newsOnly := flag.Bool("news", false, "Extract only news article content")
-	maxPages := flag.Int("max", 20, "Maximum number of pages to crawl")
-	userAgent := flag.String("agent", "GoCrawler/1.0", "User-Agent string")
-	verbose := flag.Bool("verbose", false, "Verbose output")
-	stayOnDomain := flag.Bool("stay-domain", true, "Stay on the same domain as the seed URL")
-	urlFilter := flag.String("filter", "", "Only crawl URLs containing this string (e.g., '/wiki/')")
-	seedOnly := flag.Bool("seed-only", false, "Crawl only the seed URL, don't follow any links")
-	extractLinks := flag.Bool("extract-links", false, "Extract links from crawled pages")
-
-	flag.Parse()
-
-	if *seedURL == "" {
-		fmt.Println("Error: seed URL is required")
-		flag.Usage()
+	// Without a subcommand, the arguments are crawl flags
+	command, args := "crawl", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "crawl", "resume":
+		runCrawl(command, args)
+	case "linkcheck":
+		runLinkcheck(args)
+	case "sitemap":
+		runSitemap(args)
+	case "stats":
+		runStats(args)
+	case "robots-check":
+		runRobotsCheck(args)
+	case "report":
+		runReport(args)
+	case "extract-eval":
+		runExtractEval(args)
+	case "serve":
+		runServe(args)
+	case "search":
+		runSearch(args)
+	case "help":
+		printUsage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		printUsage(os.Stderr)
+		os.Exit(2)
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, `Usage: gocrawler <command> [flags] [arguments]
+
+Commands:
+  crawl        Crawl from seed URLs (the default when no command is given)
+  resume       Continue an earlier crawl from its output
+  linkcheck    Crawl a site and report its broken links
+  sitemap      Crawl a site and write an XML sitemap
+  robots-check Show whether a robots.txt allows URLs, and which rule decides
+  stats        Summarize a crawl's output: status codes, content types, errors and hosts
+  report       Break a crawl's output down by depth and section
+  extract-eval Score content extraction against HTML fixtures with expected text
+  search       Query a search index written with -format bleve
+  serve        Run the crawl daemon
+
+Run "gocrawler <command> -h" for a command's flags.
+`)
+}
+
+// Runs a crawl, or with command "resume" continues the crawl whose output is
+// given as the argument
+func runCrawl(command string, args []string) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.Usage = func() {
+		if command == "resume" {
+			fmt.Fprintln(fs.Output(), "Usage: gocrawler resume [flags] <results.json>...")
+		} else {
+			fmt.Fprintln(fs.Output(), "Usage: gocrawler [crawl] [flags]")
+		}
+		fs.PrintDefaults()
+	}
+
+	seedURL := fs.String("seed", "", "Seed URL to start crawling from (required unless -seeds is given)")
+	seedsFile := fs.String("seeds", "", "File of seed URLs with optional per-seed overrides (depth=, filter=, tag=)")
+	outputFile := fs.String("output", "results.json", "Output file name, or - to stream records to stdout (ndjson unless -format is given) with logs on stderr")
+	outputFormat := fs.String("format", "json", "Output format: json, ndjson, csv, xml, html (standalone report), sitemap, graph or bleve (full-text search index)")
+	shardRecords := fs.Int("shard-records", 0, "Start a new numbered output file every N records, e.g. results-0001.json (json, ndjson and csv; 0 = single file)")
+	shardSize := sizeFlag(fs, "shard-size", 0, "Start a new numbered output file once the current one reaches this size, e.g. 100MB (0 = no limit)")
+	csvColumns := fs.String("csv-columns", "", "Comma-separated CSV columns to write, in order (default: all; see the README for names)")
+	csvExclude := fs.String("csv-exclude", "", "Comma-separated CSV columns to leave out, e.g. Content,RawHTML")
+	csvLinks := fs.String("csv-links", storage.LinksJSON, "How CSV output encodes links: json (array in one cell), comma (comma-joined) or edges (separate <output>-links.csv)")
+	edgesFile := fs.String("edges", "", "Also write every link to this CSV edge file (from_url, to_url, anchor_text, rel, depth), for any -format")
+	seoAudit := fs.String("seo-audit", "", "Write an SEO audit CSV (issue, url, detail) of duplicate titles and descriptions, missing titles, overlong descriptions and missing h1s to this file (implies -headings)")
+	seoMaxDescription := fs.Int("seo-max-description", storage.DefaultMaxDescription, "Meta descriptions longer than this many characters are reported as overlong by -seo-audit")
+	pageRank := fs.String("pagerank", "", "Score every page by PageRank and in-links over the crawled link graph and write the scores to this CSV file (url, pagerank, inlinks) when the crawl ends")
+	redirectAudit := fs.String("redirect-audit", "", "Write a redirect audit CSV (issue, url, detail) of redirect loops, long redirect chains, https to http redirects and canonical URLs on the other scheme or another host to this file (implies -error-records)")
+	redirectMaxHops := fs.Int("redirect-max-hops", 1, "Redirect chains with more than this many redirects are reported as long by -redirect-audit")
+	compress := fs.String("compress", "none", "Compress the output file: none, gzip or zstd (adds .gz/.zst to the output name)")
+	anonymize := fs.Bool("anonymize", false, "Replace URLs and domains with hashed identifiers and drop page text, keeping the link graph and metadata (json, ndjson, csv and graph)")
+	anonymizeKey := fs.String("anonymize-key", "", "Secret key for -anonymize identifiers; reuse it to get the same identifiers across runs (default: random per run)")
+	allowlistFile := fs.String("allowlist", "", "File of URLs allowed to be crawled: exact URLs, prefixes ending in * or re:regexes, one per line; everything else is skipped")
+	denylistFile := fs.String("denylist", "", "File of URLs never to crawl, even through redirects: exact URLs, prefixes ending in * or re:regexes, one per line")
+	maxURLLength := fs.Int("max-url-length", 0, "Skip URLs longer than this many characters (0 = no limit)")
+	maxQueryParams := fs.Int("max-query-params", 0, "Skip URLs with more than this many query parameters (0 = no limit)")
+	maxPathRepeats := fs.Int("max-path-repeats", 0, "Skip URLs in which any path segment appears more than this many times (0 = no limit)")
+	queryRulesFile := fs.String("query-rules", "", "File of per-host query parameter rules (host, strip/keep/sort, parameters) applied to URLs before they are queued")
+	stripParams := fs.String("strip-params", "", "Comma-separated query parameters to remove from every URL; utm_* matches a prefix, and tracking and session stand for common tracking and session ID parameters")
+	sortParams := fs.Bool("sort-params", false, "Order every URL's query parameters by name, so links differing only in parameter order are one URL")
+	sampleOutput := fs.Int("sample-output", 0, "Also write N random full records to <output>-sample and leave page content out of the main output (json, ndjson and csv)")
+	var sinkFlags stringList
+	fs.Var(&sinkFlags, "sink", "Additional output as format:target written alongside -output, e.g. ndjson:results.ndjson or webhook:https://host/hook (repeatable)")
+	asyncQueue := fs.Int("async-writes", 0, "Save records on a background writer with a queue of this many records, so slow outputs don't hold up fetching (0 = save synchronously)")
+	asyncBatch := fs.Int("async-batch", 100, "Largest batch of queued records the background writer saves at once; csv output only, other outputs save records one by one")
+	asyncOverflow := fs.String("async-overflow", storage.OverflowBlock, "What to do when the write queue is full: block (slow the crawl down) or drop (discard and count the record)")
+	execCommand := fs.String("exec", "", "Shell command run for every record with the record's JSON on stdin, e.g. 'jq -r .title >> titles.txt'")
+	execOutput := fs.String("exec-output", storage.ExecNotify, "What to do with the -exec command's output: notify (pass it through to stdout) or filter (save the JSON record it prints instead; no output drops the record)")
+	execFailure := fs.String("exec-failure", storage.ExecWarn, "What to do when the -exec command fails: warn (log and save the record), drop (log and leave the record out) or stop (stop the crawl)")
+	execConcurrency := fs.Int("exec-concurrency", 4, "Maximum number of -exec commands running at once")
+	execTimeout := durationFlag(fs, "exec-timeout", 30*time.Second, "Kill an -exec command that runs longer than this (0 = no limit)")
+	sitemapBaseURL := fs.String("sitemap-base-url", "", "Public URL the sitemap files will be served from, used in sitemap indexes")
+	workerCount := fs.Int("workers", 2, "Number of concurrent workers")
+	parseWorkers := fs.Int("parse-workers", 0, "Parse pages on this many workers of their own so fetch workers keep fetching (0 = parse on the fetch workers)")
+	parseQueue := fs.Int("parse-queue", 0, "With -parse-workers, fetched pages that may wait for a parse worker before fetching pauses (default: twice -parse-workers)")
+	frontierBatch := fs.Int("frontier-batch", 1, "URLs each worker takes from the frontier at a time; larger batches mean less lock contention with many workers")
+	depth := fs.Int("depth", 1, "Maximum crawl depth")
+	delay := durationFlag(fs, "delay", time.Second, "Delay between requests to the same host (e.g. 500ms, 2s; bare numbers are seconds)")
+	timeout := durationFlag(fs, "timeout", 10*time.Second, "Request timeout (e.g. 30s; bare numbers are seconds)")
+	maxBody := sizeFlag(fs, "max-body", 10<<20, "Maximum response body size to read (e.g. 512KB, 5MB)")
+	respectRobots := fs.Bool("robots", true, "Respect robots.txt")
+	robotsCacheDir := fs.String("robots-cache", "", "Directory to keep fetched robots.txt rules in, so runs within -robots-cache-ttl don't fetch them again")
+	robotsCacheTTL := durationFlag(fs, "robots-cache-ttl", 24*time.Hour, "How long robots.txt rules are used before being fetched again")
+	robotsCacheSize := fs.Int("robots-cache-size", robotstxt.DefaultMaxEntries, "Maximum number of hosts whose robots.txt rules are kept in memory, least recently used evicted first (0 = no limit)")
+	robotsInheritApex := fs.Bool("robots-inherit-apex", false, "Apply the apex domain's robots.txt to subdomains whose own robots.txt is missing (404)")
+	newsOnly := fs.Bool("news", false, "Extract only news article content")
+	maxPages := fs.Int("max", 20, "Maximum number of pages to crawl")
+	maxFetches := fs.Int("max-fetches", 0, "Maximum number of fetch attempts, failures and retries included (0 = no limit)")
+	hostMaxRequests := fs.Int("host-max-requests", 0, "Stop fetching from a host after this many requests; the crawl goes on with the other hosts (0 = no limit)")
+	hostMaxBytes := sizeFlag(fs, "host-max-bytes", 0, "Stop fetching from a host after downloading this much from it, e.g. 50MB (0 = no limit)")
+	quotaWebhook := fs.String("quota-webhook", "", "URL to POST a JSON notice to when a host reaches -host-max-requests or -host-max-bytes")
+	userAgent := fs.String("agent", "GoCrawler/1.0", "User-Agent string")
+	from := fs.String("from", "", "Contact e-mail sent as the From header with every request, so site owners can reach you")
+	crawlInfoURL := fs.String("crawl-info-url", "", "URL describing the crawl, added to the User-Agent as \"(+URL)\"")
+	var rotateAgentFlags stringList
+	fs.Var(&rotateAgentFlags, "rotate-agent", "User-Agent to rotate through (repeatable); robots.txt is still checked for -agent")
+	agentsFile := fs.String("agents-file", "", "File with one User-Agent per line to rotate through")
+	rotatePer := fs.String("rotate-per", "host", "User-Agent rotation: host (same agent per host) or request")
+	accept := fs.String("accept", "", "Accept header to send")
+	var acceptLanguageFlags stringList
+	fs.Var(&acceptLanguageFlags, "accept-language", "Accept-Language value; repeat to pick one at random per request")
+	var delayJitter jitterValue
+	fs.Var(&delayJitter, "delay-jitter", "Randomize the per-host delay: up to this much extra (2s), a range to pick from that replaces -delay (1s-3s) or a percentage either way (30%)")
+	estimateOnly := fs.Bool("estimate", false, "Print the estimated pages, duration and transfer of the crawl and exit without crawling")
+	dryRun := fs.Bool("dry-run", false, "Print whether each seed and -dry-run-from URL would be crawled or why it would be skipped (robots.txt, scope, filters, skip rules) and exit without fetching pages")
+	var dryRunFromFlags stringList
+	fs.Var(&dryRunFromFlags, "dry-run-from", "Sitemap (file or URL) or earlier json/ndjson output whose URLs -dry-run checks as if linked from a seed (repeatable)")
+	var estimateFromFlags stringList
+	fs.Var(&estimateFromFlags, "estimate-from", "Sitemap (file or URL) or earlier json/ndjson output to base -estimate on (repeatable)")
+	planOnly := fs.Bool("plan", false, "Fetch only the seed pages and their /sitemap.xml, print the hosts, URL patterns and estimated cost of the crawl in scope and exit")
+	sendReferer := fs.Bool("referer", true, "Send the page a URL was found on as the Referer header (not from https to http pages)")
+	verbose := fs.Bool("verbose", false, "Verbose output")
+	stayOnDomain := fs.Bool("stay-domain", true, "Stay on the same domain as the seed URL")
+	externalHosts := fs.String("external-hosts", "", "Comma-separated hosts, with their subdomains, whose links are followed as if on-site, e.g. docs.example.org,cdn.example.net")
+	externalDepth := fs.Int("external-depth", 0, "Follow off-site links this many hops past the last on-site page, even with -stay-domain; 1 fetches linked external pages but nothing beyond (0 = -stay-domain decides)")
+	siteGroupingFlag := fs.String("site-grouping", "host", "What counts as one site for -stay-domain, per-host delays, limits and breakers and external-domain stats: host, or domain to group subdomains by registered domain (eTLD+1)")
+	urlFilter := fs.String("filter", "", "Only crawl URLs containing this string (e.g., '/wiki/')")
+	seedOnly := fs.Bool("seed-only", false, "Crawl only the seed URL, don't follow any links")
+	extractLinks := fs.Bool("extract-links", false, "Extract links from crawled pages")
+	extractLevel := fs.String("extract", parser.LevelFull, "How much of each HTML page to extract: links (title and links only, with a fast streaming tokenizer; enables -extract-links), meta (also description, author, canonical and other metadata) or full (also the content)")
+	scriptLinks := fs.Bool("script-links", false, "Also extract URL literals from inline scripts and onclick-style handlers (enables -extract-links)")
+	extractForms := fs.Bool("forms", false, "Record each page's forms (action, method, field names and types)")
+	followForms := fs.Bool("follow-forms", false, "Also crawl GET form actions submitted with empty fields (enables -extract-links)")
+	extractHeadings := fs.Bool("headings", false, "Record each page's h1-h6 outline and heading counts per level")
+	rawHTMLMode := fs.String("raw-html", "off", "Include the raw body of HTML pages in each record: off, inline or gzip (gzip+base64)")
+	rawHTMLDirPath := fs.String("raw-html-dir", "", "Directory to save raw HTML bodies to, one file per page named by the SHA-256 of its URL")
+	quality := fs.Bool("quality", false, "Record content-quality signals: word count, text/HTML ratio, link count and boilerplate percentage")
+	keywordList := fs.String("keywords", "", "Comma-separated keywords or phrases to score pages by (title matches count 5x)")
+	keywordsFile := fs.String("keywords-file", "", "File with one keyword or phrase per line to score pages by")
+	keywordPriority := fs.Bool("keyword-priority", false, "Crawl links from higher-scoring pages first (focused crawling; needs -keywords or -keywords-file)")
+	externalDomains := fs.Bool("external-domains", false, "Record the distinct external domains each page links to (enables -extract-links)")
+	hreflang := fs.String("hreflang", "", "Only follow this language's variants of pages with hreflang alternates, e.g. en or en-GB, skipping links to other languages' variants (implies -extract-links)")
+	ampMode := fs.String("amp", "crawl", "AMP pages: crawl (save both copies, recording the amp/canonical link), skip (don't crawl AMP versions) or canonical (crawl an AMP page's canonical page instead)")
+	omitLinks := fs.Bool("omit-links", false, "Leave page links out of the output; they are still followed")
+	autoScale := fs.Bool("autoscale", false, "Adjust the worker count at runtime based on backlog, error rate and bandwidth")
+	minWorkers := fs.Int("min-workers", 1, "Minimum number of workers when autoscaling")
+	maxWorkers := fs.Int("max-workers", 10, "Maximum number of workers when autoscaling")
+	maxErrorRate := fs.Float64("max-error-rate", 0.5, "Error rate (0-1) above which autoscaling retires workers")
+	maxBandwidth := sizeFlag(fs, "max-bandwidth", 0, "Bandwidth per second above which autoscaling retires workers, e.g. 2MB (0 = unlimited)")
+	bandwidthLimit := sizeFlag(fs, "bandwidth-limit", 0, "Cap on response bytes read per second across all workers, e.g. 2MB (0 = unlimited)")
+	hostBandwidthLimit := sizeFlag(fs, "host-bandwidth-limit", 0, "Cap on response bytes read per second from any one host, e.g. 500KB (0 = unlimited)")
+	breakerThreshold := fs.Int("breaker-threshold", 5, "Pause a host after this many consecutive timeouts or 5xx responses (0 = never)")
+	rampUp := durationFlag(fs, "ramp-up", 0, "Start with one worker and a slow per-host rate, reaching -workers and -delay over this long, e.g. 10m (0 = start at full speed)")
+	rampMaxErrorRate := fs.Float64("ramp-max-error-rate", 0.2, "Stop ramping up, staying at the current rate, when more than this share of requests during -ramp-up fail (0 = never)")
+	blockThreshold := fs.Int("block-threshold", 3, "Treat a host as blocking the crawl after this many consecutive 403, 429 or CAPTCHA/challenge responses: pause it and slow down (0 = off)")
+	retries := fs.Int("retries", 2, "Retry URLs that failed with a timeout, connection error, 5xx, 429 or block up to this many times (0 = never)")
+	errorRecords := fs.Bool("error-records", false, "Save a record for every URL that failed for good, with its error, error class, status code, attempts and parent URL")
+	retryDelay := durationFlag(fs, "retry-delay", 5*time.Second, "Wait before the first retry of a failed URL, doubling for each further retry; a longer Retry-After is honored")
+	blockPause := durationFlag(fs, "block-pause", 5*time.Minute, "How long to pause a host found blocking the crawl; a longer Retry-After is honored")
+	crawlWindow := fs.String("crawl-window", "", "Only fetch during this daily time window, e.g. 22:00-06:00, pausing outside it (default: any time)")
+	crawlWindowTZ := fs.String("crawl-window-tz", "", "Time zone of -crawl-window, e.g. Europe/Berlin (default: local time)")
+	breakerCooldown := durationFlag(fs, "breaker-cooldown", time.Minute, "How long to pause a host whose circuit opened before probing it again")
+	var authFlags, tokenFlags stringList
+	fs.Var(&authFlags, "auth", "Basic auth credentials as host=user:password, used to retry 401/403 responses (repeatable)")
+	fs.Var(&tokenFlags, "auth-token", "Bearer token as host=token, used to retry 401/403 responses (repeatable)")
+	runID := fs.String("run-id", "", "Identifier stamped on every record and the run metadata (default: generated)")
+	parseXML := fs.Bool("parse-xml", false, "Follow URLs found in XML sitemaps and RSS/Atom feeds")
+	parsePDF := fs.Bool("pdf", false, "Extract text, title and author from PDF documents instead of skipping them")
+	parseJSON := fs.Bool("json", false, "Store JSON documents such as OpenAPI specs instead of skipping them")
+	maxJSONSize := sizeFlag(fs, "max-json-size", 1<<20, "Maximum size of stored pretty-printed JSON content (e.g. 256KB)")
+	assetDir := fs.String("save-assets", "", "Directory to save fetched images to (disabled when empty)")
+	skipExtensions := fs.String("skip-ext", "", "Comma-separated file extensions to skip, replacing the defaults (e.g. '.zip,.mp4')")
+	skipPatterns := fs.String("skip-pattern", "", "Comma-separated URL substrings to skip, replacing the defaults (e.g. '/wp-admin/,logout')")
+	rejectedLog := fs.String("rejected-log", "", "File to log every rejected URL with its reason (tab-separated)")
+	var tagRuleFlags stringList
+	fs.Var(&tagRuleFlags, "tag-rule", "Tag URLs matching a pattern as pattern=tag, e.g. '/blog/*=blog' (repeatable)")
+	routeByTag := fs.Bool("route-by-tag", false, "Write tagged pages to a separate output file per tag (e.g. results-blog.json)")
+	seenDBPath := fs.String("seen-db", "", "Database of URLs fetched in earlier runs; already-fetched pages are skipped")
+	sitemapLastmod := fs.Bool("sitemap-lastmod", true, "With -seen-db and -parse-xml, skip sitemap URLs whose lastmod is older than their last fetch and refetch changed ones")
+	httpCacheDir := fs.String("http-cache", "", "Directory to cache responses in, honouring Cache-Control and Expires, so repeated runs are served from disk")
+	recordFile := fs.String("record", "", "Save every HTTP request and response, robots.txt included, to this cassette file for -replay")
+	replayFile := fs.String("replay", "", "Answer requests from a cassette written by -record instead of the network; unrecorded URLs fail")
+	reloadFile := fs.String("reload-file", "", "JSON file of settings (delay, workers, filter, verbose) re-read and applied on SIGHUP without restarting the crawl")
+	httpCacheTTL := durationFlag(fs, "http-cache-ttl", 0, "With -http-cache, treat cached responses as fresh for at least this long whatever their headers say, e.g. 24h (0 = headers decide)")
+	revisitAfter := durationFlag(fs, "revisit-after", 0, "Refetch pages from the seen database after this long, e.g. 168h (0 = never)")
+	dedupFlag := fs.String("dedup", strings.Join(dedup.DefaultStrategies, ","), "Comma-separated duplicate definitions: exact-url, normalized-url, canonical, content-hash, simhash, aliases")
+	aliasMap := fs.String("alias-map", "", "Write each URL found to redirect to, or name as rel=canonical, another page to this tab-separated file (alias, canonical, redirect|canonical); implies -dedup aliases")
+	urlValidation := fs.String("url-validation", "off", "RFC 3986 link validation: off, repair or strict")
+	technologies := fs.Bool("technologies", false, "Detect the CMS, frameworks, analytics and servers behind every page from its headers, cookies, meta generator, scripts and markup")
+	social := fs.Bool("social", false, "Record links to social media profiles (X/Twitter, LinkedIn, Facebook, Instagram, YouTube), normalized, in each page's social_profiles")
+	hostInfoFile := fs.String("host-info", "", "Write each host's IPs, Server and X-Powered-By headers, CDN/WAF, HTTP version and TLS details, from its first response, to this JSON file")
+	discoverHosts := fs.Bool("discover-hosts", false, "Only record the unique hostnames found in links (subdomain inventory)")
+	var selectFlags stringList
+	fs.Var(&selectFlags, "select", "Extract a field with a CSS selector, as name=selector or name=selector@attr; title, description, author and content replace the built-in values (repeatable)")
+	recipeName := fs.String("recipe", "", "Load seeds, scope, rate limits, selectors and output settings from a recipe: a name in -recipes or a YAML file; flags given explicitly win")
+	recipesDir := fs.String("recipes", "recipes", "Directory of recipe YAML files")
+	listRecipes := fs.Bool("list-recipes", false, "List the recipes in -recipes and exit")
+	scriptFile := fs.String("script", "", "Starlark file whose process(page) function runs on every record before it is saved, to change it, add fields or drop it")
+
+	fs.Parse(args)
+
+	var previous []storage.PageData
+	if command == "resume" {
+		if fs.NArg() == 0 {
+			fs.Usage()
+			os.Exit(1)
+		}
+		var err error
+		previous, err = loadPreviousRun(fs, fs.Args())
+		if err != nil {
+			log.Fatalf("Failed to resume: %v", err)
+		}
+	}
+
+	if *listRecipes {
+		recipes, err := recipe.List(*recipesDir)
+		if err != nil {
+			log.Fatalf("Failed to list recipes: %v", err)
+		}
+		for _, r := range recipes {
+			fmt.Printf("%-20s %s\n", r.Name, r.Description)
+		}
+		return
+	}
+
+	var siteRecipe *recipe.Recipe
+	if *recipeName != "" {
+		var err error
+		siteRecipe, err = recipe.Load(*recipesDir, *recipeName)
+		if err != nil {
+			log.Fatalf("Failed to load recipe: %v", err)
+		}
+		if err := applyRecipe(fs, siteRecipe); err != nil {
+			log.Fatalf("Invalid recipe %s: %v", siteRecipe.Name, err)
+		}
+	}
+	useRecipeSeeds := *seedURL == "" && *seedsFile == "" && siteRecipe != nil && len(siteRecipe.Seeds) > 0
+
+	// With -output -, records own stdout and everything else printed goes
+	// to stderr, so the crawl can be piped into other tools
+	results := os.Stdout
+	toStdout := *outputFile == storage.Stdout
+	if toStdout {
+		os.Stdout = os.Stderr
+		formatSet := false
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == "format" {
+				formatSet = true
+			}
+		})
+		if !formatSet {
+			*outputFormat = "ndjson"
+		}
+		if *compress != "none" || *routeByTag || *sampleOutput > 0 {
+			log.Fatalf("-compress, -route-by-tag and -sample-output need an output file, not -output -")
+		}
+	}
+
+	if *seedURL == "" && *seedsFile == "" && !useRecipeSeeds {
+		fmt.Println("Error: seed URL or seeds file is required")
+		fs.Usage()
 		os.Exit(1)
 	}
 
-	var store storage.Storage
-	var err error
-	switch *outputFormat {
-	case "json":
-		store, err = storage.NewJSONStorage(*outputFile)
-	case "csv":
-		store, err = storage.NewCSVStorage(*outputFile)
+	var seedList []seeds.Seed
+	if *seedsFile != "" {
+		var err error
+		seedList, err = seeds.Load(*seedsFile)
+		if err != nil {
+			log.Fatalf("Failed to load seeds: %v", err)
+		}
+	}
+	if useRecipeSeeds {
+		for _, line := range siteRecipe.Seeds {
+			seed, err := seeds.ParseLine(line)
+			if err != nil {
+				log.Fatalf("Invalid seed in recipe %s: %v", siteRecipe.Name, err)
+			}
+			seedList = append(seedList, seed)
+		}
+	}
+
+	var selectors []parser.Selector
+	for _, spec := range selectFlags {
+		selector, err := parser.ParseSelector(spec)
+		if err != nil {
+			log.Fatalf("Invalid -select: %v", err)
+		}
+		selectors = append(selectors, selector)
+	}
+
+	if *discoverHosts {
+		*extractLinks = true
+		*stayOnDomain = true
+	}
+
+	if *outputFormat == "graph" {
+		*extractLinks = true
+	}
+
+	if *externalDomains || *scriptLinks || *followForms || *hreflang != "" || *extractLevel == parser.LevelLinks {
+		*extractLinks = true
+	}
+
+	if *seoAudit != "" {
+		*extractHeadings = true
+	}
+	if *redirectAudit != "" {
+		*errorRecords = true
+	}
+
+	if *omitLinks && (*outputFormat == "graph" || *discoverHosts) {
+		log.Fatalf("-omit-links can't be used with graph output or -discover-hosts, which are built from links")
+	}
+
+	if *runID == "" {
+		*runID = newRunID()
+	}
+
+	validationMode, err := urlvalidate.ParseMode(*urlValidation)
+	if err != nil {
+		log.Fatalf("Invalid -url-validation: %v", err)
+	}
+
+	siteGrouping, err := sitekey.Parse(*siteGroupingFlag)
+	if err != nil {
+		log.Fatalf("Invalid -site-grouping: %v", err)
+	}
+
+	userAgents, err := loadUserAgents(rotateAgentFlags, *agentsFile)
+	if err != nil {
+		log.Fatalf("Failed to load user agents: %v", err)
+	}
+	if err := validateIdentity(*from, *crawlInfoURL); err != nil {
+		log.Fatalf("Invalid %v", err)
+	}
+	if *rotatePer != crawler.RotatePerHost && *rotatePer != crawler.RotatePerRequest {
+		log.Fatalf("Invalid -rotate-per %q: expected host or request", *rotatePer)
+	}
+	if !parser.IsLevel(*extractLevel) {
+		log.Fatalf("Invalid -extract %q: expected links, meta or full", *extractLevel)
+	}
+	if *ampMode != crawler.AMPCrawl && *ampMode != crawler.AMPSkip && *ampMode != crawler.AMPCanonical {
+		log.Fatalf("Invalid -amp %q: expected crawl, skip or canonical", *ampMode)
+	}
+
+	dedupStrategies, err := dedup.ParseStrategies(*dedupFlag)
+	if err != nil {
+		log.Fatalf("Invalid -dedup: %v", err)
+	}
+	if *aliasMap != "" {
+		dedupStrategies[dedup.Aliases] = true
+	}
+
+	fingerprint := crawler.Fingerprint{Accept: *accept, AcceptLanguages: acceptLanguageFlags}
+	switch {
+	case delayJitter.fraction > 0:
+		fingerprint.DelayJitter = 0
+		fingerprint.DelayJitterFraction = delayJitter.fraction
+	case delayJitter.max > 0:
+		*delay = delayJitter.min
+		fingerprint.DelayJitter = delayJitter.max - delayJitter.min
+	case delayJitter.extra > 0:
+		fingerprint.DelayJitter = delayJitter.extra
+	}
+
+	seedURLs := []string{*seedURL}
+	if len(seedList) > 0 {
+		seedURLs = seedURLs[:0]
+		for _, seed := range seedList {
+			seedURLs = append(seedURLs, seed.URL)
+		}
+	}
+	costPages := *maxPages
+	if *seedOnly {
+		costPages = len(seedURLs)
+	}
+	plan := estimate.Plan{
+		MaxPages:    costPages,
+		Workers:     *workerCount,
+		Delay:       *delay,
+		DelayJitter: fingerprint.DelayJitter,
+		Timeout:     *timeout,
+		MaxBody:     *maxBody,
+		SeedURLs:    seedURLs,
+		Sites:       siteGrouping,
+	}
+
+	if *estimateOnly {
+		if err := printEstimate(plan, estimateFromFlags, *userAgent); err != nil {
+			log.Fatalf("Failed to estimate crawl: %v", err)
+		}
+		return
+	}
+
+	var window crawler.Window
+	if *crawlWindow != "" {
+		loc := time.Local
+		if *crawlWindowTZ != "" {
+			loc, err = time.LoadLocation(*crawlWindowTZ)
+			if err != nil {
+				log.Fatalf("Invalid -crawl-window-tz: %v", err)
+			}
+		}
+		window, err = crawler.ParseWindow(*crawlWindow, loc)
+		if err != nil {
+			log.Fatalf("Invalid -crawl-window: %v", err)
+		}
+	}
+
+	switch *rawHTMLMode {
+	case "off", crawler.RawHTMLInline, crawler.RawHTMLGzip:
 	default:
-		fmt.Printf("Unsupported output format: %s, defaulting to JSON\n", *outputFormat)
-		store, err = storage.NewJSONStorage(*outputFile)
+		log.Fatalf("Invalid -raw-html %q: expected off, inline or gzip", *rawHTMLMode)
+	}
+	rawHTML := *rawHTMLMode
+	if rawHTML == "off" {
+		rawHTML = ""
+	}
+
+	var rawHTMLDir *storage.RawHTMLDir
+	if *rawHTMLDirPath != "" {
+		rawHTMLDir, err = storage.NewRawHTMLDir(*rawHTMLDirPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize raw HTML directory: %v", err)
+		}
+	}
+
+	keywordTerms := listFlagValue(fs, "keywords", *keywordList)
+	if *keywordsFile != "" {
+		terms, err := keywords.Load(*keywordsFile)
+		if err != nil {
+			log.Fatalf("Failed to load keywords: %v", err)
+		}
+		keywordTerms = append(keywordTerms, terms...)
+	}
+	keywordScorer := keywords.New(keywordTerms)
+	if *keywordPriority && keywordScorer.Empty() {
+		log.Fatalf("-keyword-priority needs -keywords or -keywords-file")
 	}
 
+	credentials, err := parseCredentials(authFlags, tokenFlags)
 	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err)
+		log.Fatalf("Invalid credentials: %v", err)
+	}
+
+	var tagRules tagging.Rules
+	for _, ruleFlag := range tagRuleFlags {
+		rule, err := tagging.ParseRule(ruleFlag)
+		if err != nil {
+			log.Fatalf("Invalid tag rule: %v", err)
+		}
+		tagRules = append(tagRules, rule)
+	}
+
+	format := *outputFormat
+	if *discoverHosts {
+		format = "hosts"
+	} else if !storage.IsFormat(format) {
+		fmt.Printf("Unsupported output format: %s, defaulting to JSON\n", format)
+		format = "json"
+	}
+
+	*outputFile, err = storage.CompressedFilename(*outputFile, *compress)
+	if err != nil {
+		log.Fatalf("Invalid -compress: %v", err)
+	}
+
+	// -dry-run and -plan exit without crawling, so leave an existing log alone
+	var rejectedWriter io.Writer
+	if *rejectedLog != "" && !*dryRun && !*planOnly {
+		rejectedFile, err := os.Create(*rejectedLog)
+		if err != nil {
+			log.Fatalf("Failed to create rejected-URL log: %v", err)
+		}
+		defer rejectedFile.Close()
+		rejectedWriter = rejectedFile
 	}
-	defer store.Close()
 
 	urlFrontier := frontier.NewURLFrontier()
-	urlFrontier.Add(*seedURL, 0)
+	urlFrontier.SetNormalizedDedup(dedupStrategies[dedup.NormalizedURL])
+	urlFrontier.SetPrioritized(*keywordPriority)
+	urlFrontier.SetURLLimits(frontier.URLLimits{
+		MaxLength:      *maxURLLength,
+		MaxParams:      *maxQueryParams,
+		MaxSegmentRuns: *maxPathRepeats,
+	})
+
+	var recipeAllow, recipeDeny []string
+	if siteRecipe != nil {
+		recipeAllow, recipeDeny = siteRecipe.Scope.Allow, siteRecipe.Scope.Deny
+	}
+	allowlist, err := loadURLList(*allowlistFile, recipeAllow)
+	if err != nil {
+		log.Fatalf("Failed to load allowlist: %v", err)
+	}
+	denylist, err := loadURLList(*denylistFile, recipeDeny)
+	if err != nil {
+		log.Fatalf("Failed to load denylist: %v", err)
+	}
+	urlFrontier.SetURLLists(allowlist, denylist)
+
+	var queryRules *queryparams.Policy
+	if *queryRulesFile != "" {
+		queryRules, err = queryparams.Load(*queryRulesFile)
+		if err != nil {
+			log.Fatalf("Failed to load query parameter rules: %v", err)
+		}
+	} else if *stripParams != "" || *sortParams {
+		queryRules, _ = queryparams.New(nil)
+	}
+	if *stripParams != "" {
+		if err := queryRules.Add(queryparams.Rule{Host: "*", Action: queryparams.Strip, Params: queryparams.ParseList(*stripParams)}); err != nil {
+			log.Fatalf("Invalid -strip-params: %v", err)
+		}
+	}
+	if *sortParams {
+		queryRules.Add(queryparams.Rule{Host: "*", Action: queryparams.Sort})
+	}
+	if queryRules != nil {
+		urlFrontier.SetURLRewriter(queryRules)
+	}
+	urlFrontier.SetSiteGrouping(siteGrouping)
+	urlFrontier.SetMaxAttempts(*retries + 1)
+
+	var seenDB *seendb.DB
+	if *seenDBPath != "" {
+		seenDB, err = seendb.Open(*seenDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open seen database: %v", err)
+		}
+		defer seenDB.Close()
+		urlFrontier.SetSeenStore(seenDB, *revisitAfter)
+	}
+
+	robotsCache := robotstxt.NewRobotsCache(*robotsCacheTTL)
+	if *robotsCacheDir != "" {
+		robotsCache, err = robotstxt.NewDiskCache(*robotsCacheDir, *robotsCacheTTL)
+		if err != nil {
+			log.Fatalf("Failed to open robots cache: %v", err)
+		}
+	}
+	robotsCache.SetMaxEntries(*robotsCacheSize)
+
+	var httpCache *httpcache.Cache
+	if *httpCacheDir != "" {
+		httpCache, err = httpcache.Open(*httpCacheDir, *httpCacheTTL)
+		if err != nil {
+			log.Fatalf("Failed to open HTTP cache: %v", err)
+		}
+	}
+	if *recordFile != "" && *replayFile != "" {
+		log.Fatal("-record and -replay cannot be used together")
+	}
+	var recorder *cassette.Recorder
+	if *recordFile != "" {
+		recorder, err = cassette.Create(*recordFile)
+		if err != nil {
+			log.Fatalf("Failed to start recording: %v", err)
+		}
+	}
+	var player *cassette.Player
+	if *replayFile != "" {
+		player, err = cassette.Load(*replayFile)
+		if err != nil {
+			log.Fatalf("Failed to load cassette: %v", err)
+		}
+	}
+	// A resumed crawl doesn't refetch pages the earlier run saved, seeds
+	// included; pages that failed are tried again
+	for _, record := range previous {
+		if record.Error == "" {
+			urlFrontier.MarkVisited(record.URL)
+		}
+	}
+	if *seedURL != "" {
+		if reason := urlFrontier.Add(*seedURL, 0); reason != frontier.Accepted && !(previous != nil && reason == frontier.RejectDuplicate) {
+			fmt.Printf("Seed %s not queued: %s\n", *seedURL, reason)
+		}
+	}
+	for _, seed := range seedList {
+		if reason := urlFrontier.AddItem(frontier.URLItem{URL: seed.URL, Depth: 0, SeedTag: seed.Tag}); reason != frontier.Accepted && !(previous != nil && reason == frontier.RejectDuplicate) {
+			fmt.Printf("Seed %s not queued: %s\n", seed.URL, reason)
+		}
+	}
 
 	crawlerConfig := crawler.Config{
-		MaxDepth:      *depth,
-		WorkerCount:   *workerCount,
-		Delay:         time.Duration(*delay) * time.Second,
-		Timeout:       time.Duration(*timeout) * time.Second,
-		MaxPages:      *maxPages,
-		RespectRobots: *respectRobots,
-		UserAgent:     *userAgent,
-		NewsOnly:      *newsOnly,
-		Verbose:       *verbose,
-		StayOnDomain:  *stayOnDomain,
-		URLFilter:     *urlFilter,
-		SeedOnly:      *seedOnly,
-		ExtractLinks:  *extractLinks,
+		MaxDepth:           *depth,
+		WorkerCount:        *workerCount,
+		ParseWorkers:       *parseWorkers,
+		ParseQueueSize:     *parseQueue,
+		FrontierBatch:      *frontierBatch,
+		Delay:              *delay,
+		Timeout:            *timeout,
+		MaxBodySize:        *maxBody,
+		MaxPages:           *maxPages,
+		MaxFetches:         *maxFetches,
+		HostMaxRequests:    *hostMaxRequests,
+		HostMaxBytes:       *hostMaxBytes,
+		RespectRobots:      *respectRobots,
+		RobotsInheritApex:  *robotsInheritApex,
+		RobotsCache:        robotsCache,
+		UserAgent:          *userAgent,
+		UserAgents:         userAgents,
+		UserAgentRotation:  *rotatePer,
+		From:               *from,
+		CrawlInfoURL:       *crawlInfoURL,
+		Fingerprint:        fingerprint,
+		SendReferer:        *sendReferer,
+		NewsOnly:           *newsOnly,
+		Verbose:            *verbose,
+		StayOnDomain:       *stayOnDomain,
+		ExternalHosts:      listFlagValue(fs, "external-hosts", *externalHosts),
+		ExternalDepth:      *externalDepth,
+		SiteGrouping:       siteGrouping,
+		URLFilter:          *urlFilter,
+		SeedOnly:           *seedOnly,
+		ExtractLinks:       *extractLinks,
+		ExtractLevel:       *extractLevel,
+		ScriptLinks:        *scriptLinks,
+		Forms:              *extractForms,
+		FollowForms:        *followForms,
+		Headings:           *extractHeadings,
+		RawHTML:            rawHTML,
+		RawHTMLDir:         rawHTMLDir,
+		Quality:            *quality,
+		Keywords:           keywordScorer,
+		KeywordPriority:    *keywordPriority,
+		ExternalDomains:    *externalDomains,
+		Language:           strings.ToLower(*hreflang),
+		AMP:                *ampMode,
+		Selectors:          selectors,
+		OmitLinks:          *omitLinks,
+		ParseXML:           *parseXML,
+		ParsePDF:           *parsePDF,
+		ParseJSON:          *parseJSON,
+		MaxJSONSize:        int(*maxJSONSize),
+		AssetDir:           *assetDir,
+		SkipExtensions:     listFlagValue(fs, "skip-ext", *skipExtensions),
+		SkipPatterns:       listFlagValue(fs, "skip-pattern", *skipPatterns),
+		Seeds:              seedList,
+		Credentials:        credentials,
+		RunID:              *runID,
+		RejectedLog:        rejectedWriter,
+		TagRules:           tagRules,
+		SeenDB:             seenDB,
+		SitemapLastmod:     *sitemapLastmod,
+		HTTPCache:          httpCache,
+		Record:             recorder,
+		Replay:             player,
+		HostInfo:           *hostInfoFile != "",
+		Technologies:       *technologies,
+		Social:             *social,
+		URLValidation:      validationMode,
+		Dedup:              dedupStrategies,
+		AutoScale:          *autoScale,
+		MinWorkers:         *minWorkers,
+		MaxWorkers:         *maxWorkers,
+		MaxErrorRate:       *maxErrorRate,
+		MaxBandwidth:       *maxBandwidth,
+		BreakerThreshold:   *breakerThreshold,
+		BreakerCooldown:    *breakerCooldown,
+		Window:             window,
+		RampUp:             *rampUp,
+		RampMaxErrorRate:   *rampMaxErrorRate,
+		BlockThreshold:     *blockThreshold,
+		BlockPause:         *blockPause,
+		MaxAttempts:        *retries + 1,
+		RetryDelay:         *retryDelay,
+		ErrorRecords:       *errorRecords,
+		BandwidthLimit:     *bandwidthLimit,
+		HostBandwidthLimit: *hostBandwidthLimit,
+	}
+
+	var quotaNotifier *quotaHook
+	if *quotaWebhook != "" {
+		quotaNotifier = newQuotaHook(*quotaWebhook, *runID)
+		crawlerConfig.OnEvent = quotaNotifier.onEvent
+	}
+
+	if *dryRun {
+		known, _, err := loadKnownURLs(dryRunFromFlags, *timeout, *userAgent)
+		if err != nil {
+			log.Fatalf("Failed to load -dry-run-from URLs: %v", err)
+		}
+		printDryRun(os.Stdout, crawler.New(crawlerConfig, urlFrontier, nil).DryRun(known))
+		return
+	}
+
+	if *planOnly {
+		if err := printPlan(os.Stdout, crawler.New(crawlerConfig, urlFrontier, nil), plan, estimateFromFlags, *userAgent); err != nil {
+			log.Fatalf("Failed to plan crawl: %v", err)
+		}
+		return
+	}
+
+	storageOptions := storage.Options{
+		SitemapBaseURL: *sitemapBaseURL,
+		Stdout:         results,
+		ShardRecords:   *shardRecords,
+		ShardBytes:     *shardSize,
+		Compress:       *compress,
+		CSV: storage.CSVOptions{
+			Columns: listFlagValue(fs, "csv-columns", *csvColumns),
+			Exclude: listFlagValue(fs, "csv-exclude", *csvExclude),
+			Links:   *csvLinks,
+		},
+	}
+	store, err := storage.Open(format, *outputFile, storageOptions)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	if *routeByTag {
+		store = storage.NewTagRouter(store, func(tag string) (storage.Storage, error) {
+			return storage.Open(format, taggedFilename(*outputFile, tag), storageOptions)
+		})
+	}
+
+	var multiStore *storage.MultiStorage
+	if len(sinkFlags) > 0 {
+		sinks := []storage.Sink{{Name: format + ":" + *outputFile, Storage: store}}
+		for _, sinkFlag := range sinkFlags {
+			sinkFormat, target, ok := strings.Cut(sinkFlag, ":")
+			if !ok || target == "" || !storage.IsFormat(sinkFormat) {
+				log.Fatalf("Invalid -sink %q: expected format:target with a known format", sinkFlag)
+			}
+
+			sink, err := storage.Open(sinkFormat, target, storageOptions)
+			if err != nil {
+				log.Fatalf("Failed to initialize sink %s: %v", sinkFlag, err)
+			}
+			sinks = append(sinks, storage.Sink{Name: sinkFlag, Storage: sink})
+		}
+		multiStore = storage.NewMultiStorage(sinks...)
+		store = multiStore
+	}
+
+	if *edgesFile != "" {
+		if format == "csv" && *csvLinks == storage.LinksEdges {
+			log.Fatalf("-edges can't be combined with -csv-links edges, which already writes an edge file")
+		}
+
+		edgesName, err := storage.CompressedFilename(*edgesFile, *compress)
+		if err != nil {
+			log.Fatalf("Invalid -compress: %v", err)
+		}
+		store, err = storage.NewEdgeStorage(store, edgesName)
+		if err != nil {
+			log.Fatalf("Failed to initialize edge file: %v", err)
+		}
+	}
+
+	var rankStore *storage.PageRankStorage
+	if *pageRank != "" {
+		var err error
+		rankStore, err = storage.NewPageRankStorage(store, *pageRank)
+		if err != nil {
+			log.Fatalf("Failed to initialize PageRank file: %v", err)
+		}
+		store = rankStore
+	}
+
+	var seoStore *storage.SEOAuditStorage
+	if *hostInfoFile != "" && *anonymize {
+		log.Fatalf("-host-info can't be combined with -anonymize, which would leave the hosts identifiable")
+	}
+	if *aliasMap != "" && *anonymize {
+		log.Fatalf("-alias-map can't be combined with -anonymize, which would leave the URLs identifiable")
+	}
+
+	if *seoAudit != "" {
+		if *anonymize {
+			log.Fatalf("-seo-audit can't be combined with -anonymize, which drops the titles it checks")
+		}
+		seoStore = storage.NewSEOAuditStorage(store, *seoAudit, *seoMaxDescription)
+		store = seoStore
+	}
+
+	var redirectStore *storage.RedirectAuditStorage
+	if *redirectAudit != "" {
+		if *anonymize {
+			log.Fatalf("-redirect-audit can't be combined with -anonymize, which hashes the URLs it compares")
+		}
+		redirectStore = storage.NewRedirectAuditStorage(store, *redirectAudit, *redirectMaxHops)
+		store = redirectStore
+	}
+
+	var asyncStore *storage.AsyncStorage
+	if *asyncQueue > 0 {
+		// Only plain csv output writes batches; elsewhere records are saved
+		// one by one
+		if _, ok := store.(storage.BatchSaver); !ok {
+			fs.Visit(func(f *flag.Flag) {
+				if f.Name == "async-batch" {
+					log.Printf("-async-batch has no effect: only csv output, without sinks, tag routing or audit files, is saved in batches")
+				}
+			})
+		}
+		asyncStore, err = storage.NewAsyncStorage(store, *asyncQueue, *asyncBatch, *asyncOverflow)
+		if err != nil {
+			log.Fatalf("Invalid -async-overflow: %v", err)
+		}
+		store = asyncStore
+	}
+
+	if *sampleOutput > 0 {
+		if !storage.IsRecordFormat(format) {
+			log.Fatalf("-sample-output needs json, ndjson, csv or xml output")
+		}
+
+		sampleFile := taggedFilename(*outputFile, "sample")
+		sample, err := storage.Open(format, sampleFile, storage.Options{Compress: *compress, CSV: storageOptions.CSV})
+		if err != nil {
+			log.Fatalf("Failed to initialize sample storage: %v", err)
+		}
+		store = storage.NewSampleStorage(store, sample, *sampleOutput, time.Now().UnixNano())
+	}
+
+	// Wraps the sample too, so no output holds page text
+	if *anonymize {
+		if !storage.IsRecordFormat(format) && format != "graph" {
+			log.Fatalf("-anonymize needs json, ndjson, csv, xml or graph output")
+		}
+
+		key := []byte(*anonymizeKey)
+		if len(key) == 0 {
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				log.Fatalf("Failed to generate anonymization key: %v", err)
+			}
+		}
+		store = storage.NewAnonymizeStorage(store, key)
+	}
+
+	// The hooks are outermost, so they see records before they are anonymized
+	var execStore *storage.ExecStorage
+	if *execCommand != "" {
+		execStore, err = storage.NewExecStorage(store, *execCommand, *execOutput, *execFailure, *execConcurrency, *execTimeout)
+		if err != nil {
+			log.Fatalf("Invalid -exec settings: %v", err)
+		}
+		store = execStore
+	}
+
+	var pageScript *script.Script
+	if *scriptFile != "" {
+		pageScript, err = script.Load(*scriptFile)
+		if err != nil {
+			log.Fatalf("Failed to initialize script: %v", err)
+		}
+		store = pageScript.Wrap(store)
 	}
 
 	c := crawler.New(crawlerConfig, urlFrontier, store)
+	if previous != nil {
+		queued := c.Resume(previous)
+		fmt.Printf("Resuming after %d earlier records: %d links queued\n", len(previous), queued)
+	}
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -91,14 +936,214 @@ func main() {
 		}
 	}()
 
-	select {
-	case sig := <-sigChan:
-		fmt.Printf("\nReceived signal %v, shutting down gracefully...\n", sig)
-		c.Stop()
-	case <-c.Done():
-		fmt.Println("\nCrawling completed successfully!")
+	var execStopped <-chan struct{}
+	if execStore != nil {
+		execStopped = execStore.Stopped()
+	}
+
+wait:
+	for {
+		select {
+		case sig := <-sigChan:
+			fmt.Printf("\nReceived signal %v, shutting down gracefully...\n", sig)
+			c.Stop()
+			break wait
+		case <-execStopped:
+			fmt.Println("\n-exec command failed, stopping the crawl...")
+			c.Stop()
+			break wait
+		case <-hupChan:
+			if *reloadFile == "" {
+				fmt.Println("Received SIGHUP but no -reload-file is set, ignoring")
+				continue
+			}
+			reload, err := readReloadFile(*reloadFile)
+			if err != nil {
+				log.Printf("Reload failed: %v", err)
+				continue
+			}
+			c.Reload(reload)
+			fmt.Printf("Reloaded settings: %v\n", reload)
+		case <-c.Done():
+			fmt.Println("\nCrawling completed successfully!")
+			break wait
+		}
 	}
 
 	wg.Wait()
-	fmt.Printf("Crawled %d pages. Results saved to %s\n", c.Stats().PagesCrawled, *outputFile)
+	if quotaNotifier != nil {
+		quotaNotifier.wait()
+	}
+
+	// Closing drains the background writer, so its counts below are final
+	if err := store.Close(); err != nil {
+		log.Printf("Failed to finish output: %v", err)
+	}
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Printf("Failed to save recording: %v", err)
+		}
+	}
+
+	if *hostInfoFile != "" {
+		hosts := c.HostInfos()
+		if err := writeHostInfo(*hostInfoFile, hosts); err != nil {
+			log.Printf("Failed to write host info: %v", err)
+		} else {
+			fmt.Printf("Host info for %d hosts written to %s\n", len(hosts), *hostInfoFile)
+		}
+	}
+
+	if *aliasMap != "" {
+		aliases := c.Aliases()
+		if err := writeAliases(*aliasMap, aliases); err != nil {
+			log.Printf("Failed to write alias map: %v", err)
+		} else {
+			fmt.Printf("%d aliases written to %s\n", len(aliases), *aliasMap)
+		}
+	}
+
+	stats := c.Stats()
+	runConfig := configSnapshot(fs)
+	if *anonymize {
+		// The seeds and filters would reveal the site
+		runConfig = nil
+	}
+	if toStdout {
+		fmt.Printf("Crawled %d pages. Results written to stdout\n", stats.PagesCrawled)
+	} else {
+		err = storage.WriteRunMetadata(*outputFile, storage.RunMetadata{
+			RunID:        *runID,
+			Version:      version,
+			StartTime:    stats.StartTime,
+			EndTime:      stats.EndTime,
+			PagesCrawled: stats.PagesCrawled,
+			Config:       runConfig,
+		})
+		if err != nil {
+			log.Printf("Failed to write run metadata: %v", err)
+		}
+		fmt.Printf("Crawled %d pages. Results saved to %s\n", stats.PagesCrawled, *outputFile)
+	}
+	if validationMode != urlvalidate.Off {
+		fmt.Printf("URL validation: repaired %v, rejected %v\n", stats.URLRepairs, stats.URLRejects)
+	}
+	if *maxURLLength > 0 || *maxQueryParams > 0 || *maxPathRepeats > 0 {
+		fmt.Printf("URL limits: rejected %d too long, %d with too many query parameters, %d with repeated path segments\n",
+			stats.Rejected[string(frontier.RejectTooLong)],
+			stats.Rejected[string(frontier.RejectTooManyParams)],
+			stats.Rejected[string(frontier.RejectRepeatedPath)])
+	}
+	if queryRules != nil {
+		fmt.Printf("Query parameter rules rewrote %d URLs\n", queryRules.Rewritten())
+	}
+	if pageScript != nil {
+		fmt.Printf("Script dropped %d pages\n", pageScript.Dropped())
+	}
+	if seoStore != nil {
+		counts := seoStore.Counts()
+		fmt.Printf("SEO audit: %d pages with duplicate titles, %d with duplicate descriptions, %d missing titles, %d overlong descriptions, %d missing h1s (details in %s)\n",
+			counts[storage.IssueDuplicateTitle], counts[storage.IssueDuplicateDescription], counts[storage.IssueMissingTitle],
+			counts[storage.IssueLongDescription], counts[storage.IssueMissingH1], *seoAudit)
+	}
+	if rankStore != nil {
+		fmt.Printf("Top pages by PageRank (all scores in %s):\n", *pageRank)
+		for _, page := range rankStore.Top(10) {
+			fmt.Printf("  %.4f  %4d in-links  %s\n", page.PageRank, page.InLinks, page.URL)
+		}
+	}
+	if redirectStore != nil {
+		counts := redirectStore.Counts()
+		fmt.Printf("Redirect audit: %d redirect loops, %d long redirect chains, %d https to http redirects, %d canonical scheme mismatches, %d off-site canonicals (details in %s)\n",
+			counts[storage.IssueRedirectLoop], counts[storage.IssueLongRedirectChain], counts[storage.IssueHTTPSToHTTP],
+			counts[storage.IssueCanonicalScheme], counts[storage.IssueCanonicalOffSite], *redirectAudit)
+	}
+	if execStore != nil {
+		if failed, dropped := execStore.Stats(); failed > 0 || dropped > 0 {
+			fmt.Printf("-exec: %d commands failed, %d records dropped by the filter\n", failed, dropped)
+		}
+	}
+	if asyncStore != nil {
+		if dropped, failed := asyncStore.Stats(); dropped > 0 || failed > 0 {
+			fmt.Printf("Background writer: %d records dropped on a full queue, %d failed to save\n", dropped, failed)
+		}
+	}
+	if multiStore != nil {
+		if sinkErrors := multiStore.Errors(); len(sinkErrors) > 0 {
+			fmt.Printf("Records that failed to save, by sink: %v\n", sinkErrors)
+		}
+	}
+	if len(stats.ErrorClasses) > 0 {
+		fmt.Printf("Errors by class: %v\n", stats.ErrorClasses)
+	}
+	if stats.Retries > 0 {
+		fmt.Printf("Retried %d failed fetches; gave up on %d URLs\n", stats.Retries, stats.Rejected[string(frontier.RejectRetriesExhausted)])
+	}
+	if unchanged := stats.Rejected[string(frontier.RejectUnchanged)]; unchanged > 0 {
+		fmt.Printf("Skipped %d sitemap URLs unchanged since their last fetch\n", unchanged)
+	}
+	if httpCache != nil {
+		cacheStats := httpCache.Stats()
+		fmt.Printf("HTTP cache: %d hits, %d revalidated, %d misses, %d responses stored\n", cacheStats.Hits, cacheStats.Revalidated, cacheStats.Misses, cacheStats.Stored)
+	}
+	if *respectRobots {
+		robotsStats := robotsCache.Stats()
+		fmt.Printf("Robots cache: %d hits, %d read from disk, %d fetched, %d waits for a fetch in progress, %d evicted, %d hosts cached\n",
+			robotsStats.Hits, robotsStats.DiskHits, robotsStats.Misses, robotsStats.Waited, robotsStats.Evictions, robotsStats.Entries)
+	}
+	if recorder != nil {
+		fmt.Printf("HTTP interactions recorded to %s\n", *recordFile)
+	}
+	if player != nil && player.Misses() > 0 {
+		fmt.Printf("Replay: %d requests had no recorded response\n", player.Misses())
+	}
+	if len(stats.Blocked) > 0 {
+		fmt.Printf("Hosts that blocked the crawl (403/429/challenge pages): %v\n", stats.Blocked)
+	}
+	if len(stats.CircuitOpened) > 0 {
+		fmt.Printf("Hosts paused after repeated failures: %v\n", stats.CircuitOpened)
+	}
+	if *verbose {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		fmt.Printf("Memory: %.1fMB allocated in %d allocations, %d GC cycles (%v paused); body buffers: %d allocated, %d reused\n",
+			float64(mem.TotalAlloc)/(1<<20), mem.Mallocs, mem.NumGC, time.Duration(mem.PauseTotalNs).Round(time.Microsecond),
+			stats.BuffersAllocated, stats.BuffersReused)
+	}
+	if len(stats.QuotaReached) > 0 {
+		fmt.Printf("Hosts that used up their quota: %v (%d URLs skipped)\n", stats.QuotaReached, stats.Rejected[crawler.RejectQuota])
+	}
+	if *rejectedLog != "" {
+		fmt.Printf("Rejected URLs by reason: %v (details in %s)\n", stats.Rejected, *rejectedLog)
+	}
+}
+
+func writeHostInfo(filename string, hosts []crawler.HostInfo) error {
+	data, err := json.MarshalIndent(hosts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+func writeAliases(filename string, aliases []crawler.Alias) error {
+	var buf strings.Builder
+	for _, alias := range aliases {
+		fmt.Fprintf(&buf, "%s\t%s\t%s\n", alias.URL, alias.Canonical, alias.Via)
+	}
+	return os.WriteFile(filename, []byte(buf.String()), 0o644)
+}
+
+// results.json.gz + blog -> results-blog.json.gz
+func taggedFilename(filename, tag string) string {
+	safeTag := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, tag)
+
+	base, ext := storage.SplitExt(filename)
+	return base + "-" + safeTag + ext
 }
@@ -0,0 +1,198 @@
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// One recorded request and what came back: a response or a transport error.
+// Body holds only what the crawler read, so replays see the same truncated
+// or peeked bodies the recorded crawl saw.
+type Interaction struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header,omitempty"`
+	StatusCode    int         `json:"status_code,omitempty"`
+	Header        http.Header `json:"header,omitempty"`
+	Body          []byte      `json:"body,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	RecordedAt    time.Time   `json:"recorded_at"`
+}
+
+// Saves every HTTP interaction to a cassette file, one JSON object per line
+type Recorder struct {
+	file    *os.File
+	encoder *json.Encoder
+	mutex   sync.Mutex
+	err     error
+}
+
+func Create(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cassette: %w", err)
+	}
+	return &Recorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Returns a RoundTripper that sends requests through next and records them
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		interaction := &Interaction{
+			Method:        req.Method,
+			URL:           req.URL.String(),
+			RequestHeader: req.Header.Clone(),
+			RecordedAt:    time.Now(),
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			interaction.Error = err.Error()
+			r.save(interaction)
+			return nil, err
+		}
+
+		interaction.StatusCode = resp.StatusCode
+		interaction.Header = resp.Header.Clone()
+		resp.Body = &recordingBody{ReadCloser: resp.Body, recorder: r, interaction: interaction}
+		return resp, nil
+	})
+}
+
+func (r *Recorder) save(interaction *Interaction) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.encoder.Encode(interaction); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to write cassette: %w", err)
+	}
+}
+
+// Closes the cassette, returning the first error hit while recording
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to close cassette: %w", err)
+	}
+	return r.err
+}
+
+// Records the interaction once its body is closed, with whatever was read
+type recordingBody struct {
+	io.ReadCloser
+	recorder    *Recorder
+	interaction *Interaction
+	buf         bytes.Buffer
+	once        sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.interaction.Body = b.buf.Bytes()
+		b.recorder.save(b.interaction)
+	})
+	return err
+}
+
+// Serves recorded interactions back instead of going to the network.
+// Repeated requests for a URL get its recordings in order, then the last
+// one again.
+type Player struct {
+	interactions map[string][]*Interaction
+	served       map[string]int
+	misses       int
+	mutex        sync.Mutex
+}
+
+func Load(path string) (*Player, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cassette: %w", err)
+	}
+	defer file.Close()
+
+	p := &Player{
+		interactions: make(map[string][]*Interaction),
+		served:       make(map[string]int),
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette line %d: %w", line, err)
+		}
+		key := interaction.Method + " " + interaction.URL
+		p.interactions[key] = append(p.interactions[key], &interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	return p, nil
+}
+
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+
+	p.mutex.Lock()
+	recorded := p.interactions[key]
+	if len(recorded) == 0 {
+		p.misses++
+		p.mutex.Unlock()
+		return nil, fmt.Errorf("no recorded response for %s", key)
+	}
+	i := p.served[key]
+	if i < len(recorded)-1 {
+		p.served[key] = i + 1
+	}
+	p.mutex.Unlock()
+
+	interaction := recorded[i]
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Number of requests that had no recording
+func (p *Player) Misses() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.misses
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,168 @@
+package crawler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/user/gocrawler/pkg/frontier"
+)
+
+// How a URL was found to be another name for a page
+const (
+	AliasRedirect  = "redirect"
+	AliasCanonical = "canonical"
+)
+
+// A URL that leads to, or declares itself a copy of, the page at Canonical
+type Alias struct {
+	URL       string
+	Canonical string
+	Via       string
+}
+
+// Remembers every URL known to lead to a crawled page, the URL it was
+// fetched at as well as where its redirects ended, so the page isn't
+// fetched again under another name. rel=canonical targets are crawled
+// themselves, with the URLs naming them as their aliases.
+type aliasTracker struct {
+	mutex sync.Mutex
+	// URL -> URL of the record the page was saved under
+	pages   map[string]string
+	aliases []Alias
+	// Canonical URL -> URLs that are aliases of it
+	byTarget map[string][]string
+}
+
+func newAliasTracker(enabled bool) *aliasTracker {
+	if !enabled {
+		return nil
+	}
+	return &aliasTracker{pages: make(map[string]string), byTarget: make(map[string][]string)}
+}
+
+// Must be called with t.mutex held
+func (t *aliasTracker) add(alias Alias) {
+	t.aliases = append(t.aliases, alias)
+	t.byTarget[alias.Canonical] = append(t.byTarget[alias.Canonical], alias.URL)
+}
+
+// Returns the aliases found so far of a page fetched at pageURL whose
+// redirects ended at finalURL, for its record
+func (t *aliasTracker) aliasesOf(pageURL, finalURL string) []string {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var aliases []string
+	seen := map[string]bool{pageURL: true}
+	for _, target := range []string{pageURL, finalURL} {
+		for _, alias := range t.byTarget[target] {
+			if !seen[alias] {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases
+}
+
+// Returns the URL a page was saved under when rawURL is known to lead to it
+func (t *aliasTracker) crawledAs(rawURL string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	original, ok := t.pages[rawURL]
+	return original, ok
+}
+
+// Records a page fetched at pageURL whose redirects ended at finalURL ("" if
+// there were none) and that declares canonical ("" if it doesn't). Returns
+// the URL of the earlier record when the redirects led to a page already
+// crawled under another URL.
+func (t *aliasTracker) record(pageURL, finalURL, canonical string) (string, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	target := pageURL
+	if finalURL != "" && finalURL != pageURL {
+		target = finalURL
+		if original, ok := t.pages[target]; ok {
+			t.pages[pageURL] = original
+			t.add(Alias{URL: pageURL, Canonical: target, Via: AliasRedirect})
+			return original, true
+		}
+		t.pages[target] = pageURL
+		t.add(Alias{URL: pageURL, Canonical: target, Via: AliasRedirect})
+	}
+	t.pages[pageURL] = pageURL
+
+	if canonical != "" && canonical != target && canonical != pageURL {
+		t.add(Alias{URL: pageURL, Canonical: canonical, Via: AliasCanonical})
+	}
+	return "", false
+}
+
+// Returns the aliases found so far, in the order they were found; empty
+// unless the aliases dedup strategy is enabled
+func (c *Crawler) Aliases() []Alias {
+	if c.aliases == nil {
+		return nil
+	}
+	c.aliases.mutex.Lock()
+	defer c.aliases.mutex.Unlock()
+	return append([]Alias(nil), c.aliases.aliases...)
+}
+
+// Drops a fetched page reached through redirects that ended at a page
+// already crawled. Otherwise keeps its final URL out of the frontier and
+// queues its canonical URL, which records the page among its aliases.
+// Reports whether the page was dropped.
+func (c *Crawler) isAlias(item frontier.URLItem, finalURL, canonical string) bool {
+	if c.aliases == nil {
+		return false
+	}
+
+	urlStr := item.URL
+	if original, ok := c.aliases.record(urlStr, finalURL, canonical); ok {
+		if c.verbose.Load() {
+			fmt.Printf("Skipping %s - redirects to %s, already crawled as %s\n", urlStr, finalURL, original)
+		}
+		c.reject(urlStr, original, RejectDuplicateAlias)
+		return true
+	}
+
+	if finalURL != "" {
+		c.frontier.MarkVisited(finalURL)
+	}
+	if canonical != "" && canonical != urlStr && canonical != finalURL {
+		c.enqueueCanonical(item, canonical)
+	}
+	return false
+}
+
+// Queues a page's canonical URL at the page's depth, within the crawl's
+// scope
+func (c *Crawler) enqueueCanonical(item frontier.URLItem, canonical string) {
+	hops, ok := c.externalHops(c.config.SiteGrouping.KeyOfURL(item.URL), item.ExternalHops, canonical)
+	if !ok {
+		c.reject(canonical, item.URL, RejectOffDomain)
+		return
+	}
+
+	next := frontier.URLItem{
+		URL:     canonical,
+		Depth:   item.Depth,
+		SeedTag: item.SeedTag,
+		Tags:    c.config.TagRules.Tags(canonical),
+		Parent:  item.URL,
+
+		ExternalHops: hops,
+	}
+	if reason := c.frontier.AddItem(next); reason != frontier.Accepted {
+		c.reject(canonical, item.URL, string(reason))
+	}
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"fmt"
+
+	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// How AMP pages are handled (Config.AMP)
+const (
+	// Crawl and save AMP pages like any other, recording which canonical
+	// page each belongs to
+	AMPCrawl = "crawl"
+	// Don't follow links to AMP versions, and drop AMP pages that are
+	// fetched anyway
+	AMPSkip = "skip"
+	// Like skip, but crawl an AMP page's canonical page in its place
+	AMPCanonical = "canonical"
+)
+
+// Remembers a page's AMP version so links to it can be skipped
+func (c *Crawler) noteAMPVersion(pageURL string, result *parser.Result) {
+	if result.AMPURL == "" || result.AMPURL == pageURL {
+		return
+	}
+	c.mutex.Lock()
+	c.ampVersions[result.AMPURL] = true
+	c.mutex.Unlock()
+}
+
+// Reports whether the URL is known to be another page's AMP version
+func (c *Crawler) isAMPVersion(link string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ampVersions[link]
+}
+
+// Drops a fetched AMP page under AMPSkip or AMPCanonical; the latter
+// enqueues the canonical page at the AMP page's depth instead
+func (c *Crawler) dropAMPPage(item frontier.URLItem, result *parser.Result) {
+	c.reject(item.URL, item.Parent, RejectAMP)
+	if c.verbose.Load() {
+		fmt.Printf("Skipping AMP page %s (canonical %s)\n", item.URL, result.Canonical)
+	}
+
+	if c.config.AMP != AMPCanonical || result.Canonical == "" || result.Canonical == item.URL {
+		return
+	}
+	canonical := frontier.URLItem{
+		URL:     result.Canonical,
+		Depth:   item.Depth,
+		SeedTag: item.SeedTag,
+		Tags:    c.config.TagRules.Tags(result.Canonical),
+		Parent:  item.Parent,
+
+		ExternalHops: item.ExternalHops,
+	}
+	if reason := c.frontier.AddItem(canonical); reason != frontier.Accepted {
+		c.reject(result.Canonical, item.URL, string(reason))
+	}
+}
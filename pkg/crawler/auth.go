@@ -0,0 +1,45 @@
+package crawler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Credentials used to retry a request that was answered with 401 or 403.
+// A non-empty Token is sent as a bearer token, otherwise basic auth is used.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+}
+
+func (cr Credentials) apply(req *http.Request) {
+	if cr.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+cr.Token)
+		return
+	}
+	req.SetBasicAuth(cr.Username, cr.Password)
+}
+
+func isAuthStatus(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// Looks up credentials by host, trying host:port before the bare hostname
+func (c *Crawler) credentialsFor(rawURL string) (Credentials, bool) {
+	if len(c.config.Credentials) == 0 {
+		return Credentials{}, false
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return Credentials{}, false
+	}
+
+	if creds, ok := c.config.Credentials[strings.ToLower(parsedURL.Host)]; ok {
+		return creds, true
+	}
+	creds, ok := c.config.Credentials[strings.ToLower(parsedURL.Hostname())]
+	return creds, ok
+}
@@ -0,0 +1,181 @@
+package crawler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bytes of a response searched for challenge markers, and the largest 200
+// response still considered a possible challenge page
+const (
+	blockPeekSize      = 16 * 1024
+	challengeMaxLength = 64 * 1024
+)
+
+// Upper bound of the crawl-wide backoff delay
+const maxBlockBackoff = time.Minute
+
+// Body markers of bot challenges and CAPTCHA walls. Widget markers also
+// appear on ordinary login, contact and comment forms, so they only count on
+// error responses.
+var challengeMarkers = []struct {
+	marker string
+	reason string
+	widget bool
+}{
+	{"cf-browser-verification", "cloudflare challenge", false},
+	{"cf_chl_opt", "cloudflare challenge", false},
+	{"/cdn-cgi/challenge-platform/", "cloudflare challenge", true},
+	{"<title>just a moment...</title>", "cloudflare challenge", false},
+	{"<title>attention required! | cloudflare</title>", "cloudflare block", false},
+	{"_incapsula_resource", "incapsula challenge", false},
+	{"px-captcha", "captcha", false},
+	{"g-recaptcha", "captcha", true},
+	{"h-captcha", "captcha", true},
+	{"captcha-delivery.com", "captcha", false},
+}
+
+// Returns why a response looks like a challenge page, or "". body holds the
+// start of the response body.
+func challengeReason(resp *http.Response, body []byte) string {
+	if strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge") {
+		return "cloudflare challenge"
+	}
+	// Real pages may mention CAPTCHAs; challenge pages are small
+	if resp.StatusCode == http.StatusOK && (resp.ContentLength > challengeMaxLength || len(body) > challengeMaxLength) {
+		return ""
+	}
+
+	if len(body) > blockPeekSize {
+		body = body[:blockPeekSize]
+	}
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	lower := bytes.ToLower(body)
+	for _, m := range challengeMarkers {
+		if m.widget && success {
+			continue
+		}
+		if bytes.Contains(lower, []byte(m.marker)) {
+			return m.reason
+		}
+	}
+	return ""
+}
+
+// Reports whether a fetch error suggests the crawler is being blocked
+func isBlockSignal(err error) bool {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return true
+	}
+	var statusErr *HTTPStatusError
+	return errors.As(err, &statusErr) &&
+		(statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode == http.StatusForbidden)
+}
+
+// Parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		return time.Until(when)
+	}
+	return 0
+}
+
+// Counts consecutive block signals per host; threshold of them in a row mean
+// the host is blocking the crawl
+type blockDetector struct {
+	threshold int
+	mutex     sync.Mutex
+	signals   map[string]int
+}
+
+func newBlockDetector(threshold int) *blockDetector {
+	if threshold <= 0 {
+		return nil
+	}
+	return &blockDetector{threshold: threshold, signals: make(map[string]int)}
+}
+
+// Records a response from host and reports whether it completed a block
+func (d *blockDetector) record(host string, signal bool) bool {
+	if d == nil {
+		return false
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !signal {
+		delete(d.signals, host)
+		return false
+	}
+	d.signals[host]++
+	if d.signals[host] < d.threshold {
+		return false
+	}
+	delete(d.signals, host)
+	return true
+}
+
+// Feeds a fetch outcome to the block detector. A detected block pauses the
+// host for BlockPause (or longer if the server asked via Retry-After) and
+// doubles the delay between all requests; successes shrink it again.
+func (c *Crawler) recordBlockResult(urlStr string, err error) {
+	if c.blocks == nil || c.ctx.Err() != nil {
+		return
+	}
+
+	parsedURL, parseErr := url.Parse(urlStr)
+	if parseErr != nil {
+		return
+	}
+	host := c.config.SiteGrouping.Key(parsedURL.Host)
+
+	if !c.blocks.record(host, err != nil && isBlockSignal(err)) {
+		if err == nil {
+			c.mutex.Lock()
+			c.blockBackoff /= 2
+			if c.blockBackoff < 100*time.Millisecond {
+				c.blockBackoff = 0
+			}
+			c.mutex.Unlock()
+		}
+		return
+	}
+
+	pause := c.config.BlockPause
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > pause {
+		pause = statusErr.RetryAfter
+	}
+	until := time.Now().Add(pause)
+	c.frontier.PauseHost(host, until)
+
+	c.mutex.Lock()
+	c.stats.Blocked[host]++
+	c.blockBackoff *= 2
+	if c.blockBackoff < time.Second {
+		c.blockBackoff = time.Second
+	}
+	if c.blockBackoff > maxBlockBackoff {
+		c.blockBackoff = maxBlockBackoff
+	}
+	backoff := c.blockBackoff
+	c.mutex.Unlock()
+
+	fmt.Printf("%s appears to be blocking the crawl (%v), pausing it until %s; requests now at least %v apart\n",
+		host, err, until.Format(time.TimeOnly), backoff)
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Tracks consecutive timeouts and 5xx responses per host. After threshold of
+// them the host's circuit opens for the cool-down; the first request after
+// that is a probe, and a failed probe reopens the circuit straight away.
+type hostBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mutex     sync.Mutex
+	hosts     map[string]*breakerState
+}
+
+type breakerState struct {
+	failures int
+	probing  bool
+}
+
+func newHostBreaker(threshold int, cooldown time.Duration) *hostBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &hostBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		hosts:     make(map[string]*breakerState),
+	}
+}
+
+// Records the outcome of a request to host and reports whether it opened the
+// circuit
+func (b *hostBreaker) record(host string, failed bool) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.hosts[host]
+	if !ok {
+		state = &breakerState{}
+		b.hosts[host] = state
+	}
+
+	if !failed {
+		state.failures = 0
+		state.probing = false
+		return false
+	}
+
+	state.failures++
+	if state.probing || state.failures >= b.threshold {
+		// The next request after the cool-down probes the host
+		state.failures = 0
+		state.probing = true
+		return true
+	}
+	return false
+}
+
+// Reports whether a fetch error means the host is dead or overloaded
+func isHostFailure(err error) bool {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// Buffers that grew beyond this are dropped rather than pooled, so one huge
+// page doesn't pin its memory for the rest of the crawl
+const maxPooledBuffer = 4 << 20
+
+// Reuses response body buffers across pages. A body is read into a buffer
+// from get and handed back with put once the page is parsed, so a large
+// crawl doesn't allocate, and collect, a fresh body per page.
+type bufferPool struct {
+	pool      sync.Pool
+	gets      atomic.Int64
+	allocated atomic.Int64
+}
+
+func newBufferPool() *bufferPool {
+	p := &bufferPool{}
+	p.pool.New = func() any {
+		p.allocated.Add(1)
+		return new(bytes.Buffer)
+	}
+	return p
+}
+
+func (p *bufferPool) get() *bytes.Buffer {
+	p.gets.Add(1)
+	buf := p.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func (p *bufferPool) put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBuffer {
+		return
+	}
+	p.pool.Put(buf)
+}
+
+// Returns how many buffers were allocated and how many times one was reused
+func (p *bufferPool) counts() (int64, int64) {
+	allocated := p.allocated.Load()
+	return allocated, p.gets.Load() - allocated
+}
+
+// Hands the response's body buffer back to the pool. The body must not be
+// used afterwards.
+func (c *Crawler) release(resp *response) {
+	if resp == nil || resp.buf == nil {
+		return
+	}
+	c.buffers.put(resp.buf)
+	resp.buf, resp.body = nil, nil
+}
@@ -1,49 +1,244 @@
 package crawler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/user/gocrawler/pkg/cassette"
+	"github.com/user/gocrawler/pkg/dedup"
 	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/handlers"
+	"github.com/user/gocrawler/pkg/httpcache"
+	"github.com/user/gocrawler/pkg/keywords"
 	"github.com/user/gocrawler/pkg/parser"
 	"github.com/user/gocrawler/pkg/robotstxt"
+	"github.com/user/gocrawler/pkg/seeds"
+	"github.com/user/gocrawler/pkg/seendb"
+	"github.com/user/gocrawler/pkg/sitekey"
 	"github.com/user/gocrawler/pkg/storage"
+	"github.com/user/gocrawler/pkg/tagging"
+	"github.com/user/gocrawler/pkg/techdetect"
+	"github.com/user/gocrawler/pkg/urlpolicy"
+	"github.com/user/gocrawler/pkg/urlvalidate"
 )
 
 type Config struct {
-	MaxDepth      int
-	WorkerCount   int
+	MaxDepth    int
+	WorkerCount int
+	// Parse pages on this many goroutines of their own, fed by the fetch
+	// workers through a queue of up to ParseQueueSize pages (default twice
+	// ParseWorkers), so large documents don't hold up fetching. 0 parses on
+	// the fetch workers.
+	ParseWorkers   int
+	ParseQueueSize int
+	// URLs a worker takes from the frontier at a time, fewer lock round
+	// trips at high worker counts; 0 or 1 takes them one by one
+	FrontierBatch int
 	Delay         time.Duration
 	Timeout       time.Duration
+	MaxBodySize   int64
 	MaxPages      int
-	RespectRobots bool
-	UserAgent     string
-	NewsOnly      bool
-	Verbose       bool
-	StayOnDomain  bool
+	// Stop after this many fetch attempts, failures and retries included
+	// (0 = no limit)
+	MaxFetches int
+	// Stop fetching from a host after this many requests or downloaded
+	// bytes, emitting EventQuotaReached (0 = no limit)
+	HostMaxRequests int
+	HostMaxBytes    int64
+	RespectRobots   bool
+	// Subdomains without a robots.txt follow their apex domain's rules
+	RobotsInheritApex bool
+	// robots.txt rules to use, e.g. a disk cache kept between runs or one
+	// shared by several crawls; nil caches them for this crawl only
+	RobotsCache *robotstxt.RobotsCache
+	UserAgent   string
+	// Agents to rotate the User-Agent header through, per request or per
+	// host (UserAgentRotation); robots.txt is still checked for UserAgent
+	UserAgents        []string
+	UserAgentRotation string
+	// Sent with every request so site owners can reach the operator: a From
+	// header, and a "+URL" comment in the User-Agent; robots.txt is still
+	// checked for the bare UserAgent
+	From         string
+	CrawlInfoURL string
+	Fingerprint  Fingerprint
+	// Send the page a URL was discovered on as the Referer header
+	SendReferer  bool
+	NewsOnly     bool
+	Verbose      bool
+	StayOnDomain bool
+	// Groups hosts into the sites that StayOnDomain, ExternalDomains and the
+	// per-host delay, bandwidth, breaker and block limits apply to
+	SiteGrouping sitekey.Grouping
+	// Hosts (with their subdomains) whose links are followed as if on-site
+	ExternalHosts []string
+	// Follow off-site links this many hops past the last on-site page,
+	// regardless of StayOnDomain (0 = StayOnDomain decides)
+	ExternalDepth int
 	URLFilter     string
 	SeedOnly      bool
 	ExtractLinks  bool
+	// How much of each HTML page to extract, a parser.Level* constant; ""
+	// extracts everything
+	ExtractLevel string
+	// Also follow URL literals found in inline scripts and event handlers
+	ScriptLinks bool
+	// Record each page's forms; FollowForms also enqueues GET form actions
+	// with empty parameters
+	Forms       bool
+	FollowForms bool
+	// Record each page's heading outline and counts per level
+	Headings bool
+	// Keep the raw body of HTML pages in the record ("inline", or "gzip"
+	// for gzip+base64) and/or in a directory of files named by URL hash
+	RawHTML    string
+	RawHTMLDir *storage.RawHTMLDir
+	// Record word count, text ratio, link count and boilerplate share
+	Quality bool
+	// Score pages by keyword occurrences; with KeywordPriority, links are
+	// enqueued with their page's score as priority
+	Keywords        *keywords.Scorer
+	KeywordPriority bool
+	// Record the distinct external domains each page links to
+	ExternalDomains bool
+	// Follow only this language's hreflang variants of pages, e.g. "en" or
+	// "en-gb", and skip links to the other languages' (empty = all)
+	Language string
+	// AMPCrawl (default), AMPSkip or AMPCanonical
+	AMP string
+	// Site-specific extraction into PageData.Fields, or replacing the
+	// title, description, author or content
+	Selectors []parser.Selector
+	// Keep links out of the saved records; they are still followed
+	OmitLinks   bool
+	ParseXML    bool
+	ParsePDF    bool
+	ParseJSON   bool
+	MaxJSONSize int
+	AssetDir    string
+
+	// Nil lists use the urlpolicy defaults, minus any extensions the
+	// enabled content handlers can process
+	SkipExtensions []string
+	SkipPatterns   []string
+	Seeds          []seeds.Seed
+	Credentials    map[string]Credentials
+	RejectedLog    io.Writer
+	TagRules       tagging.Rules
+	SeenDB         *seendb.DB
+	// Serves repeated fetches from disk while the cached copy is fresh
+	HTTPCache *httpcache.Cache
+	// Save every request and response, robots.txt included, to a cassette,
+	// or answer them from one instead of the network
+	Record *cassette.Recorder
+	Replay *cassette.Player
+	// Detect the technologies behind every page
+	Technologies bool
+	// Record links to social media profiles
+	Social bool
+	// Record IPs, server headers, CDN, HTTP version and TLS details of every
+	// host, for HostInfos
+	HostInfo bool
+	// Skip sitemap URLs whose lastmod predates their last fetch in SeenDB,
+	// and refetch changed ones regardless of the revisit interval
+	SitemapLastmod bool
+	URLValidation  urlvalidate.Mode
+	// Page-level duplicate detection; URL-level strategies are applied by
+	// the frontier
+	Dedup dedup.Strategies
+	RunID string
+
+	// Adaptive worker pool scaling between MinWorkers and MaxWorkers
+	AutoScale     bool
+	MinWorkers    int
+	MaxWorkers    int
+	ScaleInterval time.Duration
+	MaxErrorRate  float64
+	MaxBandwidth  int64
+
+	// Stop fetching from a host for BreakerCooldown after this many
+	// consecutive timeouts or 5xx responses (0 = never)
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// Caps on response bytes read per second, across the crawl and per
+	// host (0 = unlimited)
+	BandwidthLimit     int64
+	HostBandwidthLimit int64
+
+	// Only fetch during this daily window, pausing outside it
+	Window Window
+
+	// Start with one worker and a raised per-host delay, reaching
+	// WorkerCount and Delay after RampUp; ramping stops early when the
+	// error rate exceeds RampMaxErrorRate (0 = never)
+	RampUp           time.Duration
+	RampMaxErrorRate float64
+
+	// Treat a host as blocking the crawl after this many consecutive 403,
+	// 429 or challenge-page responses (0 = never): pause it for BlockPause
+	// and slow down the whole crawl
+	BlockThreshold int
+	BlockPause     time.Duration
+
+	// Requeue URLs that failed with a timeout, connection error, 5xx, 429 or
+	// block until they have been tried MaxAttempts times, waiting RetryDelay
+	// before the first retry and twice as long before each further one
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	// Called with every crawl event as it happens, from the worker
+	// goroutines; it must be safe for concurrent use and return quickly
+	OnEvent func(Event)
+
+	// Save a record for every URL that failed for good, with the error, its
+	// class, the status code and the number of attempts
+	ErrorRecords bool
 }
 
 type Statistics struct {
 	PagesCrawled    int
+	Fetches         int // fetch attempts, failures included
 	LinksDiscovered int
-	StartTime       time.Time
-	EndTime         time.Time
+	Errors          int
+	ErrorClasses    map[string]int // typed errors by class, see ErrorClass
+	Retries         int
+	AuthRequired    int
+	CircuitOpened   map[string]int    // times each host's circuit opened
+	Blocked         map[string]int    // times each host was found blocking the crawl
+	QuotaReached    map[string]string // hosts that used up a quota, and which
+	Rejected        map[string]int
+	URLRepairs      map[string]int
+	URLRejects      map[string]int
+	BytesDownloaded int64
+	// Response body buffers allocated, and reused from earlier pages
+	BuffersAllocated int64
+	BuffersReused    int64
+	StartTime        time.Time
+	EndTime          time.Time
 }
 
 type Crawler struct {
 	config     Config
 	frontier   *frontier.URLFrontier
 	storage    storage.Storage
-	robots     *robotstxt.RobotsCache
+	robots     *robotstxt.Checker
+	handlers   *handlers.Registry
+	userAgents *userAgentPicker
+	dedup      *dedup.Checker
+	breaker    *hostBreaker
+	validator  *urlvalidate.Validator
+	policy     *urlpolicy.Policy
+	seeds      map[string]seeds.Seed
 	httpClient *http.Client
 	done       chan struct{}
 	stats      Statistics
@@ -51,57 +246,262 @@ type Crawler struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	mutex      sync.Mutex
+
+	rejectedLogMutex sync.Mutex
+
+	hostLimiters      map[string]chan time.Time
+	hostLimitersMutex sync.Mutex
+
+	bandwidth          *tokenBucket
+	hostBandwidth      map[string]*tokenBucket
+	hostBandwidthMutex sync.Mutex
+
+	activeWorkers int
+	targetWorkers int
+	nextWorkerID  int
+	// Workers that have taken, or are about to take, an item from the
+	// frontier and may still queue links found on it
+	inFlight int
+	// Closed and replaced whenever an item finishes, waking idle workers
+	progress chan struct{}
+
+	// Last sequence number given to a saved record
+	sequence int
+
+	// When the current crawl window pause ends, so each pause is reported once
+	windowOpensAt time.Time
+	// Extra per-host delay while ramping up
+	rampDelay time.Duration
+
+	blocks  *blockDetector
+	hosts   *hostRecorder
+	quota   *hostQuota
+	aliases *aliasTracker
+
+	buffers *bufferPool
+
+	// Fetched pages on their way to the parse workers; nil without them
+	parseQueue chan parseJob
+	parseWG    sync.WaitGroup
+	// Minimum per-host delay after blocks, halved by each success
+	blockBackoff time.Duration
+
+	// Config.Verbose, changeable by Reload
+	verbose atomic.Bool
+
+	// hreflang variants in languages other than Config.Language
+	otherLanguages map[string]bool
+	// AMP versions declared by crawled pages, skipped unless AMPCrawl
+	ampVersions map[string]bool
 }
 
 func New(config Config, frontier *frontier.URLFrontier, storage storage.Storage) *Crawler {
 	ctx, cancel := context.WithCancel(context.Background())
+	validator := urlvalidate.New(config.URLValidation)
+	robots := config.RobotsCache
+	if robots == nil {
+		robots = robotstxt.NewRobotsCache(24 * time.Hour)
+		robots.SetMaxEntries(robotstxt.DefaultMaxEntries)
+	}
+
+	seedsByTag := make(map[string]seeds.Seed)
+	for _, seed := range config.Seeds {
+		seedsByTag[seed.Tag] = seed
+	}
+
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     30 * time.Second,
+	}
 
+	// The recorder sits outside the cache, so responses served from the
+	// cache are recorded too and a replay sees everything the crawl saw
+	var roundTripper http.RoundTripper = transport
+	if config.HTTPCache != nil {
+		roundTripper = config.HTTPCache.Wrap(roundTripper)
+	}
+	if config.Replay != nil {
+		roundTripper = config.Replay
+	} else if config.Record != nil {
+		roundTripper = config.Record.Wrap(roundTripper)
+	}
 	httpClient := &http.Client{
-		Timeout: config.Timeout,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     30 * time.Second,
-		},
+		Timeout:   config.Timeout,
+		Transport: newIdentityTransport(roundTripper, config.From, config.CrawlInfoURL),
 	}
 
-	return &Crawler{
+	var dedupChecker *dedup.Checker
+	if config.Dedup.CheckPages() {
+		dedupChecker = dedup.NewChecker(config.Dedup)
+	}
+
+	c := &Crawler{
 		config:     config,
 		frontier:   frontier,
 		storage:    storage,
-		robots:     robotstxt.NewRobotsCache(24 * time.Hour),
+		robots:     robots.Checker(httpClient, config.RobotsInheritApex),
+		handlers:   defaultHandlers(config, validator),
+		userAgents: newUserAgentPicker(config),
+		dedup:      dedupChecker,
+		breaker:    newHostBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		blocks:     newBlockDetector(config.BlockThreshold),
+		quota:      newHostQuota(config.HostMaxRequests, config.HostMaxBytes),
+		aliases:    newAliasTracker(config.Dedup[dedup.Aliases]),
+		buffers:    newBufferPool(),
+		hosts:      newHostRecorder(config.HostInfo, config.Replay == nil),
+		validator:  validator,
+		policy:     newURLPolicy(config),
+		seeds:      seedsByTag,
 		httpClient: httpClient,
 		done:       make(chan struct{}),
+		progress:   make(chan struct{}),
+
+		otherLanguages: make(map[string]bool),
+		ampVersions:    make(map[string]bool),
 		stats: Statistics{
-			StartTime: time.Now(),
+			StartTime:     time.Now(),
+			CircuitOpened: make(map[string]int),
+			Blocked:       make(map[string]int),
+			QuotaReached:  make(map[string]string),
+			ErrorClasses:  make(map[string]int),
+			Rejected:      make(map[string]int),
 		},
-		ctx:    ctx,
-		cancel: cancel,
+		ctx:           ctx,
+		cancel:        cancel,
+		hostLimiters:  make(map[string]chan time.Time),
+		bandwidth:     newTokenBucket(config.BandwidthLimit),
+		hostBandwidth: make(map[string]*tokenBucket),
 	}
+	httpClient.CheckRedirect = c.checkRedirect
+	// Keeps a worker's batch from holding several URLs of one host
+	frontier.SetHostDelay(config.Delay)
+	c.verbose.Store(config.Verbose)
+	if config.ParseWorkers > 0 {
+		queueSize := config.ParseQueueSize
+		if queueSize <= 0 {
+			queueSize = 2 * config.ParseWorkers
+		}
+		c.parseQueue = make(chan parseJob, queueSize)
+	}
+	return c
+}
+
+// Builds the content type registry for a crawl. HTML is always handled; other
+// types are enabled by configuration.
+func defaultHandlers(config Config, validator *urlvalidate.Validator) *handlers.Registry {
+	registry := handlers.NewRegistry()
+
+	html := handlers.HTMLHandler{
+		NewsOnly:     config.NewsOnly,
+		ExtractLinks: config.ExtractLinks,
+		Level:        config.ExtractLevel,
+		ScriptLinks:  config.ScriptLinks,
+		Forms:        config.Forms,
+		FollowForms:  config.FollowForms,
+		Headings:     config.Headings,
+		Quality:      config.Quality,
+		Technologies: config.Technologies,
+		Social:       config.Social,
+		Selectors:    config.Selectors,
+		Validator:    validator,
+	}
+	registry.Register("text/html", html)
+	registry.Register("application/xhtml+xml", html)
+
+	if config.ParseXML {
+		for _, mediaType := range []string{"application/xml", "text/xml", "application/rss+xml", "application/atom+xml"} {
+			registry.Register(mediaType, handlers.XMLHandler{})
+		}
+	}
+
+	if config.ParsePDF {
+		registry.Register("application/pdf", handlers.PDFHandler{})
+	}
+
+	if config.ParseJSON {
+		jsonHandler := handlers.JSONHandler{MaxSize: config.MaxJSONSize}
+		for _, mediaType := range []string{"application/json", "text/json", "application/vnd.oai.openapi+json"} {
+			registry.Register(mediaType, jsonHandler)
+		}
+	}
+
+	if config.AssetDir != "" {
+		registry.Register("image/*", handlers.AssetHandler{Dir: config.AssetDir})
+	}
+
+	return registry
+}
+
+func newURLPolicy(config Config) *urlpolicy.Policy {
+	extensions := config.SkipExtensions
+	if extensions == nil {
+		extensions = urlpolicy.DefaultSkipExtensions
+		if config.ParsePDF {
+			extensions = urlpolicy.Without(extensions, ".pdf")
+		}
+		if config.ParseXML {
+			extensions = urlpolicy.Without(extensions, ".xml")
+		}
+		if config.ParseJSON {
+			extensions = urlpolicy.Without(extensions, ".json")
+		}
+		if config.AssetDir != "" {
+			extensions = urlpolicy.Without(extensions, urlpolicy.ImageExtensions...)
+		}
+	}
+
+	patterns := config.SkipPatterns
+	if patterns == nil {
+		patterns = urlpolicy.DefaultSkipPatterns
+	}
+
+	return urlpolicy.New(extensions, patterns)
+}
+
+// Registers a handler for an additional content type, replacing any existing
+// handler for it. Must be called before Start.
+func (c *Crawler) RegisterHandler(mediaType string, h handlers.Handler) {
+	c.handlers.Register(mediaType, h)
 }
 
 func (c *Crawler) Start() error {
-	if c.config.Verbose {
+	if c.verbose.Load() {
 		fmt.Println("Starting crawler with", c.config.WorkerCount, "workers")
 	}
 
-	rateLimiter := make(chan struct{}, c.config.WorkerCount)
+	workers := c.config.WorkerCount
+	if c.config.RampUp > 0 {
+		workers = 1
+		c.rampDelay = c.rampStartDelay()
+	}
 
-	hostLimiters := make(map[string]chan time.Time)
-	hostLimitersMutex := sync.Mutex{}
+	c.startParseWorkers()
 
-	for i := 0; i < c.config.WorkerCount; i++ {
-		c.wg.Add(1)
-		go c.worker(i, rateLimiter, hostLimiters, &hostLimitersMutex)
+	c.mutex.Lock()
+	c.targetWorkers = workers
+	for i := 0; i < workers; i++ {
+		c.spawnWorkerLocked()
+	}
+	c.mutex.Unlock()
+
+	// Auto-scaling takes over once the ramp-up completes
+	if c.config.RampUp > 0 {
+		go c.rampUp()
+	} else if c.config.AutoScale {
+		go c.autoScale()
 	}
 
 	c.wg.Wait()
+	c.stopParseWorkers()
 
+	c.mutex.Lock()
 	c.stats.EndTime = time.Now()
+	c.mutex.Unlock()
 
 	close(c.done)
 
-	if c.config.Verbose {
+	if c.verbose.Load() {
 		fmt.Println("Crawling completed. Crawled", c.stats.PagesCrawled, "pages")
 	}
 
@@ -119,177 +519,946 @@ func (c *Crawler) Done() <-chan struct{} {
 func (c *Crawler) Stats() Statistics {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	return c.stats
+
+	stats := c.stats
+	stats.Rejected = make(map[string]int, len(c.stats.Rejected))
+	for reason, count := range c.stats.Rejected {
+		stats.Rejected[reason] = count
+	}
+	stats.CircuitOpened = make(map[string]int, len(c.stats.CircuitOpened))
+	for host, count := range c.stats.CircuitOpened {
+		stats.CircuitOpened[host] = count
+	}
+	stats.Blocked = make(map[string]int, len(c.stats.Blocked))
+	for host, count := range c.stats.Blocked {
+		stats.Blocked[host] = count
+	}
+	stats.QuotaReached = make(map[string]string, len(c.stats.QuotaReached))
+	for host, quota := range c.stats.QuotaReached {
+		stats.QuotaReached[host] = quota
+	}
+	stats.ErrorClasses = make(map[string]int, len(c.stats.ErrorClasses))
+	for class, count := range c.stats.ErrorClasses {
+		stats.ErrorClasses[class] = count
+	}
+
+	stats.BuffersAllocated, stats.BuffersReused = c.buffers.counts()
+
+	validation := c.validator.Stats()
+	stats.URLRepairs = validation.Repaired
+	stats.URLRejects = validation.Rejected
+	return stats
 }
 
-func (c *Crawler) worker(id int, rateLimiter chan struct{}, hostLimiters map[string]chan time.Time, hostLimitersMutex *sync.Mutex) {
-	defer c.wg.Done()
+// Changes the number of workers while the crawl is running. Extra workers
+// are started immediately; surplus workers retire after their current page.
+func (c *Crawler) SetWorkerCount(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// A pool with no live workers has either finished or not started yet.
+	if c.activeWorkers == 0 {
+		return
+	}
+
+	c.targetWorkers = n
+	for c.activeWorkers < c.targetWorkers {
+		c.spawnWorkerLocked()
+	}
+
+	if c.verbose.Load() {
+		fmt.Println("Worker count set to", n)
+	}
+}
+
+func (c *Crawler) WorkerCount() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.activeWorkers
+}
+
+// Must be called with c.mutex held
+func (c *Crawler) spawnWorkerLocked() {
+	c.activeWorkers++
+	c.wg.Add(1)
+	go c.worker(c.nextWorkerID)
+	c.nextWorkerID++
+}
+
+// Reports whether the calling worker should exit, either because the pool
+// has been scaled down or the page or fetch limit has been reached. The
+// worker is deregistered before returning true.
+func (c *Crawler) shouldExit() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.activeWorkers > c.targetWorkers || c.limitReachedLocked(0) {
+		c.activeWorkers--
+		return true
+	}
+	return false
+}
+
+// Reports whether MaxPages or MaxFetches would be reached with this many
+// more pages. Must be called with c.mutex held.
+func (c *Crawler) limitReachedLocked(claims int) bool {
+	return (c.config.MaxPages > 0 && c.stats.PagesCrawled+claims >= c.config.MaxPages) ||
+		(c.config.MaxFetches > 0 && c.stats.Fetches+claims >= c.config.MaxFetches)
+}
 
+func (c *Crawler) exitWorker() {
+	c.mutex.Lock()
+	c.activeWorkers--
+	c.mutex.Unlock()
+}
+
+func (c *Crawler) autoScale() {
+	interval := c.config.ScaleInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	minWorkers := c.config.MinWorkers
+	if minWorkers < 1 {
+		minWorkers = 1
+	}
+	maxWorkers := c.config.MaxWorkers
+	if maxWorkers < minWorkers {
+		maxWorkers = minWorkers
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := c.Stats()
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
-		default:
+		case <-c.done:
+			return
+		case <-ticker.C:
 		}
 
+		current := c.Stats()
+		attempts := (current.PagesCrawled + current.Errors) - (last.PagesCrawled + last.Errors)
+		errors := current.Errors - last.Errors
+		bandwidth := float64(current.BytesDownloaded-last.BytesDownloaded) / interval.Seconds()
+		last = current
+
 		c.mutex.Lock()
-		if c.config.MaxPages > 0 && c.stats.PagesCrawled >= c.config.MaxPages {
-			c.mutex.Unlock()
+		target := c.targetWorkers
+		active := c.activeWorkers
+		c.mutex.Unlock()
+
+		overErrors := c.config.MaxErrorRate > 0 && attempts > 0 &&
+			float64(errors)/float64(attempts) > c.config.MaxErrorRate
+		overBandwidth := c.config.MaxBandwidth > 0 && bandwidth > float64(c.config.MaxBandwidth)
+
+		switch {
+		case (overErrors || overBandwidth) && target > minWorkers:
+			c.SetWorkerCount(target - 1)
+		case !overErrors && !overBandwidth && target < maxWorkers && c.frontier.Size() > active:
+			c.SetWorkerCount(target + 1)
+		}
+	}
+}
+
+func (c *Crawler) worker(id int) {
+	defer c.wg.Done()
+
+	// Items taken from the frontier, each holding a claim, that this
+	// worker hasn't processed yet
+	var batch []frontier.URLItem
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.returnItems(batch)
+			c.exitWorker()
 			return
+		default:
 		}
-		c.mutex.Unlock()
 
-		urlStr, depth, ok := c.frontier.Next()
-		if !ok {
+		if c.shouldExit() {
+			c.returnItems(batch)
 			return
 		}
 
-		if depth > c.config.MaxDepth {
+		if wait := c.config.Window.Until(time.Now()); wait > 0 {
+			c.waitForWindow(wait)
 			continue
 		}
 
-		rateLimiter <- struct{}{}
-
-		parsedURL, err := url.Parse(urlStr)
-		if err == nil {
-			host := parsedURL.Host
-			hostLimitersMutex.Lock()
-			limiter, exists := hostLimiters[host]
-			if !exists {
-				limiter = make(chan time.Time, 1)
-				hostLimiters[host] = limiter
-				limiter <- time.Now()
+		if len(batch) == 0 {
+			// Claimed before taking the items, so no other worker sees an
+			// empty frontier and nothing in flight while this one holds pages
+			claimed, progress := c.claimItems(c.config.FrontierBatch)
+			if claimed == 0 {
+				select {
+				case <-c.ctx.Done():
+				case <-progress:
+				}
+				continue
 			}
-			hostLimitersMutex.Unlock()
-
-			lastTime := <-limiter
-			sleepTime := c.config.Delay - time.Since(lastTime)
-			if sleepTime > 0 {
-				time.Sleep(sleepTime)
+			batch = c.frontier.NextBatch(claimed)
+			if len(batch) == 0 {
+				c.releaseClaims(claimed - 1)
+				if !c.waitForWork() {
+					c.exitWorker()
+					return
+				}
+				continue
 			}
+			c.releaseClaims(claimed - len(batch))
+		}
+		item := batch[0]
+		batch = batch[1:]
 
+		// A page handed to the parse workers keeps its claim until parsed,
+		// as its links are still to be queued
+		if item.Depth > c.maxDepthFor(item.SeedTag) || !c.fetchItem(item) {
+			c.finishItem()
+		}
+	}
+}
+
+// Waits out the per-host delay, then processes the item. Reports whether it
+// was handed to the parse workers.
+func (c *Crawler) fetchItem(item frontier.URLItem) bool {
+	parsedURL, err := url.Parse(item.URL)
+	if err == nil {
+		host := c.config.SiteGrouping.Key(parsedURL.Host)
+		if c.quota.exhausted(host) {
+			// Don't wait out the delay just to skip the URL
+			c.reject(item.URL, "", RejectQuota)
+			return false
+		}
+
+		c.hostLimitersMutex.Lock()
+		limiter, exists := c.hostLimiters[host]
+		if !exists {
+			limiter = make(chan time.Time, 1)
+			c.hostLimiters[host] = limiter
 			limiter <- time.Now()
 		}
+		c.hostLimitersMutex.Unlock()
 
-		c.processURL(urlStr, depth)
+		lastTime := <-limiter
+		delay := c.hostDelay()
+		sleepTime := delay + c.config.Fingerprint.jitter(delay) - time.Since(lastTime)
+		if sleepTime > 0 {
+			time.Sleep(sleepTime)
+		}
 
-		<-rateLimiter
+		limiter <- time.Now()
 	}
+
+	return c.processURL(item)
 }
 
-func (c *Crawler) processURL(urlStr string, depth int) {
+// Claims slots for up to n (at least 1) next items. Claims count against
+// MaxPages and MaxFetches until they finish, so concurrent workers can't
+// overshoot the limits. When the other workers hold the remaining slots,
+// returns 0 and a channel closed once one of them finishes.
+func (c *Crawler) claimItems(n int) (int, <-chan struct{}) {
+	if n < 1 {
+		n = 1
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	claimed := 0
+	for claimed < n && !c.limitReachedLocked(c.inFlight) {
+		c.inFlight++
+		claimed++
+	}
+	if claimed == 0 {
+		progress := c.progress
+		if c.inFlight == 0 {
+			// Reached for good; the worker exits on its next check
+			progress = make(chan struct{})
+			close(progress)
+		}
+		return 0, progress
+	}
+	return claimed, nil
+}
+
+// Releases an item's claim and wakes the workers waiting for more work
+func (c *Crawler) finishItem() {
+	c.releaseClaims(1)
+}
+
+// Releases n claims, waking the workers waiting for more work
+func (c *Crawler) releaseClaims(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	c.inFlight -= n
+	close(c.progress)
+	c.progress = make(chan struct{})
+	c.mutex.Unlock()
+}
+
+// Hands items a stopping worker took but didn't process back to the
+// frontier for the other workers
+func (c *Crawler) returnItems(items []frontier.URLItem) {
+	c.frontier.Requeue(items)
+	c.releaseClaims(len(items))
+}
+
+// Called with an unused claim when the frontier had nothing ready. Waits
+// until queued URLs become ready or another worker finishes a page, and
+// reports false once the crawl is out of work: nothing is queued or waiting
+// and no page that could queue more is in flight.
+func (c *Crawler) waitForWork() bool {
+	c.mutex.Lock()
+	c.inFlight--
+	busy := c.inFlight > 0
+	progress := c.progress
+	c.mutex.Unlock()
+
+	// URLs of hosts with an open circuit, and retries, are still queued
+	wait, pending := c.frontier.NextReadyIn()
+	if !pending && !busy {
+		return false
+	}
+
+	var ready <-chan time.Time
+	if pending {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		ready = timer.C
+	}
+	select {
+	case <-c.ctx.Done():
+	case <-ready:
+	case <-progress:
+	}
+	return true
+}
+
+// Sleeps until the crawl window opens or the crawl is stopped
+func (c *Crawler) waitForWindow(wait time.Duration) {
+	opens := time.Now().Add(wait)
+	c.mutex.Lock()
+	report := opens.Sub(c.windowOpensAt) > time.Minute
+	if report {
+		c.windowOpensAt = opens
+	}
+	c.mutex.Unlock()
+
+	if report {
+		fmt.Printf("Outside crawl window %v, pausing until %s\n", c.config.Window, opens.In(c.config.Window.Location).Format(time.DateTime))
+	}
+
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(wait):
+	}
+}
+
+func (c *Crawler) maxDepthFor(seedTag string) int {
+	if seed, ok := c.seeds[seedTag]; ok && seed.MaxDepth >= 0 {
+		return seed.MaxDepth
+	}
+	return c.config.MaxDepth
+}
+
+func (c *Crawler) urlFilterFor(seedTag string) string {
+	if seed, ok := c.seeds[seedTag]; ok && seed.URLFilter != "" {
+		return seed.URLFilter
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.config.URLFilter
+}
+
+// Fetches an item and passes the response on to parse. Reports whether a
+// parse worker took it over, along with the item's claim.
+func (c *Crawler) processURL(item frontier.URLItem) bool {
+	urlStr, depth := item.URL, item.Depth
+
+	// Seeds are enqueued directly by the caller and haven't been tagged yet
+	if item.Tags == nil {
+		item.Tags = c.config.TagRules.Tags(urlStr)
+	}
+
+	if original, ok := c.aliases.crawledAs(urlStr); ok {
+		c.reject(urlStr, original, RejectDuplicateAlias)
+		return false
+	}
+
 	if c.config.RespectRobots {
 		allowed, delay, err := c.robots.IsAllowed(urlStr, c.config.UserAgent)
-		if err != nil && c.config.Verbose {
+		if err != nil && c.verbose.Load() {
 			fmt.Printf("Warning: Robots.txt error for %s: %v\n", urlStr, err)
 		}
 
 		if !allowed {
-			if c.config.Verbose {
-				fmt.Printf("Skipping %s - disallowed by robots.txt\n", urlStr)
-			}
-			return
+			c.emitError(urlStr, &RobotsBlockedError{URL: urlStr})
+			c.reject(urlStr, "", RejectRobots)
+			return false
 		}
 
-		if delay > c.config.Delay {
-			time.Sleep(delay - c.config.Delay)
+		if base := c.baseDelay(); delay > base {
+			time.Sleep(delay - base)
 		}
 	}
 
-	if c.config.Verbose {
+	if c.verbose.Load() {
 		fmt.Printf("Crawling [depth:%d] %s\n", depth, urlStr)
 	}
 
-	html, err := c.fetchURL(urlStr)
+	if !c.quotaRequest(urlStr) {
+		c.reject(urlStr, "", RejectQuota)
+		return false
+	}
+
+	c.mutex.Lock()
+	c.stats.Fetches++
+	c.mutex.Unlock()
+	resp, err := c.fetchURL(urlStr, item.Parent, nil)
+
+	var statusErr *HTTPStatusError
+	authRequired := errors.As(err, &statusErr) && isAuthStatus(statusErr.StatusCode)
+	if authRequired {
+		c.mutex.Lock()
+		c.stats.AuthRequired++
+		c.mutex.Unlock()
+
+		if creds, ok := c.credentialsFor(urlStr); ok {
+			if c.verbose.Load() {
+				fmt.Printf("Retrying %s with credentials\n", urlStr)
+			}
+			resp, err = c.fetchURL(urlStr, item.Parent, &creds)
+		}
+	}
+
+	c.recordHostResult(urlStr, err)
+	c.recordBlockResult(urlStr, err)
+
 	if err != nil {
-		if c.config.Verbose {
-			fmt.Printf("Error fetching %s: %v\n", urlStr, err)
+		c.recordError()
+		if c.ctx.Err() == nil {
+			c.emitError(urlStr, err)
 		}
-		return
+
+		if c.ctx.Err() != nil {
+			return false
+		}
+		if isTransient(err) && c.config.MaxAttempts > 1 && c.retry(item, err) {
+			return false
+		}
+		if authRequired || c.config.ErrorRecords {
+			c.saveError(item, err, authRequired)
+		}
+		return false
 	}
 
-	result, err := parser.Parse(html, urlStr, c.config.NewsOnly, c.config.ExtractLinks)
+	c.mutex.Lock()
+	c.stats.BytesDownloaded += int64(len(resp.body))
+	c.mutex.Unlock()
+	c.quotaDownload(urlStr, int64(len(resp.body)))
+
+	if c.config.SeenDB != nil {
+		if key, err := frontier.Normalize(urlStr); err == nil {
+			if err := c.config.SeenDB.MarkFetched(key, time.Now()); err != nil && c.verbose.Load() {
+				fmt.Printf("Warning: failed to record %s in seen database: %v\n", urlStr, err)
+			}
+		}
+	}
+
+	return c.parse(parseJob{item: item, resp: resp, authRequired: authRequired})
+}
+
+// Parses a fetched page, saves its record and queues its links
+func (c *Crawler) parsePage(job parseJob) {
+	item, resp, authRequired := job.item, job.resp, job.authRequired
+	urlStr, depth := item.URL, item.Depth
+	defer c.release(resp)
+
+	result, err := resp.handler.Handle(c.ctx, resp.body, urlStr)
 	if err != nil {
-		if c.config.Verbose {
-			fmt.Printf("Error parsing %s: %v\n", urlStr, err)
+		if c.ctx.Err() != nil {
+			// Stopped mid-parse; not the page's fault
+			return
+		}
+		c.recordError()
+		parseErr := &ParseError{URL: urlStr, Err: err}
+		c.emitError(urlStr, parseErr)
+		if c.config.ErrorRecords {
+			c.saveError(item, parseErr, false)
 		}
 		return
 	}
 
+	skipAMP := c.config.AMP == AMPSkip || c.config.AMP == AMPCanonical
+	if result.AMP && skipAMP {
+		c.dropAMPPage(item, result)
+		return
+	}
+	if skipAMP {
+		c.noteAMPVersion(urlStr, result)
+	}
+
+	if c.isAlias(item, resp.finalURL, result.Canonical) {
+		return
+	}
+	if c.dedup != nil && c.isDuplicate(urlStr, result) {
+		return
+	}
+
+	// Anchors line up with links only for parsed HTML
+	hasAnchors := len(result.Anchors) == len(result.Links)
+	if c.config.Language != "" && c.config.ExtractLinks {
+		for _, variant := range c.languageVariants(result.Alternates) {
+			if variant == urlStr {
+				continue
+			}
+			result.Links = append(result.Links, variant)
+			result.Anchors = append(result.Anchors, parser.Anchor{URL: variant, Rel: "alternate"})
+		}
+	}
+	links := make([]string, 0, len(result.Links))
+	anchors := make([]storage.Anchor, 0, len(result.Links))
+	for i, link := range result.Links {
+		if c.policy.Skip(link) {
+			c.reject(link, urlStr, RejectSkipRule)
+			continue
+		}
+		if c.config.Language != "" && c.isOtherLanguage(link) {
+			c.reject(link, urlStr, RejectLanguage)
+			continue
+		}
+		if skipAMP && c.isAMPVersion(link) {
+			c.reject(link, urlStr, RejectAMP)
+			continue
+		}
+		links = append(links, link)
+
+		anchor := storage.Anchor{URL: link}
+		if hasAnchors {
+			anchor.Text, anchor.Rel = result.Anchors[i].Text, result.Anchors[i].Rel
+		}
+		anchors = append(anchors, anchor)
+	}
+	result.Links = links
+
 	c.mutex.Lock()
 	c.stats.PagesCrawled++
 	c.stats.LinksDiscovered += len(result.Links)
+	c.sequence++
+	sequence := c.sequence
 	c.mutex.Unlock()
 
-	err = c.storage.Save(storage.PageData{
-		URL:         urlStr,
-		Title:       result.Title,
-		Description: result.Description,
-		Content:     result.Content,
-		Links:       result.Links,
-		CrawledAt:   time.Now(),
-		Depth:       depth,
+	var external []string
+	if c.config.ExternalDomains {
+		external = externalDomains(urlStr, result.Links, c.config.SiteGrouping)
+	}
+
+	var headings []storage.Heading
+	var headingCounts map[string]int
+	if c.config.Headings && result.Kind == "html" {
+		headings = make([]storage.Heading, len(result.Headings))
+		headingCounts = make(map[string]int)
+		for i, heading := range result.Headings {
+			headings[i] = storage.Heading{Level: heading.Level, Text: heading.Text}
+			headingCounts[fmt.Sprintf("h%d", heading.Level)]++
+		}
+	}
+
+	var keywordScore *int
+	if !c.config.Keywords.Empty() {
+		score := c.config.Keywords.Score(result.Title, result.Content)
+		keywordScore = &score
+	}
+
+	var technologies []string
+	if c.config.Technologies {
+		page := techdetect.Page{Header: resp.header, Generator: result.Generator, Scripts: result.Scripts}
+		if result.Kind == "html" {
+			page.HTML = resp.body
+		}
+		technologies = techdetect.Detect(page)
+	}
+
+	var raw rawHTML
+	if result.Kind == "html" {
+		raw = c.rawHTML(urlStr, resp.body)
+	}
+
+	savedLinks := result.Links
+	if c.config.OmitLinks {
+		savedLinks = nil
+	}
+
+	err = c.storage.Save(c.ctx, storage.PageData{
+		URL:             urlStr,
+		Title:           result.Title,
+		Description:     result.Description,
+		Author:          result.Author,
+		Content:         result.Content,
+		Links:           savedLinks,
+		Anchors:         anchors,
+		ExternalDomains: external,
+		CrawledAt:       time.Now(),
+		Depth:           depth,
+		SeedTag:         item.SeedTag,
+		Tags:            item.Tags,
+		AuthRequired:    authRequired,
+		RunID:           c.config.RunID,
+		ContentType:     resp.contentType,
+		ContentKind:     result.Kind,
+		Size:            int64(len(resp.body)),
+		StatusCode:      http.StatusOK,
+		Sequence:        sequence,
+		ParentURL:       item.Parent,
+		Forms:           storageForms(result.Forms),
+		Headings:        headings,
+		HeadingCounts:   headingCounts,
+		RawHTML:         raw.inline,
+		RawHTMLEncoding: raw.encoding,
+		RawHTMLFile:     raw.file,
+		Quality:         storageQuality(result.Quality),
+		KeywordScore:    keywordScore,
+		Alternates:      storageAlternates(result.Alternates),
+		AMP:             result.AMP,
+		CanonicalURL:    result.Canonical,
+		AMPURL:          result.AMPURL,
+		Fields:          result.Fields,
+		Redirects:       resp.redirects,
+		FinalURL:        resp.finalURL,
+		Technologies:    technologies,
+		SocialProfiles:  storageSocialProfiles(result.SocialProfiles),
+		Aliases:         c.aliases.aliasesOf(urlStr, resp.finalURL),
 	})
 
-	if err != nil && c.config.Verbose {
-		fmt.Printf("Error saving data for %s: %v\n", urlStr, err)
+	if err != nil {
+		c.emitError(urlStr, &StorageError{URL: urlStr, Err: err})
 	}
 
+	c.followLinks(item, result.Links, result.LastModified, keywordScore)
+}
+
+// Queues a page's links that are within its seed's depth, scope and filter.
+// Returns the number queued.
+func (c *Crawler) followLinks(item frontier.URLItem, links []string, lastModified map[string]time.Time, keywordScore *int) int {
+	urlStr, depth := item.URL, item.Depth
+
 	if c.config.SeedOnly {
-		return
+		for _, link := range links {
+			c.reject(link, urlStr, RejectSeedOnly)
+		}
+		return 0
 	}
 
-	var seedDomain string
-	if c.config.StayOnDomain {
-		parsedURL, err := url.Parse(urlStr)
-		if err == nil {
-			seedDomain = parsedURL.Host
+	// Links past the seed's depth limit are never enqueued, so they cannot
+	// shadow the same URL discovered later through a deeper-reaching seed.
+	if depth+1 > c.maxDepthFor(item.SeedTag) {
+		for _, link := range links {
+			c.reject(link, urlStr, RejectDepth)
 		}
+		return 0
 	}
 
-	for _, link := range result.Links {
-		if c.config.StayOnDomain {
-			parsedLink, err := url.Parse(link)
-			if err != nil || parsedLink.Host != seedDomain {
-				continue
-			}
+	pageSite := c.config.SiteGrouping.KeyOfURL(urlStr)
+
+	urlFilter := c.urlFilterFor(item.SeedTag)
+	queued := 0
+	for _, link := range links {
+		hops, ok := c.externalHops(pageSite, item.ExternalHops, link)
+		if !ok {
+			c.reject(link, urlStr, RejectOffDomain)
+			continue
+		}
+
+		if urlFilter != "" && !strings.Contains(link, urlFilter) {
+			c.reject(link, urlStr, RejectFilter)
+			continue
 		}
 
-		if c.config.URLFilter != "" && !strings.Contains(link, c.config.URLFilter) {
+		next := frontier.URLItem{
+			URL:     link,
+			Depth:   depth + 1,
+			SeedTag: item.SeedTag,
+			Tags:    c.config.TagRules.Tags(link),
+			Parent:  urlStr,
+
+			ExternalHops: hops,
+		}
+		if c.config.SitemapLastmod {
+			next.LastModified = lastModified[link]
+		}
+		if c.config.KeywordPriority && keywordScore != nil {
+			next.Priority = *keywordScore
+		}
+		if reason := c.frontier.AddItem(next); reason != frontier.Accepted {
+			c.reject(link, urlStr, string(reason))
 			continue
 		}
+		queued++
+	}
+	return queued
+}
+
+func storageForms(forms []parser.Form) []storage.Form {
+	if forms == nil {
+		return nil
+	}
+
+	converted := make([]storage.Form, len(forms))
+	for i, form := range forms {
+		converted[i] = storage.Form{Action: form.Action, Method: form.Method}
+		for _, field := range form.Fields {
+			converted[i].Fields = append(converted[i].Fields, storage.FormField{Name: field.Name, Type: field.Type})
+		}
+	}
+	return converted
+}
+
+func storageQuality(q *parser.Quality) *storage.Quality {
+	if q == nil {
+		return nil
+	}
+	return &storage.Quality{
+		WordCount:          q.WordCount,
+		TextRatio:          q.TextRatio,
+		LinkCount:          q.LinkCount,
+		BoilerplatePercent: q.BoilerplatePercent,
+	}
+}
+
+func storageSocialProfiles(profiles []parser.SocialProfile) []storage.SocialProfile {
+	if profiles == nil {
+		return nil
+	}
+
+	converted := make([]storage.SocialProfile, len(profiles))
+	for i, profile := range profiles {
+		converted[i] = storage.SocialProfile{Network: profile.Network, Handle: profile.Handle, URL: profile.URL}
+	}
+	return converted
+}
+
+// Runs the page-level dedup strategies; a duplicate is neither saved nor
+// followed
+func (c *Crawler) isDuplicate(urlStr string, result *parser.Result) bool {
+	strategy, original := c.dedup.Check(urlStr, result.Canonical, result.Content)
+	if strategy == "" {
+		return false
+	}
+
+	if c.verbose.Load() {
+		fmt.Printf("Skipping %s - %s duplicate of %s\n", urlStr, strategy, original)
+	}
+	reasons := map[string]string{
+		dedup.Canonical:   RejectDuplicateCanonical,
+		dedup.ContentHash: RejectDuplicateContent,
+		dedup.Simhash:     RejectNearDuplicate,
+	}
+	c.reject(urlStr, original, reasons[strategy])
+	return true
+}
+
+// Stores a content-free record for a URL that failed for good, so failures
+// and pages requiring authentication remain visible in the output
+func (c *Crawler) saveError(item frontier.URLItem, fetchErr error, authRequired bool) {
+	var statusCode int
+	var statusErr *HTTPStatusError
+	if errors.As(fetchErr, &statusErr) {
+		statusCode = statusErr.StatusCode
+	}
+
+	c.mutex.Lock()
+	c.sequence++
+	sequence := c.sequence
+	c.mutex.Unlock()
+
+	err := c.storage.Save(c.ctx, storage.PageData{
+		URL:          item.URL,
+		CrawledAt:    time.Now(),
+		Depth:        item.Depth,
+		SeedTag:      item.SeedTag,
+		Tags:         item.Tags,
+		AuthRequired: authRequired,
+		RunID:        c.config.RunID,
+		Error:        fetchErr.Error(),
+		ErrorClass:   ErrorClass(fetchErr),
+		Attempts:     item.Attempts + 1,
+		StatusCode:   statusCode,
+		Sequence:     sequence,
+		ParentURL:    item.Parent,
+	})
+
+	if err != nil {
+		c.emitError(item.URL, &StorageError{URL: item.URL, Err: err})
+	}
+}
+
+// Feeds a fetch outcome to the circuit breaker, pausing the host in the
+// frontier when its circuit opens
+func (c *Crawler) recordHostResult(urlStr string, err error) {
+	if c.breaker == nil || c.ctx.Err() != nil {
+		return
+	}
 
-		c.frontier.Add(link, depth+1)
+	parsedURL, parseErr := url.Parse(urlStr)
+	if parseErr != nil {
+		return
+	}
+
+	host := c.config.SiteGrouping.Key(parsedURL.Host)
+	if !c.breaker.record(host, err != nil && isHostFailure(err)) {
+		return
+	}
+
+	until := time.Now().Add(c.config.BreakerCooldown)
+	c.frontier.PauseHost(host, until)
+
+	c.mutex.Lock()
+	c.stats.CircuitOpened[host]++
+	c.mutex.Unlock()
+
+	if c.verbose.Load() {
+		fmt.Printf("Circuit open for %s after repeated failures, pausing until %s\n", host, until.Format(time.TimeOnly))
 	}
 }
 
-func (c *Crawler) fetchURL(url string) (string, error) {
+func (c *Crawler) recordError() {
+	c.mutex.Lock()
+	c.stats.Errors++
+	c.mutex.Unlock()
+}
+
+type response struct {
+	// Read into buf from the crawler's pool; see release
+	body        []byte
+	buf         *bytes.Buffer
+	contentType string
+	handler     handlers.Handler
+	// Redirects followed on the way, and where they ended
+	redirects []storage.Redirect
+	finalURL  string
+	header    http.Header
+}
+
+// Keeps redirects from reaching URLs the allow and deny lists forbid
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			chain := make([]string, 0, len(via)+1)
+			for _, hop := range via {
+				chain = append(chain, hop.URL.String())
+			}
+			return &RedirectLoopError{URL: via[0].URL.String(), Chain: append(chain, req.URL.String())}
+		}
+	}
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if reason := c.frontier.CheckLists(req.URL.String()); reason != frontier.Accepted {
+		return fmt.Errorf("redirect to %s blocked: %s", req.URL, reason)
+	}
+	return nil
+}
+
+func (c *Crawler) fetchURL(url, referer string, creds *Credentials) (*response, error) {
 	req, err := http.NewRequestWithContext(c.ctx, "GET", url, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set("User-Agent", c.userAgents.pick(url, c.config.UserAgent))
+	c.config.Fingerprint.apply(req)
+	if c.config.SendReferer && referer != "" {
+		// Like browsers, don't leak https pages to plain http
+		if !(strings.HasPrefix(referer, "https:") && req.URL.Scheme == "http") {
+			req.Header.Set("Referer", referer)
+		}
+	}
+	if creds != nil {
+		creds.apply(req)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, classifyFetchError(url, err)
 	}
 	defer resp.Body.Close()
+	if c.hosts != nil {
+		c.hosts.record(resp)
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if c.blocks != nil {
+			peek, _ := io.ReadAll(io.LimitReader(resp.Body, blockPeekSize))
+			if reason := challengeReason(resp, peek); reason != "" {
+				return nil, &BlockedError{URL: url, StatusCode: resp.StatusCode, Reason: reason}
+			}
+		}
+		return nil, &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp.Header.Get("Retry-After"))}
 	}
 
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") && !strings.Contains(contentType, "application/xhtml+xml") {
-		return "", fmt.Errorf("non-HTML content type: %s", contentType)
+	handler, ok := c.handlers.Lookup(contentType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	var reader io.Reader = c.throttle(resp.Body, c.config.SiteGrouping.Key(req.URL.Host))
+	if c.config.MaxBodySize > 0 {
+		reader = io.LimitReader(reader, c.config.MaxBodySize)
+	}
+
+	buf := c.buffers.get()
+	if n := resp.ContentLength; n > 0 && n <= maxPooledBuffer {
+		buf.Grow(int(n))
+	}
+	if _, err := buf.ReadFrom(reader); err != nil {
+		c.buffers.put(buf)
+		return nil, classifyFetchError(url, err)
 	}
+	body := buf.Bytes()
 
-	return string(body), nil
+	if c.verbose.Load() && c.config.MaxBodySize > 0 && int64(len(body)) == c.config.MaxBodySize {
+		fmt.Printf("Warning: %s truncated at %d bytes\n", url, c.config.MaxBodySize)
+	}
+
+	if c.blocks != nil {
+		if reason := challengeReason(resp, body); reason != "" {
+			c.buffers.put(buf)
+			return nil, &BlockedError{URL: url, StatusCode: resp.StatusCode, Reason: reason}
+		}
+	}
+
+	fetched := &response{
+		body:        body,
+		buf:         buf,
+		contentType: contentType,
+		handler:     handler,
+		redirects:   redirectChain(resp),
+		header:      resp.Header,
+	}
+	if fetched.redirects != nil {
+		fetched.finalURL = resp.Request.URL.String()
+	}
+	return fetched, nil
+}
+
+// The redirects the client followed to get resp, oldest first
+func redirectChain(resp *http.Response) []storage.Redirect {
+	var chain []storage.Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, storage.Redirect{URL: req.Response.Request.URL.String(), StatusCode: req.Response.StatusCode})
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
 }
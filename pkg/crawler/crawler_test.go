@@ -0,0 +1,199 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/gocrawler/pkg/cassette"
+	"github.com/user/gocrawler/pkg/crawltest"
+	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/httpcache"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Keeps saved records in memory
+type memoryStorage struct {
+	mutex   sync.Mutex
+	records []storage.PageData
+}
+
+func (s *memoryStorage) Save(ctx context.Context, data storage.PageData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records = append(s.records, data)
+	return nil
+}
+
+func (s *memoryStorage) Close() error {
+	return nil
+}
+
+// Sorted URLs of the saved records
+func (s *memoryStorage) urls() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	urls := make([]string, len(s.records))
+	for i, record := range s.records {
+		urls[i] = record.URL
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func testConfig() Config {
+	return Config{
+		MaxDepth:     5,
+		WorkerCount:  4,
+		Timeout:      5 * time.Second,
+		MaxBodySize:  1 << 20,
+		MaxPages:     1000,
+		UserAgent:    "GoCrawler/test",
+		StayOnDomain: true,
+		ExtractLinks: true,
+	}
+}
+
+// Crawls from seed and returns the saved records
+func crawl(t *testing.T, config Config, seed string) *memoryStorage {
+	t.Helper()
+	urlFrontier := frontier.NewURLFrontier()
+	urlFrontier.Add(seed, 0)
+	store := &memoryStorage{}
+	if err := New(config, urlFrontier, store).Start(); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+	return store
+}
+
+func TestCrawlFollowsLinksAndRobots(t *testing.T) {
+	site := crawltest.NewServer(crawltest.Config{
+		Pages:  crawltest.Tree(2, 3),
+		Robots: "User-agent: *\nCrawl-delay: 0.01\nDisallow: /p/1/\n",
+	})
+	defer site.Close()
+
+	config := testConfig()
+	config.RespectRobots = true
+	config.FrontierBatch = 4
+	store := crawl(t, config, site.URL("/"))
+
+	// 1 + 3 + 9 pages, less /p/1/ and its 3 children
+	if got := len(store.urls()); got != 9 {
+		t.Errorf("crawled %d pages, want 9: %v", got, store.urls())
+	}
+	if n := site.Requests("/p/1/"); n != 0 {
+		t.Errorf("/p/1/ disallowed by robots.txt was fetched %d times", n)
+	}
+}
+
+func TestCrawlMaxPages(t *testing.T) {
+	site := crawltest.NewServer(crawltest.Config{Pages: crawltest.Random(50, 5, 1)})
+	defer site.Close()
+
+	config := testConfig()
+	config.MaxPages = 7
+	config.FrontierBatch = 8
+	store := crawl(t, config, site.URL("/"))
+
+	if got := len(store.urls()); got != 7 {
+		t.Errorf("crawled %d pages, want MaxPages 7", got)
+	}
+}
+
+// Notes when each request is sent
+type sendTimes struct {
+	next  http.RoundTripper
+	mutex sync.Mutex
+	times []time.Time
+}
+
+func (s *sendTimes) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mutex.Lock()
+	s.times = append(s.times, time.Now())
+	s.mutex.Unlock()
+	return s.next.RoundTrip(req)
+}
+
+// Batched workers still wait out the delay between a host's fetches
+func TestCrawlBatchKeepsHostDelay(t *testing.T) {
+	site := crawltest.NewServer(crawltest.Config{Pages: crawltest.Tree(1, 5)})
+	defer site.Close()
+
+	config := testConfig()
+	config.Delay = 100 * time.Millisecond
+	config.FrontierBatch = 8
+	urlFrontier := frontier.NewURLFrontier()
+	urlFrontier.Add(site.URL("/"), 0)
+	store := &memoryStorage{}
+	c := New(config, urlFrontier, store)
+	sent := &sendTimes{next: c.httpClient.Transport}
+	c.httpClient.Transport = sent
+	if err := c.Start(); err != nil {
+		t.Fatalf("crawl failed: %v", err)
+	}
+
+	if got := len(store.urls()); got != 6 {
+		t.Errorf("crawled %d pages, want 6", got)
+	}
+	// Allow for the time between a worker's delay ending and its request
+	// being sent
+	const slack = 5 * time.Millisecond
+	for i := 1; i < len(sent.times); i++ {
+		if gap := sent.times[i].Sub(sent.times[i-1]); gap < config.Delay-slack {
+			t.Errorf("requests %d and %d were sent %v apart, want at least %v", i, i+1, gap, config.Delay)
+		}
+	}
+}
+
+// A crawl served partly from the HTTP cache is recorded in full, so replaying
+// the cassette without the site gives the same pages
+func TestRecordThroughCacheReplays(t *testing.T) {
+	site := crawltest.NewServer(crawltest.Config{Pages: crawltest.Chain(5)})
+	dir := t.TempDir()
+	cache, err := httpcache.Open(filepath.Join(dir, "cache"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := testConfig()
+	config.HTTPCache = cache
+	warm := crawl(t, config, site.URL("/"))
+
+	// Every page now comes from the cache
+	before := site.TotalRequests()
+	recorder, err := cassette.Create(filepath.Join(dir, "crawl.cassette"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Record = recorder
+	recorded := crawl(t, config, site.URL("/"))
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := site.TotalRequests() - before; n != 0 {
+		t.Errorf("cached crawl sent %d requests to the site, want 0", n)
+	}
+	site.Close()
+
+	player, err := cassette.Load(filepath.Join(dir, "crawl.cassette"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = testConfig()
+	config.Replay = player
+	replayed := crawl(t, config, site.URL("/"))
+
+	if player.Misses() != 0 {
+		t.Errorf("replay missed %d requests", player.Misses())
+	}
+	for _, store := range []*memoryStorage{recorded, replayed} {
+		if got, want := store.urls(), warm.urls(); len(got) != len(want) || len(want) != 5 {
+			t.Errorf("crawled %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/user/gocrawler/pkg/frontier"
+)
+
+// What a crawl would do with a URL. Reason is empty when the URL would be
+// fetched, otherwise one of the Reject* reasons or a frontier rejection.
+type Decision struct {
+	URL    string
+	Seed   bool
+	Reason string
+}
+
+// Evaluates the queued seeds and the given known URLs (e.g. from a sitemap
+// or an earlier crawl) as links found on a seed page: skip rules, scope,
+// URL filter, the frontier's checks and robots.txt. Only robots.txt files
+// are fetched. Drains the frontier, so the crawler can't be started after.
+func (c *Crawler) DryRun(known []string) []Decision {
+	var decisions []Decision
+	seedHosts := make(map[string]bool)
+	for {
+		item, ok := c.frontier.NextItem()
+		if !ok {
+			break
+		}
+		if parsedURL, err := url.Parse(item.URL); err == nil {
+			seedHosts[c.config.SiteGrouping.Key(parsedURL.Host)] = true
+		}
+		decisions = append(decisions, Decision{URL: item.URL, Seed: true, Reason: c.robotsDecision(item.URL)})
+	}
+
+	for _, link := range known {
+		decisions = append(decisions, Decision{URL: link, Reason: c.linkDecision(link, seedHosts)})
+	}
+	return decisions
+}
+
+func (c *Crawler) linkDecision(link string, seedHosts map[string]bool) string {
+	if c.policy.Skip(link) {
+		return RejectSkipRule
+	}
+	if c.config.SeedOnly {
+		return RejectSeedOnly
+	}
+	if c.config.MaxDepth < 1 {
+		return RejectDepth
+	}
+	parsedLink, err := url.Parse(link)
+	if err != nil {
+		return RejectOffDomain
+	}
+	if !seedHosts[c.config.SiteGrouping.Key(parsedLink.Host)] {
+		if _, ok := c.externalHops("", 0, link); !ok {
+			return RejectOffDomain
+		}
+	}
+	if c.config.URLFilter != "" && !strings.Contains(link, c.config.URLFilter) {
+		return RejectFilter
+	}
+	if reason := c.frontier.AddItem(frontier.URLItem{URL: link, Depth: 1}); reason != frontier.Accepted {
+		return string(reason)
+	}
+	return c.robotsDecision(link)
+}
+
+func (c *Crawler) robotsDecision(rawURL string) string {
+	if !c.config.RespectRobots {
+		return ""
+	}
+	if allowed, _, _ := c.robots.IsAllowed(rawURL, c.config.UserAgent); !allowed {
+		return RejectRobots
+	}
+	return ""
+}
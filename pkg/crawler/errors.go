@@ -0,0 +1,227 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Classes of crawl errors, as counted in Statistics.ErrorClasses
+const (
+	ClassDNS           = "dns"
+	ClassTLS           = "tls"
+	ClassTimeout       = "timeout"
+	ClassNetwork       = "network"
+	ClassHTTPStatus    = "http-status"
+	ClassBlocked       = "blocked"
+	ClassRobotsBlocked = "robots-blocked"
+	ClassRedirectLoop  = "redirect-loop"
+	ClassParse         = "parse"
+	ClassStorage       = "storage"
+	ClassOther         = "other"
+)
+
+// The host name could not be resolved
+type DNSError struct {
+	URL string
+	Err error
+}
+
+func (e *DNSError) Error() string {
+	return fmt.Sprintf("dns lookup failed: %v", e.Err)
+}
+
+func (e *DNSError) Unwrap() error {
+	return e.Err
+}
+
+// The TLS handshake or certificate verification failed
+type TLSError struct {
+	URL string
+	Err error
+}
+
+func (e *TLSError) Error() string {
+	return fmt.Sprintf("tls error: %v", e.Err)
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// The request timed out
+type TimeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout: %v", e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// The connection was refused, reset or otherwise failed
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("connection failed: %v", e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// The server answered with a status other than 200
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// A response that looks like a bot challenge or CAPTCHA page
+type BlockedError struct {
+	URL        string
+	StatusCode int
+	Reason     string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked: %s (status %d)", e.Reason, e.StatusCode)
+}
+
+// robots.txt disallows the URL
+type RobotsBlockedError struct {
+	URL string
+}
+
+func (e *RobotsBlockedError) Error() string {
+	return "disallowed by robots.txt"
+}
+
+// A redirect led back to a URL already visited on the way
+type RedirectLoopError struct {
+	URL   string
+	Chain []string // the URLs visited, ending with the repeated one
+}
+
+func (e *RedirectLoopError) Error() string {
+	return "redirect loop: " + strings.Join(e.Chain, " -> ")
+}
+
+// The fetched body could not be parsed
+type ParseError struct {
+	URL string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse failed: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The page's record could not be saved
+type StorageError struct {
+	URL string
+	Err error
+}
+
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("failed to save record: %v", e.Err)
+}
+
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// Returns the class of a crawl error, ClassOther for untyped ones
+func ErrorClass(err error) string {
+	var (
+		dnsErr     *DNSError
+		tlsErr     *TLSError
+		timeoutErr *TimeoutError
+		networkErr *NetworkError
+		statusErr  *HTTPStatusError
+		blocked    *BlockedError
+		robotsErr  *RobotsBlockedError
+		loopErr    *RedirectLoopError
+		parseErr   *ParseError
+		storageErr *StorageError
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return ClassDNS
+	case errors.As(err, &tlsErr):
+		return ClassTLS
+	case errors.As(err, &timeoutErr):
+		return ClassTimeout
+	case errors.As(err, &networkErr):
+		return ClassNetwork
+	case errors.As(err, &statusErr):
+		return ClassHTTPStatus
+	case errors.As(err, &blocked):
+		return ClassBlocked
+	case errors.As(err, &robotsErr):
+		return ClassRobotsBlocked
+	case errors.As(err, &loopErr):
+		return ClassRedirectLoop
+	case errors.As(err, &parseErr):
+		return ClassParse
+	case errors.As(err, &storageErr):
+		return ClassStorage
+	default:
+		return ClassOther
+	}
+}
+
+// Wraps an error from the HTTP client in the typed error for its cause.
+// Errors that are already typed, and unrecognized ones, are returned as is.
+func classifyFetchError(urlStr string, err error) error {
+	var (
+		statusErr      *HTTPStatusError
+		blocked        *BlockedError
+		loopErr        *RedirectLoopError
+		dnsErr         *net.DNSError
+		recordErr      tls.RecordHeaderError
+		verifyErr      *tls.CertificateVerificationError
+		authorityErr   x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+		certInvalidErr x509.CertificateInvalidError
+		netErr         net.Error
+		opErr          *net.OpError
+	)
+	switch {
+	case errors.As(err, &statusErr), errors.As(err, &blocked):
+		return err
+	case errors.As(err, &loopErr):
+		return loopErr
+	case errors.As(err, &dnsErr):
+		return &DNSError{URL: urlStr, Err: err}
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &certInvalidErr), strings.Contains(err.Error(), "tls: "):
+		return &TLSError{URL: urlStr, Err: err}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &TimeoutError{URL: urlStr, Err: err}
+	case errors.As(err, &opErr):
+		return &NetworkError{URL: urlStr, Err: err}
+	default:
+		return err
+	}
+}
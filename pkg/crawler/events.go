@@ -0,0 +1,48 @@
+package crawler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Kinds of crawl events
+const (
+	// A URL failed; Err is one of the typed errors, see ErrorClass
+	EventError = "error"
+	// A host used up its HostMaxRequests or HostMaxBytes with URL; Quota is
+	// QuotaRequests or QuotaBytes. Its remaining URLs are skipped.
+	EventQuotaReached = "quota-reached"
+)
+
+// Something that happened during a crawl, passed to Config.OnEvent
+type Event struct {
+	Type string
+	Time time.Time
+	URL  string
+	Err  error
+	// Site key of the host and the quota it used up, for EventQuotaReached
+	Host  string
+	Quota string
+}
+
+func (c *Crawler) emit(event Event) {
+	if c.config.OnEvent == nil {
+		return
+	}
+	event.Time = time.Now()
+	c.config.OnEvent(event)
+}
+
+// Counts a typed error by class, prints it when verbose and emits it as an
+// EventError
+func (c *Crawler) emitError(urlStr string, err error) {
+	class := ErrorClass(err)
+	c.mutex.Lock()
+	c.stats.ErrorClasses[class]++
+	c.mutex.Unlock()
+
+	if c.verbose.Load() {
+		fmt.Printf("Error [%s] %s: %v\n", class, urlStr, err)
+	}
+	c.emit(Event{Type: EventError, URL: urlStr, Err: err})
+}
@@ -0,0 +1,52 @@
+package crawler
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/user/gocrawler/pkg/sitekey"
+)
+
+// Returns the distinct hosts, sorted, that the page's links point to other
+// than the page's own host. A leading "www." is ignored on both sides, so
+// example.com and www.example.com count as the same site; with domain
+// grouping, registered domains are compared and returned instead.
+func externalDomains(pageURL string, links []string, sites sitekey.Grouping) []string {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	pageHost := siteHost(parsedURL.Hostname(), sites)
+
+	seen := make(map[string]bool)
+	for _, link := range links {
+		parsedLink, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+
+		host := siteHost(parsedLink.Hostname(), sites)
+		if host != "" && host != pageHost {
+			seen[host] = true
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil
+	}
+
+	domains := make([]string, 0, len(seen))
+	for host := range seen {
+		domains = append(domains, host)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+func siteHost(host string, sites sitekey.Grouping) string {
+	if sites == sitekey.Domain {
+		return sites.Key(host)
+	}
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
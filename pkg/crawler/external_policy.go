@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Decides whether a link found on a page is followed and how many hops
+// outside the crawled site it is. pageSite and pageHops describe the page the
+// link was found on. Links into ExternalHosts count as on-site; other
+// off-site links are followed up to ExternalDepth hops, or not at all with
+// StayOnDomain.
+func (c *Crawler) externalHops(pageSite string, pageHops int, link string) (int, bool) {
+	parsedLink, err := url.Parse(link)
+	if err != nil {
+		return 0, false
+	}
+
+	if pageHops == 0 && c.config.SiteGrouping.Key(parsedLink.Host) == pageSite {
+		return 0, true
+	}
+	if c.isExternalHostAllowed(parsedLink.Hostname()) {
+		return 0, true
+	}
+
+	if c.config.ExternalDepth > 0 {
+		return pageHops + 1, pageHops+1 <= c.config.ExternalDepth
+	}
+	return 0, !c.config.StayOnDomain
+}
+
+// Reports whether host is one of ExternalHosts or a subdomain of one
+func (c *Crawler) isExternalHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range c.config.ExternalHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package crawler
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Controls how requests look on the wire. The zero value sends Go's
+// defaults.
+type Fingerprint struct {
+	// Accept header value
+	Accept string
+	// Accept-Language values; each request picks one at random
+	AcceptLanguages []string
+	// Random extra delay, up to this long, added to each request's delay
+	DelayJitter time.Duration
+	// Randomize each request's delay by up to this fraction of it either
+	// way, e.g. 0.3 for 70% to 130% of the delay
+	DelayJitterFraction float64
+}
+
+func (f Fingerprint) apply(req *http.Request) {
+	if f.Accept != "" {
+		req.Header.Set("Accept", f.Accept)
+	}
+	if len(f.AcceptLanguages) > 0 {
+		req.Header.Set("Accept-Language", f.AcceptLanguages[rand.Intn(len(f.AcceptLanguages))])
+	}
+}
+
+// Returns a random offset to add to a request's delay
+func (f Fingerprint) jitter(delay time.Duration) time.Duration {
+	var offset time.Duration
+	if f.DelayJitter > 0 {
+		offset += time.Duration(rand.Int63n(int64(f.DelayJitter)))
+	}
+	if spread := time.Duration(f.DelayJitterFraction * float64(delay)); spread > 0 {
+		offset += time.Duration(rand.Int63n(int64(2*spread))) - spread
+	}
+	return offset
+}
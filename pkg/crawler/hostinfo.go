@@ -0,0 +1,188 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// What the crawl learned about a host from its first response
+type HostInfo struct {
+	Host string   `json:"host"`
+	IPs  []string `json:"ips,omitempty"`
+	// The Server and X-Powered-By headers
+	Server    string `json:"server,omitempty"`
+	PoweredBy string `json:"powered_by,omitempty"`
+	// CDNs and WAFs recognized from the response headers
+	CDN         []string  `json:"cdn,omitempty"`
+	HTTPVersion string    `json:"http_version"`
+	TLS         *TLSInfo  `json:"tls,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+}
+
+type TLSInfo struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipher_suite"`
+	Subject     string    `json:"subject,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// Headers that give away a CDN or WAF in front of a host
+var cdnHeaders = []struct {
+	header string
+	value  string // substring of the value, lowercase; empty matches any
+	name   string
+}{
+	{"Cf-Ray", "", "cloudflare"},
+	{"Server", "cloudflare", "cloudflare"},
+	{"X-Amz-Cf-Id", "", "cloudfront"},
+	{"Via", "cloudfront", "cloudfront"},
+	{"X-Fastly-Request-Id", "", "fastly"},
+	{"X-Served-By", "cache-", "fastly"},
+	{"Server", "akamaighost", "akamai"},
+	{"X-Akamai-Transformed", "", "akamai"},
+	{"Akamai-Grn", "", "akamai"},
+	{"X-Azure-Ref", "", "azure front door"},
+	{"Via", "google", "google cloud cdn"},
+	{"X-Vercel-Id", "", "vercel"},
+	{"X-Nf-Request-Id", "", "netlify"},
+	{"Server", "bunnycdn", "bunnycdn"},
+	{"X-Sucuri-Id", "", "sucuri waf"},
+	{"X-Iinfo", "", "imperva incapsula"},
+	{"X-Cdn", "incapsula", "imperva incapsula"},
+	{"Server", "awselb", "aws elb"},
+	{"X-Amzn-Waf-Action", "", "aws waf"},
+}
+
+// Collects HostInfo for every host the first time it answers, nil entries
+// while being filled in; nil when disabled
+type hostRecorder struct {
+	// Resolve host names; off when replaying a cassette
+	lookup bool
+	mutex  sync.Mutex
+	hosts  map[string]*HostInfo
+}
+
+func newHostRecorder(enabled, lookup bool) *hostRecorder {
+	if !enabled {
+		return nil
+	}
+	return &hostRecorder{lookup: lookup, hosts: make(map[string]*HostInfo)}
+}
+
+func (r *hostRecorder) record(resp *http.Response) {
+	host := strings.ToLower(resp.Request.URL.Hostname())
+	r.mutex.Lock()
+	if _, ok := r.hosts[host]; ok {
+		r.mutex.Unlock()
+		return
+	}
+	// Claimed until filled in, so the host is looked up once
+	r.hosts[host] = nil
+	r.mutex.Unlock()
+
+	info := &HostInfo{Host: host, FirstSeen: time.Now()}
+	info.Server = resp.Header.Get("Server")
+	info.PoweredBy = resp.Header.Get("X-Powered-By")
+	info.CDN = detectCDN(resp.Header)
+	info.HTTPVersion = resp.Proto
+	if resp.TLS != nil {
+		info.TLS = tlsInfo(resp.TLS)
+	}
+	if r.lookup {
+		info.IPs = lookupIPs(host)
+	}
+
+	r.mutex.Lock()
+	r.hosts[host] = info
+	r.mutex.Unlock()
+}
+
+func (r *hostRecorder) infos() []HostInfo {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	infos := make([]HostInfo, 0, len(r.hosts))
+	for _, info := range r.hosts {
+		if info != nil {
+			infos = append(infos, *info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Host < infos[j].Host })
+	return infos
+}
+
+func detectCDN(header http.Header) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, h := range cdnHeaders {
+		value := header.Get(h.header)
+		if value == "" || seen[h.name] || !strings.Contains(strings.ToLower(value), h.value) {
+			continue
+		}
+		seen[h.name] = true
+		names = append(names, h.name)
+	}
+	return names
+}
+
+func tlsInfo(state *tls.ConnectionState) *TLSInfo {
+	info := &TLSInfo{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Subject = cert.Subject.CommonName
+		info.Issuer = cert.Issuer.CommonName
+		info.DNSNames = cert.DNSNames
+		info.NotAfter = cert.NotAfter
+	}
+	return info
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+func lookupIPs(host string) []string {
+	if net.ParseIP(host) != nil {
+		return []string{host}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil
+	}
+	sort.Strings(ips)
+	return ips
+}
+
+// What was learned about every host that answered, sorted by host; empty
+// unless Config.HostInfo is set
+func (c *Crawler) HostInfos() []HostInfo {
+	if c.hosts == nil {
+		return nil
+	}
+	return c.hosts.infos()
+}
@@ -0,0 +1,71 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/user/gocrawler/pkg/parser"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Reports whether an hreflang value is the language or one of its regional
+// variants: "en" matches "en" and "en-gb"
+func matchesLanguage(lang, want string) bool {
+	lang, want = strings.ToLower(lang), strings.ToLower(want)
+	return lang == want || strings.HasPrefix(lang, want+"-")
+}
+
+// Sorts a page's alternates by Config.Language: variants in the language
+// are returned to be followed, and the others are remembered so links to
+// them are rejected. When no variant is in the language, x-default is
+// followed instead.
+func (c *Crawler) languageVariants(alternates []parser.Alternate) []string {
+	var follow, fallback []string
+	for _, alternate := range alternates {
+		switch {
+		case matchesLanguage(alternate.Lang, c.config.Language):
+			follow = append(follow, alternate.URL)
+		case alternate.Lang == "x-default":
+			fallback = append(fallback, alternate.URL)
+		}
+	}
+	if len(follow) == 0 {
+		follow = fallback
+	}
+
+	keep := make(map[string]bool, len(follow))
+	for _, link := range follow {
+		keep[link] = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, alternate := range alternates {
+		if !keep[alternate.URL] {
+			c.otherLanguages[alternate.URL] = true
+		}
+	}
+	// A URL can be another page's variant and still be in the language
+	for link := range keep {
+		delete(c.otherLanguages, link)
+	}
+	return follow
+}
+
+// Reports whether the URL is known to be a variant in another language
+func (c *Crawler) isOtherLanguage(link string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.otherLanguages[link]
+}
+
+func storageAlternates(alternates []parser.Alternate) []storage.Alternate {
+	if alternates == nil {
+		return nil
+	}
+
+	converted := make([]storage.Alternate, len(alternates))
+	for i, alternate := range alternates {
+		converted[i] = storage.Alternate{Lang: alternate.Lang, URL: alternate.URL}
+	}
+	return converted
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"github.com/user/gocrawler/pkg/frontier"
+)
+
+// A fetched page waiting to be parsed
+type parseJob struct {
+	item         frontier.URLItem
+	resp         *response
+	authRequired bool
+}
+
+// Parses the page on the fetch worker, or with ParseWorkers hands it to the
+// parse workers, waiting while their queue is full. Reports whether it was
+// handed off; the parse worker then finishes the item.
+func (c *Crawler) parse(job parseJob) bool {
+	if c.parseQueue == nil {
+		c.parsePage(job)
+		return false
+	}
+
+	select {
+	case c.parseQueue <- job:
+		return true
+	case <-c.ctx.Done():
+		c.release(job.resp)
+		return false
+	}
+}
+
+// Starts the parse workers; stopParseWorkers ends them once the fetch
+// workers are done
+func (c *Crawler) startParseWorkers() {
+	if c.parseQueue == nil {
+		return
+	}
+	for i := 0; i < c.config.ParseWorkers; i++ {
+		c.parseWG.Add(1)
+		go c.parseWorker()
+	}
+}
+
+func (c *Crawler) parseWorker() {
+	defer c.parseWG.Done()
+	for job := range c.parseQueue {
+		c.parsePage(job)
+		c.finishItem()
+	}
+}
+
+func (c *Crawler) stopParseWorkers() {
+	if c.parseQueue == nil {
+		return
+	}
+	close(c.parseQueue)
+	c.parseWG.Wait()
+}
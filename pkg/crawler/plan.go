@@ -0,0 +1,50 @@
+package crawler
+
+import (
+	"time"
+)
+
+// A seed page fetched for a crawl plan
+type SeedPreview struct {
+	URL   string
+	Links []string
+	Size  int64
+	// How long the fetch took, for estimating the rate of the crawl
+	FetchTime time.Duration
+	// Why the seed couldn't be previewed: a Reject* reason or a fetch or
+	// parse error
+	Reason string
+}
+
+// Fetches the given seed pages, and nothing else apart from robots.txt, for
+// a crawl plan. The crawler isn't started and its frontier is left alone.
+func (c *Crawler) PreviewSeeds(seedURLs []string) []SeedPreview {
+	previews := make([]SeedPreview, 0, len(seedURLs))
+	for _, seedURL := range seedURLs {
+		preview := SeedPreview{URL: seedURL, Reason: c.robotsDecision(seedURL)}
+		if preview.Reason != "" {
+			previews = append(previews, preview)
+			continue
+		}
+
+		start := time.Now()
+		resp, err := c.fetchURL(seedURL, "", nil)
+		preview.FetchTime = time.Since(start)
+		if err != nil {
+			preview.Reason = err.Error()
+			previews = append(previews, preview)
+			continue
+		}
+		preview.Size = int64(len(resp.body))
+
+		result, err := resp.handler.Handle(c.ctx, resp.body, seedURL)
+		c.release(resp)
+		if err != nil {
+			preview.Reason = err.Error()
+		} else {
+			preview.Links = result.Links
+		}
+		previews = append(previews, preview)
+	}
+	return previews
+}
@@ -0,0 +1,140 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Quotas a host can exhaust, see EventQuotaReached
+const (
+	QuotaRequests = "requests"
+	QuotaBytes    = "bytes"
+)
+
+// Counts requests and downloaded bytes per host against HostMaxRequests and
+// HostMaxBytes. A host that reached either is done; its remaining URLs are
+// rejected while the crawl goes on with the other hosts.
+type hostQuota struct {
+	maxRequests int
+	maxBytes    int64
+	mutex       sync.Mutex
+	hosts       map[string]*quotaUsage
+}
+
+type quotaUsage struct {
+	requests  int
+	bytes     int64
+	exhausted string
+}
+
+func newHostQuota(maxRequests int, maxBytes int64) *hostQuota {
+	if maxRequests <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &hostQuota{
+		maxRequests: maxRequests,
+		maxBytes:    maxBytes,
+		hosts:       make(map[string]*quotaUsage),
+	}
+}
+
+func (q *hostQuota) usage(host string) *quotaUsage {
+	usage, ok := q.hosts[host]
+	if !ok {
+		usage = &quotaUsage{}
+		q.hosts[host] = usage
+	}
+	return usage
+}
+
+// Reports whether host has used up a quota
+func (q *hostQuota) exhausted(host string) bool {
+	if q == nil {
+		return false
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	usage, ok := q.hosts[host]
+	return ok && usage.exhausted != ""
+}
+
+// Counts a request to host, reporting false when its quota is already used
+// up, and the quota this request used up, if any
+func (q *hostQuota) request(host string) (bool, string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	usage := q.usage(host)
+	if usage.exhausted != "" {
+		return false, ""
+	}
+	usage.requests++
+	if q.maxRequests > 0 && usage.requests >= q.maxRequests {
+		usage.exhausted = QuotaRequests
+		return true, QuotaRequests
+	}
+	return true, ""
+}
+
+// Counts bytes downloaded from host and returns the quota they used up, if any
+func (q *hostQuota) download(host string, n int64) string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	usage := q.usage(host)
+	usage.bytes += n
+	if q.maxBytes > 0 && usage.bytes >= q.maxBytes && usage.exhausted == "" {
+		usage.exhausted = QuotaBytes
+		return QuotaBytes
+	}
+	return ""
+}
+
+// Reports whether urlStr's host has used up its quota, counting the request
+// otherwise
+func (c *Crawler) quotaRequest(urlStr string) bool {
+	if c.quota == nil {
+		return true
+	}
+	host, ok := c.quotaHost(urlStr)
+	if !ok {
+		return true
+	}
+	allowed, reached := c.quota.request(host)
+	if reached != "" {
+		c.quotaReached(urlStr, host, reached)
+	}
+	return allowed
+}
+
+// Counts the bytes of a response from urlStr against its host's quota
+func (c *Crawler) quotaDownload(urlStr string, n int64) {
+	if c.quota == nil {
+		return
+	}
+	if host, ok := c.quotaHost(urlStr); ok {
+		if reached := c.quota.download(host, n); reached != "" {
+			c.quotaReached(urlStr, host, reached)
+		}
+	}
+}
+
+func (c *Crawler) quotaHost(urlStr string) (string, bool) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", false
+	}
+	return c.config.SiteGrouping.Key(parsedURL.Host), true
+}
+
+func (c *Crawler) quotaReached(urlStr, host, quota string) {
+	c.mutex.Lock()
+	c.stats.QuotaReached[host] = quota
+	c.mutex.Unlock()
+
+	if c.verbose.Load() {
+		fmt.Printf("%s used up its %s quota, skipping its remaining URLs\n", host, quota)
+	}
+	c.emit(Event{Type: EventQuotaReached, URL: urlStr, Host: host, Quota: quota})
+}
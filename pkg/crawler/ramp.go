@@ -0,0 +1,90 @@
+package crawler
+
+import (
+	"fmt"
+	"time"
+)
+
+// Requests made during ramp-up before its error rate is judged
+const rampMinAttempts = 10
+
+// Per-host delay at the start of a ramp-up: four times the configured delay,
+// and at least a second
+func (c *Crawler) rampStartDelay() time.Duration {
+	delay := 4 * c.baseDelay()
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay
+}
+
+// Grows the pool from one worker to WorkerCount and shrinks the extra
+// per-host delay to nothing over RampUp. When the error rate during the ramp
+// exceeds RampMaxErrorRate, the crawl stays at its current level instead.
+func (c *Crawler) rampUp() {
+	start := time.Now()
+	before := c.Stats()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := c.Stats()
+		attempts := (current.PagesCrawled + current.Errors) - (before.PagesCrawled + before.Errors)
+		errors := current.Errors - before.Errors
+		if c.config.RampMaxErrorRate > 0 && attempts >= rampMinAttempts &&
+			float64(errors)/float64(attempts) > c.config.RampMaxErrorRate {
+			fmt.Printf("Ramp-up aborted at %d workers: error rate %.0f%% is over %.0f%%\n",
+				c.WorkerCount(), 100*float64(errors)/float64(attempts), 100*c.config.RampMaxErrorRate)
+			return
+		}
+
+		progress := float64(time.Since(start)) / float64(c.config.RampUp)
+		if progress >= 1 {
+			c.mutex.Lock()
+			c.rampDelay = 0
+			c.mutex.Unlock()
+			c.SetWorkerCount(c.config.WorkerCount)
+
+			if c.verbose.Load() {
+				fmt.Println("Ramp-up complete")
+			}
+			if c.config.AutoScale {
+				c.autoScale()
+			}
+			return
+		}
+
+		workers := 1 + int(progress*float64(c.config.WorkerCount-1))
+		c.mutex.Lock()
+		c.rampDelay = time.Duration((1 - progress) * float64(c.rampStartDelay()))
+		grow := workers > c.targetWorkers
+		c.mutex.Unlock()
+
+		if grow {
+			c.SetWorkerCount(workers)
+		}
+	}
+}
+
+// The per-host delay between requests, raised while ramping up and after
+// blocks
+func (c *Crawler) hostDelay() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delay := c.config.Delay
+	if c.rampDelay > delay {
+		delay = c.rampDelay
+	}
+	if c.blockBackoff > delay {
+		delay = c.blockBackoff
+	}
+	return delay
+}
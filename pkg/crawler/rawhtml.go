@@ -0,0 +1,53 @@
+package crawler
+
+import (
+	"fmt"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Values for Config.RawHTML
+const (
+	RawHTMLInline = "inline"
+	RawHTMLGzip   = "gzip"
+)
+
+type rawHTML struct {
+	inline   string
+	encoding string
+	file     string
+}
+
+// Prepares the raw body for the record as configured. Failures only cost
+// the raw copy, not the page.
+func (c *Crawler) rawHTML(urlStr string, body []byte) rawHTML {
+	var raw rawHTML
+
+	switch c.config.RawHTML {
+	case RawHTMLInline:
+		raw.inline = string(body)
+	case RawHTMLGzip:
+		encoded, err := storage.EncodeRawHTML(body)
+		if err != nil {
+			if c.verbose.Load() {
+				fmt.Printf("Warning: %s: %v\n", urlStr, err)
+			}
+			break
+		}
+		raw.inline = encoded
+		raw.encoding = storage.RawHTMLGzipBase64
+	}
+
+	if c.config.RawHTMLDir != nil {
+		path, err := c.config.RawHTMLDir.Write(urlStr, body)
+		if err != nil {
+			if c.verbose.Load() {
+				fmt.Printf("Warning: %s: %v\n", urlStr, err)
+			}
+		} else {
+			raw.file = path
+		}
+	}
+
+	return raw
+}
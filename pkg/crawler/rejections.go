@@ -0,0 +1,44 @@
+package crawler
+
+import (
+	"fmt"
+)
+
+// Reasons a discovered or dequeued URL was not crawled, in addition to the
+// frontier's own rejection reasons
+const (
+	RejectSkipRule  = "skip-rule"
+	RejectRobots    = "robots"
+	RejectOffDomain = "off-domain"
+	RejectFilter    = "filter"
+	RejectDepth     = "depth"
+	RejectSeedOnly  = "seed-only"
+	RejectLanguage  = "language"
+	RejectAMP       = "amp"
+	RejectQuota     = "quota"
+
+	// Fetched pages dropped as duplicates by a page-level dedup strategy;
+	// the source column holds the page they duplicate
+	RejectDuplicateCanonical = "duplicate-canonical"
+	RejectDuplicateContent   = "duplicate-content"
+	RejectNearDuplicate      = "near-duplicate"
+	// Queued under one URL but known to lead to a page crawled under
+	// another, or redirected there; see the aliases dedup strategy
+	RejectDuplicateAlias = "duplicate-alias"
+)
+
+// Counts a rejected URL by reason and, when a rejected-URL log is configured,
+// appends a tab-separated line: reason, URL, page it was found on
+func (c *Crawler) reject(rawURL, source, reason string) {
+	c.mutex.Lock()
+	c.stats.Rejected[reason]++
+	c.mutex.Unlock()
+
+	if c.config.RejectedLog == nil {
+		return
+	}
+
+	c.rejectedLogMutex.Lock()
+	defer c.rejectedLogMutex.Unlock()
+	fmt.Fprintf(c.config.RejectedLog, "%s\t%s\t%s\n", reason, rawURL, source)
+}
@@ -0,0 +1,96 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Settings that can be changed while a crawl runs, keeping the frontier;
+// nil fields are left as they are
+type Reload struct {
+	Delay       *time.Duration
+	WorkerCount *int
+	URLFilter   *string
+	Verbose     *bool
+}
+
+// Decodes reloadable settings from JSON such as
+// {"delay": "2s", "workers": 4, "filter": "/blog/", "verbose": true}.
+// Settings left out are not changed.
+func ParseReload(data []byte) (Reload, error) {
+	var raw struct {
+		Delay   *string `json:"delay"`
+		Workers *int    `json:"workers"`
+		Filter  *string `json:"filter"`
+		Verbose *bool   `json:"verbose"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return Reload{}, fmt.Errorf("failed to parse settings: %w", err)
+	}
+
+	r := Reload{WorkerCount: raw.Workers, URLFilter: raw.Filter, Verbose: raw.Verbose}
+	if raw.Delay != nil {
+		delay, err := time.ParseDuration(*raw.Delay)
+		if err != nil || delay < 0 {
+			return Reload{}, fmt.Errorf("invalid delay %q", *raw.Delay)
+		}
+		r.Delay = &delay
+	}
+	if r.WorkerCount != nil && *r.WorkerCount < 1 {
+		return Reload{}, fmt.Errorf("workers must be at least 1")
+	}
+	return r, nil
+}
+
+// Describes the settings the reload changes, e.g. "delay=2s workers=4"
+func (r Reload) String() string {
+	var changes []string
+	if r.Delay != nil {
+		changes = append(changes, fmt.Sprintf("delay=%v", *r.Delay))
+	}
+	if r.WorkerCount != nil {
+		changes = append(changes, fmt.Sprintf("workers=%d", *r.WorkerCount))
+	}
+	if r.URLFilter != nil {
+		changes = append(changes, fmt.Sprintf("filter=%q", *r.URLFilter))
+	}
+	if r.Verbose != nil {
+		changes = append(changes, fmt.Sprintf("verbose=%v", *r.Verbose))
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, " ")
+}
+
+// Applies new settings to the running crawl. Queued URLs stay queued; the
+// filter applies to links found from now on.
+func (c *Crawler) Reload(r Reload) {
+	c.mutex.Lock()
+	if r.Delay != nil {
+		c.config.Delay = *r.Delay
+	}
+	if r.URLFilter != nil {
+		c.config.URLFilter = *r.URLFilter
+	}
+	c.mutex.Unlock()
+
+	if r.Verbose != nil {
+		c.verbose.Store(*r.Verbose)
+	}
+	if r.WorkerCount != nil {
+		c.SetWorkerCount(*r.WorkerCount)
+	}
+}
+
+// The configured per-host delay, before ramp-up and block back-off
+func (c *Crawler) baseDelay() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.config.Delay
+}
@@ -0,0 +1,27 @@
+package crawler
+
+import (
+	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Continues from the records of an earlier run, before Start: the links they
+// saved are queued as if just found on their pages. The records' own URLs
+// should already be marked visited in the frontier so they aren't fetched
+// again. Returns the number of links queued.
+func (c *Crawler) Resume(records []storage.PageData) int {
+	queued := 0
+	for _, record := range records {
+		if record.Error != "" {
+			continue
+		}
+		item := frontier.URLItem{
+			URL:     record.URL,
+			Depth:   record.Depth,
+			SeedTag: record.SeedTag,
+			Tags:    record.Tags,
+		}
+		queued += c.followLinks(item, record.Links, nil, record.KeywordScore)
+	}
+	return queued
+}
@@ -0,0 +1,62 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/user/gocrawler/pkg/frontier"
+)
+
+// Reports whether a fetch error may go away if the URL is tried again later
+func isTransient(err error) bool {
+	if isHostFailure(err) {
+		return true
+	}
+
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return true
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusRequestTimeout || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	// Refused and reset connections
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// Hands a failed item back to the frontier for a later attempt, or records
+// it as rejected once its attempts are used up. Reports whether it was
+// requeued.
+func (c *Crawler) retry(item frontier.URLItem, err error) bool {
+	delay := c.config.RetryDelay << item.Attempts
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+
+	if reason := c.frontier.Retry(item, delay); reason != frontier.Accepted {
+		if c.verbose.Load() {
+			fmt.Printf("Giving up on %s after %d attempts\n", item.URL, c.config.MaxAttempts)
+		}
+		c.reject(item.URL, item.Parent, string(reason))
+		return false
+	}
+
+	c.mutex.Lock()
+	c.stats.Retries++
+	c.mutex.Unlock()
+	if c.verbose.Load() {
+		fmt.Printf("Retrying %s in %v (attempt %d of %d)\n", item.URL, delay.Round(time.Millisecond), item.Attempts+2, c.config.MaxAttempts)
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package crawler
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Largest read between bandwidth checks, so a single read can't overshoot a
+// low limit by much
+const throttleChunk = 16 << 10
+
+// Token bucket over bytes. Reads take tokens as they complete and sleep off
+// any debt, so concurrent readers share the rate.
+type tokenBucket struct {
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) take(ctx context.Context, n int) error {
+	b.mutex.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate // at most one second of burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	debt := b.tokens
+	b.mutex.Unlock()
+
+	if debt >= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(-debt / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type throttledReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	buckets []*tokenBucket
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+
+	n, err := t.reader.Read(p)
+	for _, bucket := range t.buckets {
+		if waitErr := bucket.take(t.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Wraps a response body in the global and per-host bandwidth limits
+func (c *Crawler) throttle(body io.Reader, host string) io.Reader {
+	var buckets []*tokenBucket
+	if c.bandwidth != nil {
+		buckets = append(buckets, c.bandwidth)
+	}
+
+	if c.config.HostBandwidthLimit > 0 {
+		c.hostBandwidthMutex.Lock()
+		bucket, ok := c.hostBandwidth[host]
+		if !ok {
+			bucket = newTokenBucket(c.config.HostBandwidthLimit)
+			c.hostBandwidth[host] = bucket
+		}
+		c.hostBandwidthMutex.Unlock()
+		buckets = append(buckets, bucket)
+	}
+
+	if len(buckets) == 0 {
+		return body
+	}
+	return &throttledReader{ctx: c.ctx, reader: body, buckets: buckets}
+}
@@ -0,0 +1,87 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Each request uses the next agent in the list
+	RotatePerRequest = "request"
+	// Each host always gets the same agent
+	RotatePerHost = "host"
+)
+
+// Picks the User-Agent header for a request. Without a rotation list every
+// request uses Config.UserAgent, which is also the agent robots.txt rules are
+// always checked for.
+type userAgentPicker struct {
+	agents  []string
+	perHost bool
+	next    atomic.Uint64
+}
+
+func newUserAgentPicker(config Config) *userAgentPicker {
+	return &userAgentPicker{
+		agents:  config.UserAgents,
+		perHost: config.UserAgentRotation != RotatePerRequest,
+	}
+}
+
+func (p *userAgentPicker) pick(rawURL, fallback string) string {
+	if len(p.agents) == 0 {
+		return fallback
+	}
+
+	if p.perHost {
+		host := rawURL
+		if parsedURL, err := url.Parse(rawURL); err == nil {
+			host = strings.ToLower(parsedURL.Hostname())
+		}
+		h := fnv.New32a()
+		h.Write([]byte(host))
+		return p.agents[h.Sum32()%uint32(len(p.agents))]
+	}
+
+	n := p.next.Add(1) - 1
+	return p.agents[n%uint64(len(p.agents))]
+}
+
+// Identifies the crawler to site owners on every request, robots.txt
+// included: a From header with a contact address and a "+URL" comment in the
+// User-Agent pointing at a page about the crawl
+type identityTransport struct {
+	next    http.RoundTripper
+	from    string
+	infoURL string
+}
+
+func newIdentityTransport(next http.RoundTripper, from, infoURL string) http.RoundTripper {
+	if from == "" && infoURL == "" {
+		return next
+	}
+	return &identityTransport{next: next, from: from, infoURL: infoURL}
+}
+
+func (t *identityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.from != "" {
+		req.Header.Set("From", t.from)
+	}
+	if t.infoURL != "" {
+		req.Header.Set("User-Agent", WithContact(req.Header.Get("User-Agent"), t.infoURL))
+	}
+	return t.next.RoundTrip(req)
+}
+
+// Adds the crawl info URL to a User-Agent as a comment:
+// "GoCrawler/1.0 (+https://example.com/bot)"
+func WithContact(userAgent, infoURL string) string {
+	if infoURL == "" || strings.Contains(userAgent, "+"+infoURL) {
+		return userAgent
+	}
+	return strings.TrimSpace(userAgent + " (+" + infoURL + ")")
+}
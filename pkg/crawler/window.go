@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Daily time window in which fetching is allowed, e.g. 22:00-06:00. A window
+// ending before it starts spans midnight. The zero Window allows any time.
+type Window struct {
+	Start    time.Duration // since midnight
+	End      time.Duration
+	Location *time.Location
+}
+
+// Parses "HH:MM-HH:MM" in the given location (nil = local time)
+func ParseWindow(s string, loc *time.Location) (Window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("expected HH:MM-HH:MM, got %q", s)
+	}
+
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, err
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, err
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("window %q is empty", s)
+	}
+
+	if loc == nil {
+		loc = time.Local
+	}
+	return Window{Start: start, End: end, Location: loc}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (want HH:MM)", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w Window) IsZero() bool {
+	return w.Start == w.End
+}
+
+// Returns how long until the window opens; zero when t is inside it
+func (w Window) Until(t time.Time) time.Duration {
+	if w.IsZero() {
+		return 0
+	}
+
+	// Wall clock times, so a DST change doesn't shift the window
+	t = t.In(w.Location)
+	now := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+
+	inside := now >= w.Start && now < w.End
+	if w.End < w.Start {
+		inside = now >= w.Start || now < w.End
+	}
+	if inside {
+		return 0
+	}
+
+	open := w.startOn(t, 0)
+	if !open.After(t) {
+		open = w.startOn(t, 1)
+	}
+	return open.Sub(t)
+}
+
+// The window's start on the day days after t's
+func (w Window) startOn(t time.Time, days int) time.Time {
+	hour := int(w.Start / time.Hour)
+	minute := int(w.Start % time.Hour / time.Minute)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, hour, minute, 0, 0, w.Location)
+}
+
+func (w Window) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End) + " " + w.Location.String()
+}
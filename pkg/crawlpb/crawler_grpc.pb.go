@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: crawler.proto
+
+package crawlpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Crawler_SubmitCrawl_FullMethodName   = "/gocrawler.v1.Crawler/SubmitCrawl"
+	Crawler_StreamResults_FullMethodName = "/gocrawler.v1.Crawler/StreamResults"
+	Crawler_GetStats_FullMethodName      = "/gocrawler.v1.Crawler/GetStats"
+	Crawler_CancelCrawl_FullMethodName   = "/gocrawler.v1.Crawler/CancelCrawl"
+)
+
+// CrawlerClient is the client API for Crawler service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CrawlerClient interface {
+	// Queues a crawl job
+	SubmitCrawl(ctx context.Context, in *SubmitCrawlRequest, opts ...grpc.CallOption) (*Job, error)
+	// Streams pages as the job saves them, ending when the job finishes.
+	// Pages saved before the stream was opened are not replayed.
+	StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Crawler_StreamResultsClient, error)
+	// Returns the job status and crawl statistics
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Job, error)
+	// Cancels a queued job or stops a running one
+	CancelCrawl(ctx context.Context, in *CancelCrawlRequest, opts ...grpc.CallOption) (*Job, error)
+}
+
+type crawlerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrawlerClient(cc grpc.ClientConnInterface) CrawlerClient {
+	return &crawlerClient{cc}
+}
+
+func (c *crawlerClient) SubmitCrawl(ctx context.Context, in *SubmitCrawlRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Crawler_SubmitCrawl_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerClient) StreamResults(ctx context.Context, in *StreamResultsRequest, opts ...grpc.CallOption) (Crawler_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Crawler_ServiceDesc.Streams[0], Crawler_StreamResults_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &crawlerStreamResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Crawler_StreamResultsClient interface {
+	Recv() (*Page, error)
+	grpc.ClientStream
+}
+
+type crawlerStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *crawlerStreamResultsClient) Recv() (*Page, error) {
+	m := new(Page)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *crawlerClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Crawler_GetStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crawlerClient) CancelCrawl(ctx context.Context, in *CancelCrawlRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, Crawler_CancelCrawl_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CrawlerServer is the server API for Crawler service.
+// All implementations must embed UnimplementedCrawlerServer
+// for forward compatibility
+type CrawlerServer interface {
+	// Queues a crawl job
+	SubmitCrawl(context.Context, *SubmitCrawlRequest) (*Job, error)
+	// Streams pages as the job saves them, ending when the job finishes.
+	// Pages saved before the stream was opened are not replayed.
+	StreamResults(*StreamResultsRequest, Crawler_StreamResultsServer) error
+	// Returns the job status and crawl statistics
+	GetStats(context.Context, *GetStatsRequest) (*Job, error)
+	// Cancels a queued job or stops a running one
+	CancelCrawl(context.Context, *CancelCrawlRequest) (*Job, error)
+	mustEmbedUnimplementedCrawlerServer()
+}
+
+// UnimplementedCrawlerServer must be embedded to have forward compatible implementations.
+type UnimplementedCrawlerServer struct {
+}
+
+func (UnimplementedCrawlerServer) SubmitCrawl(context.Context, *SubmitCrawlRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitCrawl not implemented")
+}
+func (UnimplementedCrawlerServer) StreamResults(*StreamResultsRequest, Crawler_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (UnimplementedCrawlerServer) GetStats(context.Context, *GetStatsRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedCrawlerServer) CancelCrawl(context.Context, *CancelCrawlRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelCrawl not implemented")
+}
+func (UnimplementedCrawlerServer) mustEmbedUnimplementedCrawlerServer() {}
+
+// UnsafeCrawlerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrawlerServer will
+// result in compilation errors.
+type UnsafeCrawlerServer interface {
+	mustEmbedUnimplementedCrawlerServer()
+}
+
+func RegisterCrawlerServer(s grpc.ServiceRegistrar, srv CrawlerServer) {
+	s.RegisterService(&Crawler_ServiceDesc, srv)
+}
+
+func _Crawler_SubmitCrawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitCrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServer).SubmitCrawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Crawler_SubmitCrawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServer).SubmitCrawl(ctx, req.(*SubmitCrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crawler_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamResultsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrawlerServer).StreamResults(m, &crawlerStreamResultsServer{stream})
+}
+
+type Crawler_StreamResultsServer interface {
+	Send(*Page) error
+	grpc.ServerStream
+}
+
+type crawlerStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *crawlerStreamResultsServer) Send(m *Page) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Crawler_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Crawler_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Crawler_CancelCrawl_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelCrawlRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrawlerServer).CancelCrawl(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Crawler_CancelCrawl_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrawlerServer).CancelCrawl(ctx, req.(*CancelCrawlRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Crawler_ServiceDesc is the grpc.ServiceDesc for Crawler service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Crawler_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gocrawler.v1.Crawler",
+	HandlerType: (*CrawlerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitCrawl",
+			Handler:    _Crawler_SubmitCrawl_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Crawler_GetStats_Handler,
+		},
+		{
+			MethodName: "CancelCrawl",
+			Handler:    _Crawler_CancelCrawl_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _Crawler_StreamResults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crawler.proto",
+}
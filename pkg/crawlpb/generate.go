@@ -0,0 +1,5 @@
+// Package crawlpb holds the generated gRPC stubs for the crawl job API
+// defined in proto/crawler.proto.
+package crawlpb
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative crawler.proto
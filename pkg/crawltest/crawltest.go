@@ -0,0 +1,265 @@
+// Package crawltest serves synthetic sites from a local httptest server, for
+// end-to-end tests of crawl configurations.
+//
+//	site := crawltest.NewServer(crawltest.Config{
+//		Pages:  crawltest.Tree(3, 4),
+//		Robots: "User-agent: *\nDisallow: /p/1/\n",
+//	})
+//	defer site.Close()
+//	frontier.Add(site.URL("/"), 0)
+package crawltest
+
+import (
+	"fmt"
+	"html"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// One page of a synthetic site
+type Page struct {
+	Title string
+	// Paths on the site, or absolute URLs
+	Links []string
+	// Extra HTML placed in the body; Links are listed after it
+	Body string
+	// Replaces the generated HTML entirely when set
+	Raw         string
+	ContentType string // default text/html; charset=utf-8
+	Status      int    // default 200
+	Header      http.Header
+	// Redirect to this path or URL with Status (default 302)
+	Redirect string
+	// Wait this long before answering, on top of Config.Latency
+	Latency time.Duration
+	// Answer the first FailFirst requests with 503
+	FailFirst int
+}
+
+type Config struct {
+	// Pages by path; paths not listed answer 404
+	Pages map[string]Page
+	// robots.txt content; empty answers 404
+	Robots string
+	// Added to every response
+	Latency time.Duration
+	// Fraction of page requests, 0 to 1, answered with 500 at random
+	ErrorRate float64
+	// Seeds ErrorRate's random choices, for repeatable runs
+	RandomSeed int64
+}
+
+type Server struct {
+	*httptest.Server
+	config Config
+
+	mutex    sync.Mutex
+	random   *rand.Rand
+	requests map[string]int
+}
+
+// Starts serving the site; Close stops it
+func NewServer(config Config) *Server {
+	s := &Server{
+		config:   config,
+		random:   rand.New(rand.NewSource(config.RandomSeed)),
+		requests: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Returns the absolute URL of a path on the site
+func (s *Server) URL(path string) string {
+	return s.Server.URL + path
+}
+
+// Number of requests the path has received, robots.txt included
+func (s *Server) Requests(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[path]
+}
+
+// Number of requests across all paths
+func (s *Server) TotalRequests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	total := 0
+	for _, count := range s.requests {
+		total += count
+	}
+	return total
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	s.mutex.Lock()
+	s.requests[path]++
+	count := s.requests[path]
+	injectError := s.config.ErrorRate > 0 && path != "/robots.txt" && s.random.Float64() < s.config.ErrorRate
+	s.mutex.Unlock()
+
+	page, found := s.config.Pages[path]
+	if !s.sleep(r, s.config.Latency+page.Latency) {
+		return
+	}
+
+	if path == "/robots.txt" && !found {
+		if s.config.Robots == "" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, s.config.Robots)
+		return
+	}
+
+	switch {
+	case !found:
+		http.NotFound(w, r)
+	case injectError:
+		http.Error(w, "injected error", http.StatusInternalServerError)
+	case count <= page.FailFirst:
+		http.Error(w, "injected failure", http.StatusServiceUnavailable)
+	default:
+		s.writePage(w, r, page)
+	}
+}
+
+// Waits out a latency, giving up when the client goes away
+func (s *Server) sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, page Page) {
+	for name, values := range page.Header {
+		w.Header()[name] = values
+	}
+
+	if page.Redirect != "" {
+		status := page.Status
+		if status == 0 {
+			status = http.StatusFound
+		}
+		http.Redirect(w, r, page.Redirect, status)
+		return
+	}
+
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if page.Status != 0 {
+		w.WriteHeader(page.Status)
+	}
+
+	if page.Raw != "" {
+		fmt.Fprint(w, page.Raw)
+		return
+	}
+	fmt.Fprint(w, page.HTML())
+}
+
+// Renders the page's title, body and links as an HTML document
+func (p Page) HTML() string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><title>")
+	b.WriteString(html.EscapeString(p.Title))
+	b.WriteString("</title></head><body>\n")
+	b.WriteString(p.Body)
+	for _, link := range p.Links {
+		fmt.Fprintf(&b, "<p><a href=\"%s\">%s</a></p>\n", html.EscapeString(link), html.EscapeString(link))
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+// Path of the nth generated page; page 0 is the home page "/"
+func PagePath(n int) string {
+	if n == 0 {
+		return "/"
+	}
+	return fmt.Sprintf("/p/%d", n)
+}
+
+// n pages, each linking to the next: the deepest page is n-1 hops from "/"
+func Chain(n int) map[string]Page {
+	return Graph(n, func(i int) []int {
+		if i+1 < n {
+			return []int{i + 1}
+		}
+		return nil
+	})
+}
+
+// A tree of the given depth below "/" where every page links to fanout
+// children
+func Tree(depth, fanout int) map[string]Page {
+	pages := make(map[string]Page)
+	var add func(path string, level int)
+	add = func(path string, level int) {
+		page := Page{Title: "Page " + path}
+		if level < depth {
+			for i := 0; i < fanout; i++ {
+				child := fmt.Sprintf("%s%d/", path, i)
+				if path == "/" {
+					child = fmt.Sprintf("/p/%d/", i)
+				}
+				page.Links = append(page.Links, child)
+				add(child, level+1)
+			}
+		}
+		pages[path] = page
+	}
+	add("/", 0)
+	return pages
+}
+
+// n pages with linksPerPage links each to pages chosen at random, plus a link
+// from each page to the next so every page is reachable from "/"
+func Random(n, linksPerPage int, seed int64) map[string]Page {
+	random := rand.New(rand.NewSource(seed))
+	return Graph(n, func(i int) []int {
+		var targets []int
+		if i+1 < n {
+			targets = append(targets, i+1)
+		}
+		for j := 0; j < linksPerPage; j++ {
+			targets = append(targets, random.Intn(n))
+		}
+		return targets
+	})
+}
+
+// n pages where page i links to the pages returned by links(i)
+func Graph(n int, links func(i int) []int) map[string]Page {
+	pages := make(map[string]Page, n)
+	for i := 0; i < n; i++ {
+		page := Page{Title: fmt.Sprintf("Page %d", i)}
+		for _, target := range links(i) {
+			page.Links = append(page.Links, PagePath(target))
+		}
+		pages[PagePath(i)] = page
+	}
+	return pages
+}
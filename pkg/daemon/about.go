@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+)
+
+// Who runs the crawler, for site owners; see SetIdentity
+type Identity struct {
+	Operator string // name of the organization running the crawls
+	From     string // contact e-mail, sent as the From header
+	InfoURL  string // page about the crawls, linked from the User-Agent
+}
+
+// Sends the identity with every job's requests and describes it on /about
+func (m *Manager) SetIdentity(identity Identity) {
+	m.mutex.Lock()
+	m.identity = identity
+	m.mutex.Unlock()
+}
+
+var aboutTemplate = template.Must(template.New("about").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>About this crawler</title></head>
+<body>
+<h1>About this crawler</h1>
+<p>This web crawler{{if .Operator}} is operated by {{.Operator}}. It{{end}} fetches public pages and
+respects robots.txt. It identifies itself with the User-Agent
+{{range $i, $agent := .Agents}}{{if $i}}, {{end}}<code>{{$agent}}</code>{{end}}.</p>
+{{if .From}}<p>Contact: <a href="mailto:{{.From}}">{{.From}}</a></p>{{end}}
+{{if .InfoURL}}<p>More information: <a href="{{.InfoURL}}">{{.InfoURL}}</a></p>{{end}}
+<h2>Opting out</h2>
+<p>To keep it off your site, add to your robots.txt:</p>
+<pre>{{range .Agents}}User-agent: {{.}}
+{{end}}Disallow: /</pre>
+<p>A Crawl-delay in the same group slows it down instead.</p>
+</body></html>
+`))
+
+// Describes the crawler to site owners who follow the link in its User-Agent
+func (m *Manager) handleAbout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	m.mutex.Lock()
+	identity := m.identity
+	seen := map[string]bool{DefaultJobSpec().UserAgent: true}
+	for _, j := range m.jobs {
+		seen[j.spec.UserAgent] = true
+	}
+	m.mutex.Unlock()
+
+	agents := make([]string, 0, len(seen))
+	for agent := range seen {
+		if agent != "" {
+			agents = append(agents, agent)
+		}
+	}
+	sort.Strings(agents)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	aboutTemplate.Execute(w, struct {
+		Identity
+		Agents []string
+	}{identity, agents})
+}
@@ -0,0 +1,418 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/user/gocrawler/pkg/crawler"
+	"github.com/user/gocrawler/pkg/frontier"
+	"github.com/user/gocrawler/pkg/robotstxt"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+var (
+	ErrNotFound   = errors.New("job not found")
+	ErrQueueFull  = errors.New("job queue is full")
+	ErrFinished   = errors.New("job has already finished")
+	ErrShutdown   = errors.New("daemon is shutting down")
+	ErrNotRunning = errors.New("job is not running")
+)
+
+// Runs submitted crawl jobs in the background, each with its own frontier
+// and output file, at most concurrency jobs at a time
+type Manager struct {
+	outputDir string
+	// Shared by all jobs when set, so they and later runs reuse robots.txt
+	robots   *robotstxt.RobotsCache
+	identity Identity
+	queue    chan *job
+	mutex    sync.Mutex
+	jobs     map[string]*job
+	order    []string
+	wg       sync.WaitGroup
+	done     chan struct{}
+	closed   bool
+}
+
+func NewManager(outputDir string, concurrency int) (*Manager, error) {
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	m := &Manager{
+		outputDir: outputDir,
+		queue:     make(chan *job, 1000),
+		jobs:      make(map[string]*job),
+		done:      make(chan struct{}),
+	}
+
+	for i := 0; i < concurrency; i++ {
+		m.wg.Add(1)
+		go m.runner()
+	}
+
+	return m, nil
+}
+
+// Makes every job use the same robots.txt cache, e.g. one kept on disk
+// across restarts
+func (m *Manager) SetRobotsCache(robots *robotstxt.RobotsCache) {
+	m.mutex.Lock()
+	m.robots = robots
+	m.mutex.Unlock()
+}
+
+func (m *Manager) Submit(spec JobSpec) (JobStatus, error) {
+	if spec.Format == "" {
+		spec.Format = "json"
+	}
+	if err := spec.validate(); err != nil {
+		return JobStatus{}, err
+	}
+
+	id := newJobID()
+	output, _ := storage.CompressedFilename(filepath.Join(m.outputDir, id+formatExtensions[spec.Format]), spec.Compress)
+	j := &job{
+		id:        id,
+		spec:      spec,
+		output:    output,
+		status:    StatusQueued,
+		createdAt: time.Now(),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return JobStatus{}, ErrShutdown
+	}
+
+	select {
+	case m.queue <- j:
+	default:
+		return JobStatus{}, ErrQueueFull
+	}
+
+	m.jobs[id] = j
+	m.order = append(m.order, id)
+	return j.view(), nil
+}
+
+func (m *Manager) Get(id string) (JobStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobStatus{}, ErrNotFound
+	}
+	return j.view(), nil
+}
+
+func (m *Manager) List() []JobStatus {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(m.order))
+	for _, id := range m.order {
+		statuses = append(statuses, m.jobs[id].view())
+	}
+	return statuses
+}
+
+// Cancels a queued job or stops a running one; a stopped job keeps the
+// pages it crawled so far
+func (m *Manager) Cancel(id string) (JobStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobStatus{}, ErrNotFound
+	}
+
+	switch j.status {
+	case StatusQueued:
+		j.status = StatusCancelled
+		j.finishedAt = time.Now()
+		j.closeStreams()
+	case StatusRunning:
+		j.status = StatusCancelled
+		j.crawler.Stop()
+	default:
+		return j.view(), ErrFinished
+	}
+	return j.view(), nil
+}
+
+// Applies new settings to a running job without restarting it; the job's
+// spec is updated to match
+func (m *Manager) Reload(id string, r crawler.Reload) (JobStatus, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return JobStatus{}, ErrNotFound
+	}
+	if j.status != StatusRunning {
+		return j.view(), ErrNotRunning
+	}
+	j.reload(r)
+	return j.view(), nil
+}
+
+// Applies new settings to every running job, returning how many there were
+func (m *Manager) ReloadAll(r crawler.Reload) int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	count := 0
+	for _, j := range m.jobs {
+		if j.status == StatusRunning {
+			j.reload(r)
+			count++
+		}
+	}
+	return count
+}
+
+// Stops accepting jobs, stops running crawls and waits for them to finish
+// writing their output
+func (m *Manager) Shutdown() {
+	m.mutex.Lock()
+	if m.closed {
+		m.mutex.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.done)
+	for _, j := range m.jobs {
+		switch j.status {
+		case StatusQueued:
+			j.status = StatusCancelled
+			j.finishedAt = time.Now()
+			j.closeStreams()
+		case StatusRunning:
+			j.status = StatusCancelled
+			j.crawler.Stop()
+		}
+	}
+	m.mutex.Unlock()
+
+	m.wg.Wait()
+}
+
+func (m *Manager) runner() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case j := <-m.queue:
+			m.run(j)
+		}
+	}
+}
+
+func (m *Manager) run(j *job) {
+	m.mutex.Lock()
+	if j.status != StatusQueued {
+		m.mutex.Unlock()
+		return
+	}
+
+	store, err := storage.Open(j.spec.Format, j.output, storage.Options{Compress: j.spec.Compress})
+	if err != nil {
+		j.status = StatusFailed
+		j.err = err.Error()
+		j.finishedAt = time.Now()
+		j.closeStreams()
+		m.mutex.Unlock()
+		return
+	}
+
+	urlFrontier := frontier.NewURLFrontier()
+	for _, seed := range append([]string{j.spec.Seed}, j.spec.Seeds...) {
+		if seed != "" {
+			urlFrontier.Add(seed, 0)
+		}
+	}
+
+	config := j.spec.crawlerConfig(j.id)
+	config.RobotsCache = m.robots
+	config.From = m.identity.From
+	config.CrawlInfoURL = m.identity.InfoURL
+	if j.spec.Format == "graph" || j.spec.Format == "hosts" {
+		config.ExtractLinks = true
+	}
+
+	c := crawler.New(config, urlFrontier, &publishingStorage{Storage: store, manager: m, job: j})
+	j.crawler = c
+	j.status = StatusRunning
+	j.startedAt = time.Now()
+	m.mutex.Unlock()
+
+	crawlErr := c.Start()
+	closeErr := store.Close()
+	stats := c.Stats()
+
+	err = storage.WriteRunMetadata(j.output, storage.RunMetadata{
+		RunID:        j.id,
+		Version:      "daemon",
+		StartTime:    stats.StartTime,
+		EndTime:      stats.EndTime,
+		PagesCrawled: stats.PagesCrawled,
+		Config:       j.spec.snapshot(),
+	})
+	if err != nil {
+		log.Printf("Job %s: failed to write run metadata: %v", j.id, err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	j.stats = stats
+	j.finishedAt = time.Now()
+	j.closeStreams()
+	if runErr := errors.Join(crawlErr, closeErr); runErr != nil {
+		j.status = StatusFailed
+		j.err = runErr.Error()
+	} else if j.status == StatusRunning {
+		j.status = StatusCompleted
+	}
+}
+
+// Pages saved by a job, delivered to one StreamResults caller
+type stream struct {
+	pages chan storage.PageData
+	done  chan struct{}
+}
+
+// Returns the pages saved by a queued or running job from now on. The
+// channel is closed when the job finishes; call stop when no longer
+// reading. A slow reader holds up the job's workers rather than losing pages.
+func (m *Manager) Subscribe(id string) (<-chan storage.PageData, func(), error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	if j.status != StatusQueued && j.status != StatusRunning {
+		return nil, nil, ErrFinished
+	}
+
+	s := &stream{
+		pages: make(chan storage.PageData, 64),
+		done:  make(chan struct{}),
+	}
+	if j.streams == nil {
+		j.streams = make(map[*stream]struct{})
+	}
+	j.streams[s] = struct{}{}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(s.done)
+			m.mutex.Lock()
+			delete(j.streams, s)
+			m.mutex.Unlock()
+		})
+	}
+	return s.pages, stop, nil
+}
+
+func (m *Manager) publish(j *job, page storage.PageData) {
+	m.mutex.Lock()
+	streams := make([]*stream, 0, len(j.streams))
+	for s := range j.streams {
+		streams = append(streams, s)
+	}
+	m.mutex.Unlock()
+
+	for _, s := range streams {
+		select {
+		case s.pages <- page:
+		case <-s.done:
+		}
+	}
+}
+
+// Passes saved pages on to the job's result streams
+type publishingStorage struct {
+	storage.Storage
+	manager *Manager
+	job     *job
+}
+
+func (s *publishingStorage) Save(ctx context.Context, page storage.PageData) error {
+	if err := s.Storage.Save(ctx, page); err != nil {
+		return err
+	}
+	s.manager.publish(s.job, page)
+	return nil
+}
+
+// Polls dir for *.json job specs and submits them. Accepted files are
+// renamed to <name>.<job id>.accepted, invalid ones to <name>.rejected with
+// the reason written to <name>.rejected.txt.
+func (m *Manager) WatchDir(dir string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.scanDir(dir)
+
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) scanDir(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		status, err := m.submitFile(file)
+		if err != nil {
+			log.Printf("Rejected job file %s: %v", file, err)
+			os.Rename(file, file+".rejected")
+			os.WriteFile(file+".rejected.txt", []byte(err.Error()+"\n"), 0o644)
+			continue
+		}
+		os.Rename(file, file+"."+status.ID+".accepted")
+	}
+}
+
+func (m *Manager) submitFile(file string) (JobStatus, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return JobStatus{}, err
+	}
+
+	spec := DefaultJobSpec()
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return JobStatus{}, fmt.Errorf("invalid job spec: %w", err)
+	}
+	return m.Submit(spec)
+}
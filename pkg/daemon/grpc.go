@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/user/gocrawler/pkg/crawlpb"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Serves the job API over gRPC; see proto/crawler.proto
+type grpcServer struct {
+	crawlpb.UnimplementedCrawlerServer
+	manager *Manager
+}
+
+func (m *Manager) GRPCServer() crawlpb.CrawlerServer {
+	return &grpcServer{manager: m}
+}
+
+func (s *grpcServer) SubmitCrawl(ctx context.Context, req *crawlpb.SubmitCrawlRequest) (*crawlpb.Job, error) {
+	spec := DefaultJobSpec()
+	spec.Seeds = req.Seeds
+	spec.ExtractLinks = req.ExtractLinks
+	spec.SeedOnly = req.SeedOnly
+	spec.NewsOnly = req.News
+	spec.URLFilter = req.Filter
+	spec.Compress = req.Compress
+	spec.Extract = req.Extract
+	if req.Format != "" {
+		spec.Format = req.Format
+	}
+	if req.Delay != "" {
+		spec.Delay = req.Delay
+	}
+	if req.Timeout != "" {
+		spec.Timeout = req.Timeout
+	}
+	if req.Agent != "" {
+		spec.UserAgent = req.Agent
+	}
+	if req.Depth != nil {
+		spec.MaxDepth = int(*req.Depth)
+	}
+	if req.MaxPages != nil {
+		spec.MaxPages = int(*req.MaxPages)
+	}
+	if req.Workers != nil {
+		spec.Workers = int(*req.Workers)
+	}
+	if req.Robots != nil {
+		spec.RespectRobots = *req.Robots
+	}
+	if req.StayDomain != nil {
+		spec.StayOnDomain = *req.StayDomain
+	}
+
+	jobStatus, err := s.manager.Submit(spec)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return jobProto(jobStatus), nil
+}
+
+func (s *grpcServer) StreamResults(req *crawlpb.StreamResultsRequest, stream crawlpb.Crawler_StreamResultsServer) error {
+	pages, stop, err := s.manager.Subscribe(req.JobId)
+	if err != nil {
+		return grpcError(err)
+	}
+	defer stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case page, ok := <-pages:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(pageProto(page)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *grpcServer) GetStats(ctx context.Context, req *crawlpb.GetStatsRequest) (*crawlpb.Job, error) {
+	jobStatus, err := s.manager.Get(req.JobId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return jobProto(jobStatus), nil
+}
+
+func (s *grpcServer) CancelCrawl(ctx context.Context, req *crawlpb.CancelCrawlRequest) (*crawlpb.Job, error) {
+	jobStatus, err := s.manager.Cancel(req.JobId)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return jobProto(jobStatus), nil
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrFinished):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrShutdown):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+}
+
+func jobProto(j JobStatus) *crawlpb.Job {
+	rejected := make(map[string]int64, len(j.Stats.Rejected))
+	for reason, count := range j.Stats.Rejected {
+		rejected[reason] = int64(count)
+	}
+
+	msg := &crawlpb.Job{
+		Id:        j.ID,
+		Status:    j.Status,
+		Output:    j.Output,
+		Error:     j.Error,
+		CreatedAt: unixMilli(j.CreatedAt),
+		Stats: &crawlpb.Stats{
+			PagesCrawled:    int64(j.Stats.PagesCrawled),
+			LinksDiscovered: int64(j.Stats.LinksDiscovered),
+			Errors:          int64(j.Stats.Errors),
+			AuthRequired:    int64(j.Stats.AuthRequired),
+			BytesDownloaded: j.Stats.BytesDownloaded,
+			Rejected:        rejected,
+		},
+	}
+	if j.StartedAt != nil {
+		msg.StartedAt = unixMilli(*j.StartedAt)
+	}
+	if j.FinishedAt != nil {
+		msg.FinishedAt = unixMilli(*j.FinishedAt)
+	}
+	return msg
+}
+
+func pageProto(page storage.PageData) *crawlpb.Page {
+	return &crawlpb.Page{
+		Url:          page.URL,
+		Title:        page.Title,
+		Description:  page.Description,
+		Author:       page.Author,
+		Content:      page.Content,
+		Links:        page.Links,
+		CrawledAt:    unixMilli(page.CrawledAt),
+		Depth:        int32(page.Depth),
+		SeedTag:      page.SeedTag,
+		Tags:         page.Tags,
+		AuthRequired: page.AuthRequired,
+		RunId:        page.RunID,
+		ContentType:  page.ContentType,
+		ContentKind:  page.ContentKind,
+		Size:         page.Size,
+		Error:        page.Error,
+	}
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/user/gocrawler/pkg/crawler"
+)
+
+// Serves the job API:
+//
+//	POST   /jobs              submit a job spec, returns the queued job
+//	GET    /jobs              list jobs
+//	GET    /jobs/{id}         job status and statistics
+//	DELETE /jobs/{id}         cancel a job (also POST /jobs/{id}/cancel)
+//	POST   /jobs/{id}/reload  change a running job's delay, workers, filter or verbose
+//	GET    /jobs/{id}/output  download the output of a finished job
+//	GET    /health            liveness check
+//
+// The API has no authentication; the public /about page is served
+// separately by AboutHandler.
+func (m *Manager) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("/jobs", m.handleJobs)
+	mux.HandleFunc("/jobs/", m.handleJob)
+	return mux
+}
+
+// Serves only GET /about, so it can be exposed to site owners without the job API
+func (m *Manager) AboutHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/about", m.handleAbout)
+	return mux
+}
+
+func (m *Manager) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, m.List())
+	case http.MethodPost:
+		spec := DefaultJobSpec()
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid job spec: "+err.Error())
+			return
+		}
+
+		status, err := m.Submit(spec)
+		if err != nil {
+			code := http.StatusBadRequest
+			if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrShutdown) {
+				code = http.StatusServiceUnavailable
+			}
+			writeError(w, code, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, status)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (m *Manager) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		status, err := m.Get(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case (action == "" && r.Method == http.MethodDelete) || (action == "cancel" && r.Method == http.MethodPost):
+		status, err := m.Cancel(id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrFinished):
+			writeJSON(w, http.StatusConflict, status)
+		default:
+			writeJSON(w, http.StatusOK, status)
+		}
+	case action == "reload" && r.Method == http.MethodPost:
+		data, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		reload, err := crawler.ParseReload(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		status, err := m.Reload(id, reload)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrNotRunning):
+			writeJSON(w, http.StatusConflict, status)
+		default:
+			writeJSON(w, http.StatusOK, status)
+		}
+	case action == "output" && r.Method == http.MethodGet:
+		status, err := m.Get(id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if status.Status == StatusQueued || status.Status == StatusRunning {
+			writeError(w, http.StatusConflict, "job has not finished")
+			return
+		}
+		http.ServeFile(w, r, status.Output)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
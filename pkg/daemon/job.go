@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/user/gocrawler/pkg/crawler"
+	"github.com/user/gocrawler/pkg/parser"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Describes a crawl job as submitted through the API or a jobs directory.
+// Fields left out of the submitted JSON keep the CLI defaults.
+type JobSpec struct {
+	Seed          string   `json:"seed"`
+	Seeds         []string `json:"seeds,omitempty"`
+	Format        string   `json:"format"`
+	Compress      string   `json:"compress,omitempty"`
+	MaxDepth      int      `json:"depth"`
+	MaxPages      int      `json:"max_pages"`
+	Workers       int      `json:"workers"`
+	Delay         string   `json:"delay"`
+	Timeout       string   `json:"timeout"`
+	UserAgent     string   `json:"agent"`
+	RespectRobots bool     `json:"robots"`
+	StayOnDomain  bool     `json:"stay_domain"`
+	ExtractLinks  bool     `json:"extract_links"`
+	Extract       string   `json:"extract,omitempty"`
+	SeedOnly      bool     `json:"seed_only"`
+	NewsOnly      bool     `json:"news"`
+	URLFilter     string   `json:"filter,omitempty"`
+}
+
+func DefaultJobSpec() JobSpec {
+	return JobSpec{
+		Format:        "json",
+		MaxDepth:      1,
+		MaxPages:      20,
+		Workers:       2,
+		Delay:         "1s",
+		Timeout:       "10s",
+		UserAgent:     "GoCrawler/1.0",
+		RespectRobots: true,
+		StayOnDomain:  true,
+	}
+}
+
+func (s JobSpec) validate() error {
+	if s.Seed == "" && len(s.Seeds) == 0 {
+		return fmt.Errorf("seed is required")
+	}
+	if !storage.IsFormat(s.Format) {
+		return fmt.Errorf("unsupported output format: %s", s.Format)
+	}
+	if _, ok := formatExtensions[s.Format]; !ok {
+		return fmt.Errorf("%s output isn't written to a file, which jobs need", s.Format)
+	}
+	if _, err := storage.CompressedFilename("", s.Compress); err != nil {
+		return err
+	}
+	if s.Format == "sitemap" && s.Compress == "zstd" {
+		return fmt.Errorf("sitemaps can only be gzip-compressed")
+	}
+	if _, err := time.ParseDuration(s.Delay); err != nil {
+		return fmt.Errorf("invalid delay %q", s.Delay)
+	}
+	if _, err := time.ParseDuration(s.Timeout); err != nil {
+		return fmt.Errorf("invalid timeout %q", s.Timeout)
+	}
+	if s.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1")
+	}
+	if s.Extract != "" && !parser.IsLevel(s.Extract) {
+		return fmt.Errorf("invalid extract %q: expected links, meta or full", s.Extract)
+	}
+	return nil
+}
+
+func (s JobSpec) crawlerConfig(runID string) crawler.Config {
+	delay, _ := time.ParseDuration(s.Delay)
+	timeout, _ := time.ParseDuration(s.Timeout)
+
+	return crawler.Config{
+		MaxDepth:      s.MaxDepth,
+		WorkerCount:   s.Workers,
+		Delay:         delay,
+		Timeout:       timeout,
+		MaxBodySize:   10 << 20,
+		MaxPages:      s.MaxPages,
+		RespectRobots: s.RespectRobots,
+		UserAgent:     s.UserAgent,
+		NewsOnly:      s.NewsOnly,
+		StayOnDomain:  s.StayOnDomain,
+		URLFilter:     s.URLFilter,
+		SeedOnly:      s.SeedOnly,
+		ExtractLinks:  s.ExtractLinks || s.Extract == parser.LevelLinks,
+		ExtractLevel:  s.Extract,
+		RunID:         runID,
+	}
+}
+
+func (j *job) reload(r crawler.Reload) {
+	j.crawler.Reload(r)
+	if r.Delay != nil {
+		j.spec.Delay = r.Delay.String()
+	}
+	if r.WorkerCount != nil {
+		j.spec.Workers = *r.WorkerCount
+	}
+	if r.URLFilter != nil {
+		j.spec.URLFilter = *r.URLFilter
+	}
+}
+
+func (s JobSpec) snapshot() map[string]string {
+	return map[string]string{
+		"seed":          s.Seed,
+		"format":        s.Format,
+		"compress":      s.Compress,
+		"depth":         fmt.Sprint(s.MaxDepth),
+		"max":           fmt.Sprint(s.MaxPages),
+		"workers":       fmt.Sprint(s.Workers),
+		"delay":         s.Delay,
+		"timeout":       s.Timeout,
+		"agent":         s.UserAgent,
+		"robots":        fmt.Sprint(s.RespectRobots),
+		"stay-domain":   fmt.Sprint(s.StayOnDomain),
+		"extract-links": fmt.Sprint(s.ExtractLinks),
+		"extract":       s.Extract,
+		"seed-only":     fmt.Sprint(s.SeedOnly),
+		"news":          fmt.Sprint(s.NewsOnly),
+		"filter":        s.URLFilter,
+	}
+}
+
+var formatExtensions = map[string]string{
+	"json":    ".json",
+	"ndjson":  ".ndjson",
+	"csv":     ".csv",
+	"sitemap": ".xml",
+	"graph":   ".csv",
+	"hosts":   ".txt",
+	"xml":     ".xml",
+	"html":    ".html",
+}
+
+type job struct {
+	id         string
+	spec       JobSpec
+	output     string
+	status     string
+	err        string
+	crawler    *crawler.Crawler
+	streams    map[*stream]struct{}
+	stats      crawler.Statistics
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// Point-in-time view of a job as returned by the API
+type JobStatus struct {
+	ID         string             `json:"id"`
+	Status     string             `json:"status"`
+	Spec       JobSpec            `json:"spec"`
+	Output     string             `json:"output"`
+	Error      string             `json:"error,omitempty"`
+	Stats      crawler.Statistics `json:"stats"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// Must be called with the manager mutex held
+func (j *job) view() JobStatus {
+	status := JobStatus{
+		ID:        j.id,
+		Status:    j.status,
+		Spec:      j.spec,
+		Output:    j.output,
+		Error:     j.err,
+		Stats:     j.stats,
+		CreatedAt: j.createdAt,
+	}
+
+	if j.status == StatusRunning && j.crawler != nil {
+		status.Stats = j.crawler.Stats()
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	return status
+}
+
+// Ends all result streams of the job; must be called with the manager mutex
+// held
+func (j *job) closeStreams() {
+	for s := range j.streams {
+		close(s.pages)
+	}
+	j.streams = nil
+}
+
+func newJobID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,202 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Duplicate definitions a crawl can combine
+const (
+	// Identical URL strings; always applied by the frontier
+	ExactURL = "exact-url"
+	// Same scheme, host and path, ignoring query and fragment
+	NormalizedURL = "normalized-url"
+	// Pages declaring the same rel=canonical URL, or a canonical URL that
+	// was already crawled. The canonical page itself is always kept, even
+	// when one of its aliases was crawled first.
+	Canonical = "canonical"
+	// Identical extracted text
+	ContentHash = "content-hash"
+	// Text whose 64-bit simhash differs in at most MaxSimhashDistance bits
+	Simhash = "simhash"
+	// URLs that redirect to, or are the rel=canonical target of, a page
+	// already crawled; applied by the crawler, which keeps them from being
+	// fetched again
+	Aliases = "aliases"
+)
+
+// The frontier's behaviour before strategies were configurable, and the
+// -dedup default
+var DefaultStrategies = []string{ExactURL, NormalizedURL}
+
+// Near-duplicate threshold for simhash
+const MaxSimhashDistance = 3
+
+// A set of enabled strategies
+type Strategies map[string]bool
+
+// Parses a comma-separated strategy list such as "normalized-url,simhash".
+// exact-url is implied, as the frontier never enqueues the same URL twice.
+func ParseStrategies(list string) (Strategies, error) {
+	strategies := Strategies{ExactURL: true}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case ExactURL, NormalizedURL, Canonical, ContentHash, Simhash, Aliases:
+			strategies[name] = true
+		default:
+			return nil, fmt.Errorf("unknown dedup strategy %q", name)
+		}
+	}
+	return strategies, nil
+}
+
+// Reports whether any strategy needs the fetched page rather than its URL
+func (s Strategies) CheckPages() bool {
+	return s[Canonical] || s[ContentHash] || s[Simhash]
+}
+
+// Detects duplicate pages after they are fetched, using the page-level
+// strategies. Safe for concurrent use.
+type Checker struct {
+	strategies Strategies
+	mutex      sync.Mutex
+	canonical  map[string]string
+	content    map[[sha256.Size]byte]string
+	simhashes  []simhashEntry
+	bands      [4]map[uint16][]int
+}
+
+type simhashEntry struct {
+	hash uint64
+	url  string
+}
+
+func NewChecker(strategies Strategies) *Checker {
+	c := &Checker{
+		strategies: strategies,
+		canonical:  make(map[string]string),
+		content:    make(map[[sha256.Size]byte]string),
+	}
+	for i := range c.bands {
+		c.bands[i] = make(map[uint16][]int)
+	}
+	return c
+}
+
+// Records the page and returns the strategy that matched and the URL of the
+// earlier page it duplicates, or "" when it is new. canonical is the page's
+// absolute rel=canonical URL ("" when absent). Pages without text are never
+// content duplicates.
+func (c *Checker) Check(pageURL, canonical, text string) (string, string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.strategies[Canonical] {
+		key := pageURL
+		if canonical != "" {
+			key = canonical
+		}
+		// The canonical target takes over the claim of an alias crawled
+		// before it rather than being dropped as its duplicate
+		if original, ok := c.canonical[key]; ok && (key != pageURL || original == pageURL) {
+			return Canonical, original
+		}
+		c.canonical[key] = pageURL
+		// A page reached before its canonical target still claims it
+		c.canonical[pageURL] = pageURL
+	}
+
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return "", ""
+	}
+
+	if c.strategies[ContentHash] {
+		sum := sha256.Sum256([]byte(text))
+		if original, ok := c.content[sum]; ok {
+			return ContentHash, original
+		}
+		c.content[sum] = pageURL
+	}
+
+	if c.strategies[Simhash] {
+		hash := simhash(text)
+		if original, ok := c.nearDuplicate(hash); ok {
+			return Simhash, original
+		}
+		c.addSimhash(hash, pageURL)
+	}
+
+	return "", ""
+}
+
+// Any hash within MaxSimhashDistance bits matches exactly in at least one
+// of the four 16-bit bands, so only hashes sharing a band are compared
+func (c *Checker) nearDuplicate(hash uint64) (string, bool) {
+	for i, band := range c.bands {
+		for _, index := range band[bandOf(hash, i)] {
+			entry := c.simhashes[index]
+			if bits.OnesCount64(entry.hash^hash) <= MaxSimhashDistance {
+				return entry.url, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (c *Checker) addSimhash(hash uint64, pageURL string) {
+	c.simhashes = append(c.simhashes, simhashEntry{hash: hash, url: pageURL})
+	for i, band := range c.bands {
+		key := bandOf(hash, i)
+		band[key] = append(band[key], len(c.simhashes)-1)
+	}
+}
+
+func bandOf(hash uint64, i int) uint16 {
+	return uint16(hash >> (16 * i))
+}
+
+// 64-bit simhash over three-word shingles of the lowercased text
+func simhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	var weights [64]int
+	addFeature := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	if len(words) < 3 {
+		for _, word := range words {
+			addFeature(word)
+		}
+	}
+	for i := 0; i+3 <= len(words); i++ {
+		addFeature(strings.Join(words[i:i+3], " "))
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
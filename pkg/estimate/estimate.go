@@ -0,0 +1,185 @@
+package estimate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/user/gocrawler/pkg/sitekey"
+)
+
+// Used when no earlier crawl tells us better
+const (
+	DefaultFetchTime = 500 * time.Millisecond
+	DefaultPageSize  = 100 << 10
+)
+
+// The settings of the planned crawl that bound its cost
+type Plan struct {
+	MaxPages    int
+	Workers     int
+	Delay       time.Duration // between requests to the same host
+	DelayJitter time.Duration // random extra delay, up to this long
+	Timeout     time.Duration
+	MaxBody     int64
+	SeedURLs    []string
+	// Groups hosts into the sites the delay applies to
+	Sites sitekey.Grouping
+}
+
+// What is known about the site before crawling it
+type Input struct {
+	// URLs from sitemaps or an earlier crawl
+	URLs []string
+	// Body sizes seen in an earlier crawl
+	PageSizes []int64
+	// How long sample fetches took, e.g. of the seed pages
+	FetchTimes []time.Duration
+}
+
+type Estimate struct {
+	Pages            int
+	Hosts            int
+	BusiestHost      string
+	BusiestHostPages int
+	FetchTime        time.Duration
+	AvgPageSize      int64
+	Duration         time.Duration
+	Bandwidth        int64
+	// What the numbers rest on, for the operator to sanity-check
+	Assumptions []string
+}
+
+// Estimates a crawl's page count, duration and transfer volume. The page
+// count is the number of known URLs capped at the page limit; pages are
+// spread over hosts in proportion to their known URLs. The duration is the
+// longer of two bounds: the busiest host fetched at the politeness delay
+// (plus average jitter), and all pages fetched by the workers in parallel.
+func Compute(plan Plan, in Input) Estimate {
+	var est Estimate
+
+	perHost := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, rawURL := range in.URLs {
+		if seen[rawURL] {
+			continue
+		}
+		seen[rawURL] = true
+		perHost[plan.Sites.KeyOfURL(rawURL)]++
+	}
+	known := len(seen)
+
+	if known == 0 {
+		est.Pages = plan.MaxPages
+		for _, seed := range plan.SeedURLs {
+			perHost[plan.Sites.KeyOfURL(seed)] = 1
+		}
+		est.Assumptions = append(est.Assumptions, "no URL list given: assuming the page limit is reached")
+	} else {
+		est.Pages = known
+		if plan.MaxPages > 0 && known > plan.MaxPages {
+			est.Pages = plan.MaxPages
+			est.Assumptions = append(est.Assumptions, fmt.Sprintf("%d known URLs, capped at the page limit", known))
+		}
+	}
+	if len(perHost) == 0 {
+		perHost[""] = 1
+	}
+
+	hosts := make([]string, 0, len(perHost))
+	total := 0
+	for host, count := range perHost {
+		hosts = append(hosts, host)
+		total += count
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if perHost[hosts[i]] != perHost[hosts[j]] {
+			return perHost[hosts[i]] > perHost[hosts[j]]
+		}
+		return hosts[i] < hosts[j]
+	})
+	est.Hosts = len(hosts)
+	est.BusiestHost = hosts[0]
+	est.BusiestHostPages = (perHost[hosts[0]]*est.Pages + total - 1) / total
+
+	est.FetchTime = DefaultFetchTime
+	if len(in.FetchTimes) > 0 {
+		var sum time.Duration
+		for _, fetchTime := range in.FetchTimes {
+			sum += fetchTime
+		}
+		est.FetchTime = sum / time.Duration(len(in.FetchTimes))
+	}
+	if plan.Timeout > 0 && plan.Timeout < est.FetchTime {
+		est.FetchTime = plan.Timeout
+	}
+	if len(in.FetchTimes) > 0 {
+		est.Assumptions = append(est.Assumptions, fmt.Sprintf("%v per fetch, as measured over %d sample fetches", est.FetchTime.Round(time.Millisecond), len(in.FetchTimes)))
+	} else {
+		est.Assumptions = append(est.Assumptions, fmt.Sprintf("%v per fetch", est.FetchTime))
+	}
+
+	est.AvgPageSize = DefaultPageSize
+	if len(in.PageSizes) > 0 {
+		var sum int64
+		for _, size := range in.PageSizes {
+			sum += size
+		}
+		est.AvgPageSize = sum / int64(len(in.PageSizes))
+		est.Assumptions = append(est.Assumptions, fmt.Sprintf("average page size from %d sample pages", len(in.PageSizes)))
+	} else {
+		est.Assumptions = append(est.Assumptions, "no earlier crawl: assuming 100KB pages")
+	}
+	if plan.MaxBody > 0 && est.AvgPageSize > plan.MaxBody {
+		est.AvgPageSize = plan.MaxBody
+	}
+	est.Bandwidth = est.AvgPageSize * int64(est.Pages)
+
+	spacing := plan.Delay + plan.DelayJitter/2
+	if spacing < est.FetchTime {
+		spacing = est.FetchTime
+	}
+	hostBound := time.Duration(0)
+	if est.BusiestHostPages > 0 {
+		hostBound = time.Duration(est.BusiestHostPages-1)*spacing + est.FetchTime
+	}
+
+	workers := plan.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	workerBound := time.Duration((est.Pages+workers-1)/workers) * est.FetchTime
+
+	est.Duration = hostBound
+	if workerBound > est.Duration {
+		est.Duration = workerBound
+	}
+	return est
+}
+
+func (e Estimate) Print(w io.Writer) {
+	fmt.Fprintf(w, "Estimated pages:     %d across %d host(s)\n", e.Pages, e.Hosts)
+	if e.BusiestHost != "" {
+		fmt.Fprintf(w, "Busiest host:        %s (%d pages)\n", e.BusiestHost, e.BusiestHostPages)
+	}
+	fmt.Fprintf(w, "Estimated duration:  %v\n", e.Duration.Round(time.Second))
+	fmt.Fprintf(w, "Estimated transfer:  %s (%s per page)\n", formatBytes(e.Bandwidth), formatBytes(e.AvgPageSize))
+	fmt.Fprintln(w, "Assumptions:")
+	for _, assumption := range e.Assumptions {
+		fmt.Fprintf(w, "  - %s\n", assumption)
+	}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
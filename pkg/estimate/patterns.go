@@ -0,0 +1,56 @@
+package estimate
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// How many URLs share a pattern
+type PatternCount struct {
+	Pattern string
+	URLs    int
+}
+
+// Groups URLs by host and first path segment, e.g. example.com/blog/* for
+// everything under /blog/, most common first. Patterns beyond limit are
+// folded into a final "other" entry (limit <= 0 keeps them all).
+func Patterns(urls []string, limit int) []PatternCount {
+	counts := make(map[string]int)
+	for _, rawURL := range urls {
+		counts[urlPattern(rawURL)]++
+	}
+
+	patterns := make([]PatternCount, 0, len(counts))
+	for pattern, count := range counts {
+		patterns = append(patterns, PatternCount{Pattern: pattern, URLs: count})
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].URLs != patterns[j].URLs {
+			return patterns[i].URLs > patterns[j].URLs
+		}
+		return patterns[i].Pattern < patterns[j].Pattern
+	})
+
+	if limit > 0 && len(patterns) > limit {
+		other := PatternCount{Pattern: "other"}
+		for _, pattern := range patterns[limit:] {
+			other.URLs += pattern.URLs
+		}
+		patterns = append(patterns[:limit], other)
+	}
+	return patterns
+}
+
+func urlPattern(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid"
+	}
+	host := strings.ToLower(parsedURL.Host)
+	segments := strings.SplitN(strings.TrimPrefix(parsedURL.Path, "/"), "/", 2)
+	if len(segments) == 2 {
+		return host + "/" + segments[0] + "/*"
+	}
+	return host + "/" + segments[0]
+}
@@ -0,0 +1,108 @@
+package estimate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/user/gocrawler/pkg/handlers"
+)
+
+// Upper bound on sitemaps read through sitemap indexes
+const maxSitemaps = 1000
+
+// Returns the page URLs listed in a sitemap, read from a file or fetched
+// from an http(s) URL. Child sitemaps of a sitemap index (entries ending in
+// .xml or .xml.gz) are read too.
+func LoadSitemap(source string, client *http.Client, userAgent string) ([]string, error) {
+	var urls []string
+	queue := []string{source}
+	loaded := make(map[string]bool)
+	for len(queue) > 0 && len(loaded) < maxSitemaps {
+		current := queue[0]
+		queue = queue[1:]
+		if loaded[current] {
+			continue
+		}
+		loaded[current] = true
+
+		body, err := readSitemap(current, client, userAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := handlers.XMLHandler{}.Handle(context.Background(), body, sitemapBase(current))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sitemap %s: %w", current, err)
+		}
+
+		for _, link := range result.Links {
+			lower := strings.ToLower(link)
+			if strings.HasSuffix(lower, ".xml") || strings.HasSuffix(lower, ".xml.gz") {
+				queue = append(queue, link)
+			} else {
+				urls = append(urls, link)
+			}
+		}
+	}
+	return urls, nil
+}
+
+func readSitemap(source string, client *http.Client, userAgent string) ([]byte, error) {
+	var body []byte
+	var err error
+	if isURL(source) {
+		body, err = fetch(source, client, userAgent)
+	} else {
+		body, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap %s: %w", source, err)
+	}
+
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %w", source, err)
+		}
+		defer gz.Close()
+		return io.ReadAll(gz)
+	}
+	return body, nil
+}
+
+func fetch(rawURL string, client *http.Client, userAgent string) ([]byte, error) {
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Relative <loc> entries in a local file have nothing to resolve against
+func sitemapBase(source string) string {
+	if isURL(source) {
+		return source
+	}
+	return ""
+}
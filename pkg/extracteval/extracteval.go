@@ -0,0 +1,182 @@
+package extracteval
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// An HTML page and the main content a good extraction returns for it: name.html
+// next to name.txt
+type Fixture struct {
+	Name     string
+	HTMLPath string
+	TextPath string
+}
+
+// Finds the fixtures in dir, in name order. Returns the HTML files without
+// an expected .txt separately, as they can't be scored.
+func LoadFixtures(dir string) ([]Fixture, []string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+
+	var fixtures []Fixture
+	var unscored []string
+	for _, htmlPath := range paths {
+		name := strings.TrimSuffix(filepath.Base(htmlPath), ".html")
+		textPath := strings.TrimSuffix(htmlPath, ".html") + ".txt"
+		if _, err := os.Stat(textPath); err != nil {
+			unscored = append(unscored, htmlPath)
+			continue
+		}
+		fixtures = append(fixtures, Fixture{Name: name, HTMLPath: htmlPath, TextPath: textPath})
+	}
+	return fixtures, unscored, nil
+}
+
+// How well extracted text matches the expected text, word by word
+type Score struct {
+	Precision float64 // share of extracted words that were expected
+	Recall    float64 // share of expected words that were extracted
+	F1        float64
+	Extracted int
+	Expected  int
+	Matched   int
+}
+
+// Compares the words of the two texts as multisets, ignoring case,
+// punctuation and whitespace, so it rewards getting the content and
+// penalizes boilerplate without caring about layout
+func Compare(extracted, expected string) Score {
+	want := make(map[string]int)
+	for _, word := range words(expected) {
+		want[word]++
+	}
+
+	score := Score{Expected: len(words(expected))}
+	for _, word := range words(extracted) {
+		score.Extracted++
+		if want[word] > 0 {
+			want[word]--
+			score.Matched++
+		}
+	}
+	score.compute()
+	return score
+}
+
+func (s *Score) compute() {
+	s.Precision, s.Recall, s.F1 = 0, 0, 0
+	if s.Extracted > 0 {
+		s.Precision = float64(s.Matched) / float64(s.Extracted)
+	} else if s.Expected == 0 {
+		s.Precision = 1
+	}
+	if s.Expected > 0 {
+		s.Recall = float64(s.Matched) / float64(s.Expected)
+	} else {
+		s.Recall = 1
+	}
+	if s.Precision+s.Recall > 0 {
+		s.F1 = 2 * s.Precision * s.Recall / (s.Precision + s.Recall)
+	}
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Scores of one fixture
+type Result struct {
+	Name  string
+	Score Score
+	Err   error
+}
+
+type Report struct {
+	Results []Result
+	// Averages of the fixtures' precision, recall and F1, each fixture
+	// counting the same
+	Macro Score
+	// Scores of all words pooled, long pages counting more
+	Micro Score
+	// Fixtures that failed to load or extract
+	Failed int
+}
+
+// Runs extract on every fixture and scores the text it returns
+func Run(fixtures []Fixture, extract func(html string) (string, error)) Report {
+	var report Report
+	var scored int
+	for _, fixture := range fixtures {
+		result := Result{Name: fixture.Name}
+		score, err := runFixture(fixture, extract)
+		if err != nil {
+			result.Err = err
+			report.Failed++
+			report.Results = append(report.Results, result)
+			continue
+		}
+		result.Score = score
+		report.Results = append(report.Results, result)
+
+		scored++
+		report.Macro.Precision += score.Precision
+		report.Macro.Recall += score.Recall
+		report.Macro.F1 += score.F1
+		report.Micro.Extracted += score.Extracted
+		report.Micro.Expected += score.Expected
+		report.Micro.Matched += score.Matched
+	}
+
+	if scored > 0 {
+		report.Macro.Precision /= float64(scored)
+		report.Macro.Recall /= float64(scored)
+		report.Macro.F1 /= float64(scored)
+	}
+	report.Micro.compute()
+	return report
+}
+
+func runFixture(fixture Fixture, extract func(html string) (string, error)) (Score, error) {
+	html, err := os.ReadFile(fixture.HTMLPath)
+	if err != nil {
+		return Score{}, err
+	}
+	expected, err := os.ReadFile(fixture.TextPath)
+	if err != nil {
+		return Score{}, err
+	}
+	extracted, err := extract(string(html))
+	if err != nil {
+		return Score{}, fmt.Errorf("failed to extract content: %w", err)
+	}
+	return Compare(extracted, string(expected)), nil
+}
+
+func (r Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-30s %9s %9s %9s %9s %9s\n", "FIXTURE", "PRECISION", "RECALL", "F1", "EXTRACTED", "EXPECTED")
+	for _, result := range r.Results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "%-30s error: %v\n", result.Name, result.Err)
+			continue
+		}
+		s := result.Score
+		fmt.Fprintf(w, "%-30s %9.3f %9.3f %9.3f %9d %9d\n", result.Name, s.Precision, s.Recall, s.F1, s.Extracted, s.Expected)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-30s %9.3f %9.3f %9.3f\n", "mean (per fixture)", r.Macro.Precision, r.Macro.Recall, r.Macro.F1)
+	fmt.Fprintf(w, "%-30s %9.3f %9.3f %9.3f %9d %9d\n", "pooled (all words)", r.Micro.Precision, r.Micro.Recall, r.Micro.F1, r.Micro.Extracted, r.Micro.Expected)
+	if r.Failed > 0 {
+		fmt.Fprintf(w, "%d fixture(s) failed\n", r.Failed)
+	}
+}
@@ -2,12 +2,77 @@ package frontier
 
 import (
 	"net/url"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/user/gocrawler/pkg/sitekey"
 )
 
 type URLItem struct {
-	URL   string
-	Depth int
+	URL     string
+	Depth   int
+	SeedTag string
+	Tags    []string
+	Parent  string // page the URL was discovered on; empty for seeds
+	// Higher priorities are dequeued first when the frontier is prioritized
+	Priority int
+	// When the page last changed according to a sitemap; zero if unknown
+	LastModified time.Time
+	// Links followed since leaving the crawled site; 0 for on-site pages
+	ExternalHops int
+	// Failed fetches so far, and when a retry may be handed out
+	Attempts  int
+	NotBefore time.Time
+}
+
+// Why Add did not enqueue a URL; Accepted when it did
+type Rejection string
+
+const (
+	Accepted             Rejection = ""
+	RejectDuplicate      Rejection = "duplicate"
+	RejectInvalid        Rejection = "invalid"
+	RejectNormalizedDupe Rejection = "normalized-duplicate"
+	RejectFiltered       Rejection = "filtered"
+	RejectSeen           Rejection = "seen"
+	RejectUnchanged      Rejection = "unchanged"
+	RejectTooLong        Rejection = "too-long"
+	RejectTooManyParams  Rejection = "too-many-params"
+	RejectRepeatedPath   Rejection = "repeated-path"
+	RejectDenylisted     Rejection = "denylisted"
+	RejectNotAllowlisted Rejection = "not-allowlisted"
+	// Retry gave up on the URL after its last allowed attempt
+	RejectRetriesExhausted Rejection = "retries-exhausted"
+)
+
+// Matches URLs against an allow or deny list
+type URLMatcher interface {
+	Match(rawURL string) bool
+}
+
+// Rewrites URLs before they are checked and enqueued, e.g. to drop
+// session IDs and tracking parameters
+type URLRewriter interface {
+	Rewrite(rawURL string) string
+}
+
+// Guards against URL explosions from calendars, session IDs and relative-link
+// loops. A zero field disables that check.
+type URLLimits struct {
+	MaxLength      int // characters in the whole URL
+	MaxParams      int // query parameters, counting repeats
+	MaxSegmentRuns int // times any one path segment may appear
+}
+
+// Decides whether an item may be enqueued; returning false rejects it with
+// RejectFiltered
+type Filter func(item URLItem) bool
+
+// Remembers URLs fetched in earlier runs, keyed by their normalized form
+type SeenStore interface {
+	FetchedSince(key string, since time.Time) bool
 }
 
 // Manages the queue of URLs to crawl
@@ -16,6 +81,30 @@ type URLFrontier struct {
 	visited    map[string]bool
 	mutex      sync.Mutex
 	normalized map[string]bool
+
+	seen            SeenStore
+	revisitAfter    time.Duration
+	normalizedDedup bool
+	filters         []Filter
+	limits          URLLimits
+	allowlist       URLMatcher
+	denylist        URLMatcher
+	rewriter        URLRewriter
+	prioritized     bool
+	rejections      map[Rejection]int
+
+	// Failed items waiting to be retried, by NotBefore
+	retries     []URLItem
+	maxAttempts int
+
+	// Per-host politeness for NextBatch; hosts are grouped into sites
+	sites     sitekey.Grouping
+	hostDelay time.Duration
+	hostReady map[string]time.Time
+	// When expired hostReady entries were last dropped
+	hostReadyPruned time.Time
+	// Hosts whose URLs are held back until the given time
+	hostPaused map[string]time.Time
 }
 
 func NewURLFrontier() *URLFrontier {
@@ -23,52 +112,473 @@ func NewURLFrontier() *URLFrontier {
 		queue:      make([]URLItem, 0),
 		visited:    make(map[string]bool),
 		normalized: make(map[string]bool),
+		rejections: make(map[Rejection]int),
+
+		normalizedDedup: true,
+		hostReady:       make(map[string]time.Time),
+		hostPaused:      make(map[string]time.Time),
+	}
+}
+
+// Makes host delays and pauses apply per site rather than per host; host
+// arguments are then site keys
+func (f *URLFrontier) SetSiteGrouping(sites sitekey.Grouping) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.sites = sites
+}
+
+// Holds back the host's queued URLs until the given time; they keep their
+// place in the queue and are handed out again afterwards
+func (f *URLFrontier) PauseHost(host string, until time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.hostPaused[host] = until
+}
+
+// Must be called with f.mutex held
+func (f *URLFrontier) pausedUntil(host string, now time.Time) (time.Time, bool) {
+	until, ok := f.hostPaused[host]
+	if !ok {
+		return time.Time{}, false
+	}
+	if !now.Before(until) {
+		delete(f.hostPaused, host)
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// Lets Retry re-queue a failed URL until it has been tried maxAttempts times
+// in all. Zero or one never retries.
+func (f *URLFrontier) SetMaxAttempts(maxAttempts int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.maxAttempts = maxAttempts
+}
+
+// Counts a failed fetch of a dequeued item and queues it again to be handed
+// out after delay. Once the item has used its attempts it is dropped with
+// RejectRetriesExhausted instead.
+func (f *URLFrontier) Retry(item URLItem, delay time.Duration) Rejection {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	item.Attempts++
+	if item.Attempts >= f.maxAttempts {
+		f.rejections[RejectRetriesExhausted]++
+		return RejectRetriesExhausted
+	}
+
+	item.NotBefore = time.Now().Add(delay)
+	i := sort.Search(len(f.retries), func(i int) bool {
+		return f.retries[i].NotBefore.After(item.NotBefore)
+	})
+	f.retries = append(f.retries, URLItem{})
+	copy(f.retries[i+1:], f.retries[i:])
+	f.retries[i] = item
+	return Accepted
+}
+
+// Moves retries that are due to the front of the queue. Must be called with
+// f.mutex held.
+func (f *URLFrontier) promoteRetries(now time.Time) {
+	due := 0
+	for due < len(f.retries) && !now.Before(f.retries[due].NotBefore) {
+		due++
 	}
+	if due == 0 {
+		return
+	}
+	f.queue = append(append([]URLItem(nil), f.retries[:due]...), f.queue...)
+	f.retries = f.retries[due:]
+}
+
+// Makes NextBatch hand out a host's URLs at least delay apart. A zero delay
+// (the default) puts no limit on hosts.
+func (f *URLFrontier) SetHostDelay(delay time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.hostDelay = delay
+}
+
+// Turns the normalized-URL duplicate check on or off (on by default). With
+// it off, URLs differing only in query or fragment are crawled separately.
+func (f *URLFrontier) SetNormalizedDedup(enabled bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.normalizedDedup = enabled
+}
+
+// Orders the queue by descending item priority, first in first out within a
+// priority. Call before adding items.
+func (f *URLFrontier) SetPrioritized(enabled bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.prioritized = enabled
+}
+
+// Makes Add reject URLs exceeding the limits
+func (f *URLFrontier) SetURLLimits(limits URLLimits) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.limits = limits
 }
 
-func (f *URLFrontier) Add(rawURL string, depth int) bool {
+// Makes Add reject URLs matching the denylist and, with an allowlist, URLs
+// not matching it. Either may be nil.
+func (f *URLFrontier) SetURLLists(allowlist, denylist URLMatcher) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
+	f.allowlist = allowlist
+	f.denylist = denylist
+}
+
+// Makes Add rewrite URLs before checking them, so URLs that only differ in
+// what the rewriter removes are duplicates
+func (f *URLFrontier) SetURLRewriter(rewriter URLRewriter) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rewriter = rewriter
+}
 
-	if f.visited[rawURL] {
-		return false
+// Checks a URL against the allow and deny lists only, e.g. for redirect
+// targets that never pass through Add
+func (f *URLFrontier) CheckLists(rawURL string) Rejection {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.checkLists(rawURL)
+}
+
+// Must be called with f.mutex held
+func (f *URLFrontier) checkLists(rawURL string) Rejection {
+	if f.denylist != nil && f.denylist.Match(rawURL) {
+		return RejectDenylisted
+	}
+	if f.allowlist != nil && !f.allowlist.Match(rawURL) {
+		return RejectNotAllowlisted
 	}
+	return Accepted
+}
 
+// Adds a filter consulted by Add after the duplicate checks
+func (f *URLFrontier) AddFilter(filter Filter) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.filters = append(f.filters, filter)
+}
+
+// Makes Add reject non-seed URLs that were fetched in an earlier run less
+// than revisitAfter ago. A zero revisitAfter never revisits. URLs with a
+// LastModified time are instead rejected unless they changed since.
+func (f *URLFrontier) SetSeenStore(seen SeenStore, revisitAfter time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.seen = seen
+	f.revisitAfter = revisitAfter
+}
+
+// Reduces a URL to scheme, host and path, the form used for duplicate
+// detection
+func Normalize(rawURL string) (string, error) {
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return false
+		return "", err
 	}
+	return parsedURL.Scheme + "://" + parsedURL.Host + parsedURL.Path, nil
+}
+
+func (f *URLFrontier) Add(rawURL string, depth int) Rejection {
+	return f.AddItem(URLItem{URL: rawURL, Depth: depth})
+}
 
-	normalized := parsedURL.Scheme + "://" + parsedURL.Host + parsedURL.Path
+func (f *URLFrontier) AddItem(item URLItem) Rejection {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
 
-	if f.normalized[normalized] {
-		return false
+	if f.rewriter != nil {
+		item.URL = f.rewriter.Rewrite(item.URL)
 	}
 
-	f.visited[rawURL] = true
+	reason := f.check(item)
+	if reason != Accepted {
+		f.rejections[reason]++
+		return reason
+	}
+
+	normalized, _ := Normalize(item.URL)
+	f.visited[item.URL] = true
 	f.normalized[normalized] = true
 
-	f.queue = append(f.queue, URLItem{URL: rawURL, Depth: depth})
-	return true
+	if f.prioritized {
+		i := sort.Search(len(f.queue), func(i int) bool {
+			return f.queue[i].Priority < item.Priority
+		})
+		f.queue = append(f.queue, URLItem{})
+		copy(f.queue[i+1:], f.queue[i:])
+		f.queue[i] = item
+		return Accepted
+	}
+
+	f.queue = append(f.queue, item)
+	return Accepted
+}
+
+// Must be called with f.mutex held
+func (f *URLFrontier) check(item URLItem) Rejection {
+	if f.visited[item.URL] {
+		return RejectDuplicate
+	}
+
+	normalized, err := Normalize(item.URL)
+	if err != nil {
+		return RejectInvalid
+	}
+
+	if reason := f.checkLists(item.URL); reason != Accepted {
+		return reason
+	}
+
+	if reason := f.limits.check(item.URL); reason != Accepted {
+		return reason
+	}
+
+	if f.normalizedDedup && f.normalized[normalized] {
+		return RejectNormalizedDupe
+	}
+
+	for _, filter := range f.filters {
+		if !filter(item) {
+			return RejectFiltered
+		}
+	}
+
+	// Seeds are always fetched so that new links below them are discovered.
+	// A known modification time replaces the revisit interval: the URL is
+	// fetched again exactly when it changed after the last fetch.
+	if f.seen != nil && item.Depth > 0 {
+		if !item.LastModified.IsZero() {
+			if f.seen.FetchedSince(normalized, item.LastModified) {
+				return RejectUnchanged
+			}
+		} else if f.seenRecently(normalized) {
+			return RejectSeen
+		}
+	}
+
+	return Accepted
+}
+
+// Returns how many URLs Add has rejected, by reason
+func (f *URLFrontier) Rejections() map[Rejection]int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	counts := make(map[Rejection]int, len(f.rejections))
+	for reason, count := range f.rejections {
+		counts[reason] = count
+	}
+	return counts
+}
+
+func (l URLLimits) check(rawURL string) Rejection {
+	if l.MaxLength > 0 && len(rawURL) > l.MaxLength {
+		return RejectTooLong
+	}
+	if l.MaxParams <= 0 && l.MaxSegmentRuns <= 0 {
+		return Accepted
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return RejectInvalid
+	}
+
+	if l.MaxParams > 0 && parsedURL.RawQuery != "" {
+		if strings.Count(parsedURL.RawQuery, "&")+1 > l.MaxParams {
+			return RejectTooManyParams
+		}
+	}
+
+	if l.MaxSegmentRuns > 0 {
+		counts := make(map[string]int)
+		for _, segment := range strings.Split(parsedURL.Path, "/") {
+			if segment == "" {
+				continue
+			}
+			counts[segment]++
+			if counts[segment] > l.MaxSegmentRuns {
+				return RejectRepeatedPath
+			}
+		}
+	}
+
+	return Accepted
+}
+
+func (f *URLFrontier) seenRecently(normalized string) bool {
+	since := time.Time{}
+	if f.revisitAfter > 0 {
+		since = time.Now().Add(-f.revisitAfter)
+	}
+	return f.seen.FetchedSince(normalized, since)
 }
 
 func (f *URLFrontier) Next() (string, int, bool) {
+	item, ok := f.NextItem()
+	return item.URL, item.Depth, ok
+}
+
+// Removes and returns the first queued item whose host is not paused
+func (f *URLFrontier) NextItem() (URLItem, bool) {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
+	f.promoteRetries(time.Now())
 	if len(f.queue) == 0 {
-		return "", 0, false
+		return URLItem{}, false
+	}
+
+	if len(f.hostPaused) == 0 {
+		item := f.queue[0]
+		f.queue = f.queue[1:]
+		return item, true
+	}
+
+	now := time.Now()
+	for i, item := range f.queue {
+		if _, paused := f.pausedUntil(f.sites.KeyOfURL(item.URL), now); paused {
+			continue
+		}
+		f.queue = append(f.queue[:i:i], f.queue[i+1:]...)
+		return item, true
+	}
+	return URLItem{}, false
+}
+
+// Removes and returns up to n queued items in one call, in queue order,
+// skipping items whose host is not ready yet. With a host delay set, a batch
+// holds at most one item per host and each returned item reserves its host
+// for the delay; skipped items keep their place in the queue.
+func (f *URLFrontier) NextBatch(n int) []URLItem {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.promoteRetries(time.Now())
+	if n <= 0 || len(f.queue) == 0 {
+		return nil
 	}
 
-	item := f.queue[0]
-	f.queue = f.queue[1:]
-	return item.URL, item.Depth, true
+	if f.hostDelay <= 0 && len(f.hostPaused) == 0 {
+		if n > len(f.queue) {
+			n = len(f.queue)
+		}
+		// The queue never writes to the front of its array again, so the
+		// batch can share it
+		batch := f.queue[:n:n]
+		f.queue = f.queue[n:]
+		return batch
+	}
+
+	now := time.Now()
+	f.pruneHostReady(now)
+	batch := make([]URLItem, 0, n)
+	var skipped []URLItem
+	i := 0
+	for ; i < len(f.queue) && len(batch) < n; i++ {
+		item := f.queue[i]
+		host := f.sites.KeyOfURL(item.URL)
+		if ready, ok := f.hostReady[host]; ok && now.Before(ready) {
+			skipped = append(skipped, item)
+			continue
+		}
+		if _, paused := f.pausedUntil(host, now); paused {
+			skipped = append(skipped, item)
+			continue
+		}
+
+		if f.hostDelay > 0 {
+			f.hostReady[host] = now.Add(f.hostDelay)
+		}
+		batch = append(batch, item)
+	}
+
+	// Skipped items move up to just before the unscanned rest, without
+	// copying the whole queue
+	start := i - len(skipped)
+	copy(f.queue[start:i], skipped)
+	f.queue = f.queue[start:]
+	return batch
 }
 
+// Drops the reservations of hosts that are ready again, at most once per
+// host delay (or second, if shorter), so hosts seen once don't stay in
+// memory. Must be called with f.mutex held.
+func (f *URLFrontier) pruneHostReady(now time.Time) {
+	if since := now.Sub(f.hostReadyPruned); since < f.hostDelay || since < time.Second {
+		return
+	}
+	for host, ready := range f.hostReady {
+		if !now.Before(ready) {
+			delete(f.hostReady, host)
+		}
+	}
+	f.hostReadyPruned = now
+}
+
+// Puts items taken with NextItem or NextBatch back at the front of the
+// queue, in order, e.g. when a worker stops before processing them
+func (f *URLFrontier) Requeue(items []URLItem) {
+	if len(items) == 0 {
+		return
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.queue = append(append([]URLItem(nil), items...), f.queue...)
+}
+
+// Returns how long until NextItem or NextBatch can return an item: zero
+// when one is ready now, and false when the queue is empty and no retries
+// are waiting
+func (f *URLFrontier) NextReadyIn() (time.Duration, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	f.promoteRetries(now)
+	var wait time.Duration
+	if len(f.retries) > 0 {
+		wait = f.retries[0].NotBefore.Sub(now)
+	}
+
+	if len(f.queue) == 0 {
+		return wait, len(f.retries) > 0
+	}
+	if f.hostDelay <= 0 && len(f.hostPaused) == 0 {
+		return 0, true
+	}
+
+	for _, item := range f.queue {
+		host := f.sites.KeyOfURL(item.URL)
+		ready := f.hostReady[host]
+		if until, paused := f.pausedUntil(host, now); paused && until.After(ready) {
+			ready = until
+		}
+		if !now.Before(ready) {
+			return 0, true
+		}
+		if d := ready.Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait, true
+}
+
+// Reports whether items are queued or waiting to be retried
 func (f *URLFrontier) HasNext() bool {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
-	return len(f.queue) > 0
+	return len(f.queue) > 0 || len(f.retries) > 0
 }
 
 func (f *URLFrontier) Size() int {
@@ -83,6 +593,17 @@ func (f *URLFrontier) VisitedCount() int {
 	return len(f.visited)
 }
 
+// Records a URL as crawled without queueing it, e.g. one fetched by an
+// earlier run that is being resumed
+func (f *URLFrontier) MarkVisited(rawURL string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.visited[rawURL] = true
+	if normalized, err := Normalize(rawURL); err == nil {
+		f.normalized[normalized] = true
+	}
+}
+
 func (f *URLFrontier) Visited(rawURL string) bool {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -93,6 +614,10 @@ func (f *URLFrontier) Clear() {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 	f.queue = make([]URLItem, 0)
+	f.retries = nil
 	f.visited = make(map[string]bool)
 	f.normalized = make(map[string]bool)
+	f.rejections = make(map[Rejection]int)
+	f.hostReady = make(map[string]time.Time)
+	f.hostPaused = make(map[string]time.Time)
 }
@@ -0,0 +1,87 @@
+package frontier
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A frontier holding n URLs spread over hosts hosts
+func benchFrontier(n, hosts int) *URLFrontier {
+	f := NewURLFrontier()
+	for i := 0; i < n; i++ {
+		f.Add(fmt.Sprintf("https://host%d.example.com/page/%d", i%hosts, i), 0)
+	}
+	return f
+}
+
+// Drains a frontier of 10000 URLs with 64 goroutines, each taking size URLs
+// per call (NextItem for 1), as workers at a high -workers count do
+func benchmarkDrain(b *testing.B, size int, hostDelay time.Duration) {
+	const urls, workers = 10000, 64
+	items := benchFrontier(urls, 1000).queue
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		f := NewURLFrontier()
+		f.queue = append([]URLItem(nil), items...)
+		f.SetHostDelay(hostDelay)
+		b.StartTimer()
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					if size == 1 {
+						if _, ok := f.NextItem(); !ok {
+							return
+						}
+					} else if len(f.NextBatch(size)) == 0 && f.Size() == 0 {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func BenchmarkNextItem(b *testing.B)    { benchmarkDrain(b, 1, 0) }
+func BenchmarkNextBatch4(b *testing.B)  { benchmarkDrain(b, 4, 0) }
+func BenchmarkNextBatch16(b *testing.B) { benchmarkDrain(b, 16, 0) }
+
+// With a host delay every batch holds one URL per host, so the 1000 hosts
+// are handed out once per delay
+func BenchmarkNextBatch16HostDelay(b *testing.B) { benchmarkDrain(b, 16, time.Nanosecond) }
+
+// Expired host reservations are dropped, so hosts seen once don't stay in
+// memory for the rest of the crawl
+func TestNextBatchPrunesHostReady(t *testing.T) {
+	f := benchFrontier(100, 100)
+	f.SetHostDelay(time.Millisecond)
+	if got := len(f.NextBatch(100)); got != 100 {
+		t.Fatalf("NextBatch returned %d items, want 100", got)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	f.hostReadyPruned = f.hostReadyPruned.Add(-time.Second)
+	f.Add("https://other.example.com/", 0)
+	f.NextBatch(1)
+	if len(f.hostReady) != 1 {
+		t.Errorf("hostReady has %d hosts after they expired, want 1", len(f.hostReady))
+	}
+}
+
+// Requeued items come back first, in order
+func TestRequeue(t *testing.T) {
+	f := benchFrontier(4, 4)
+	batch := f.NextBatch(2)
+	f.Requeue(batch)
+	again := f.NextBatch(4)
+	if len(again) != 4 || again[0].URL != batch[0].URL || again[1].URL != batch[1].URL {
+		t.Errorf("NextBatch after Requeue = %v, want %v first", again, batch)
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// Saves response bodies (typically images) to a directory, named by the
+// SHA-1 of their URL plus the original extension. The result content is the
+// path of the saved file.
+type AssetHandler struct {
+	Dir string
+}
+
+func (h AssetHandler) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create asset directory: %w", err)
+	}
+
+	sum := sha1.Sum([]byte(pageURL))
+	name := hex.EncodeToString(sum[:])
+	if parsedURL, err := url.Parse(pageURL); err == nil {
+		name += path.Ext(parsedURL.Path)
+	}
+
+	filename := filepath.Join(h.Dir, name)
+	if err := os.WriteFile(filename, body, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to save asset: %w", err)
+	}
+
+	return &parser.Result{
+		Content: filename,
+		Links:   make([]string, 0),
+		Kind:    "asset",
+	}, nil
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/user/gocrawler/pkg/parser"
+	"github.com/user/gocrawler/pkg/urlvalidate"
+)
+
+// Turns a fetched response body into a parse result. Handlers should give
+// up with ctx.Err() once ctx is done. body is reused for later responses, so
+// nothing may keep it, or slices of it, after Handle returns.
+type Handler interface {
+	Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error)
+}
+
+type HandlerFunc func(ctx context.Context, body []byte, pageURL string) (*parser.Result, error)
+
+func (f HandlerFunc) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	return f(ctx, body, pageURL)
+}
+
+// Maps media types to handlers. Types may be registered exactly
+// ("application/pdf") or by top-level wildcard ("image/*").
+type Registry struct {
+	handlers map[string]Handler
+	mutex    sync.RWMutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]Handler),
+	}
+}
+
+func (r *Registry) Register(mediaType string, h Handler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.handlers[strings.ToLower(mediaType)] = h
+}
+
+// Finds the handler for a Content-Type header value, falling back to a
+// wildcard registration for the top-level type
+func (r *Registry) Lookup(contentType string) (Handler, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if h, ok := r.handlers[mediaType]; ok {
+		return h, true
+	}
+
+	if topLevel, _, ok := strings.Cut(mediaType, "/"); ok {
+		if h, ok := r.handlers[topLevel+"/*"]; ok {
+			return h, true
+		}
+	}
+
+	return nil, false
+}
+
+// Parses HTML documents with the standard page parser
+type HTMLHandler struct {
+	NewsOnly     bool
+	ExtractLinks bool
+	Level        string
+	ScriptLinks  bool
+	Forms        bool
+	FollowForms  bool
+	Headings     bool
+	Quality      bool
+	Technologies bool
+	Social       bool
+	Selectors    []parser.Selector
+	Validator    *urlvalidate.Validator
+}
+
+func (h HTMLHandler) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	result, err := parser.ParseBytes(ctx, body, pageURL, parser.Options{
+		NewsOnly:     h.NewsOnly,
+		ExtractLinks: h.ExtractLinks,
+		Level:        h.Level,
+		ScriptLinks:  h.ScriptLinks,
+		Forms:        h.Forms,
+		FollowForms:  h.FollowForms,
+		Headings:     h.Headings,
+		Quality:      h.Quality,
+		Technologies: h.Technologies,
+		Social:       h.Social,
+		Selectors:    h.Selectors,
+		Validator:    h.Validator,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Kind = "html"
+	return result, nil
+}
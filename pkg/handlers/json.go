@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// Stores JSON documents such as API responses and OpenAPI/Swagger specs
+// pretty-printed, truncated to MaxSize bytes when MaxSize is positive. The
+// title and description of OpenAPI specs are taken from their info block.
+type JSONHandler struct {
+	MaxSize int
+}
+
+func (h JSONHandler) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	if !json.Valid(body) {
+		return nil, errors.New("invalid JSON document")
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		return nil, err
+	}
+
+	content := pretty.Bytes()
+	if h.MaxSize > 0 && len(content) > h.MaxSize {
+		content = content[:h.MaxSize]
+		for len(content) > 0 && !utf8.Valid(content) {
+			content = content[:len(content)-1]
+		}
+	}
+
+	result := &parser.Result{
+		Content: string(content),
+		Links:   make([]string, 0),
+		Kind:    "json",
+	}
+
+	var spec struct {
+		Info struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		} `json:"info"`
+	}
+	if json.Unmarshal(body, &spec) == nil {
+		result.Title = spec.Info.Title
+		result.Description = spec.Info.Description
+	}
+
+	return result, nil
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// Extracts plain text and document info (title, author) from PDFs. This is a
+// lightweight extractor: it reads uncompressed and Flate-compressed content
+// streams and decodes text drawn with simple fonts, which covers most
+// generated reports and papers but not scanned documents or CID-keyed fonts.
+type PDFHandler struct{}
+
+// Caps the inflated size of a single stream to guard against decompression bombs
+const maxPDFStreamSize = 32 << 20
+
+func (PDFHandler) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(body, " \t\r\n"), []byte("%PDF")) {
+		return nil, errors.New("not a PDF document")
+	}
+
+	result := &parser.Result{
+		Links: make([]string, 0),
+		Kind:  "pdf",
+	}
+
+	streams := pdfStreams(body)
+	// Info dictionaries live either in the file body or, since PDF 1.5, in
+	// compressed object streams
+	sources := append([][]byte{body}, streams...)
+	for _, source := range sources {
+		if result.Title == "" {
+			result.Title = pdfInfoString(source, "/Title")
+		}
+		if result.Author == "" {
+			result.Author = pdfInfoString(source, "/Author")
+		}
+	}
+
+	var content strings.Builder
+	for _, stream := range streams {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		content.WriteString(pdfStreamText(stream))
+	}
+	result.Content = collapseBlankLines(content.String())
+
+	return result, nil
+}
+
+// Returns the decoded contents of every stream object that is either
+// unfiltered or Flate-compressed
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	offset := 0
+
+	for {
+		idx := bytes.Index(data[offset:], []byte("stream"))
+		if idx < 0 {
+			break
+		}
+		start := offset + idx
+		offset = start + len("stream")
+
+		// Skip the "stream" inside "endstream"
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+
+		bodyStart := offset
+		if bodyStart < len(data) && data[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart < len(data) && data[bodyStart] == '\n' {
+			bodyStart++
+		}
+
+		end := bytes.Index(data[bodyStart:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[bodyStart : bodyStart+end]
+		offset = bodyStart + end + len("endstream")
+
+		dictStart := bytes.LastIndex(data[:start], []byte("obj"))
+		if dictStart < 0 {
+			dictStart = 0
+		}
+		dict := data[dictStart:start]
+
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			reader, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			decoded, _ := io.ReadAll(io.LimitReader(reader, maxPDFStreamSize))
+			reader.Close()
+			if len(decoded) > 0 {
+				streams = append(streams, decoded)
+			}
+		case !bytes.Contains(dict, []byte("/Filter")):
+			streams = append(streams, raw)
+		}
+	}
+
+	return streams
+}
+
+// Finds the first string value following key, e.g. /Title (Annual Report)
+func pdfInfoString(data []byte, key string) string {
+	offset := 0
+	for {
+		idx := bytes.Index(data[offset:], []byte(key))
+		if idx < 0 {
+			return ""
+		}
+		pos := offset + idx + len(key)
+		offset = pos
+
+		for pos < len(data) && isPDFWhitespace(data[pos]) {
+			pos++
+		}
+		if pos >= len(data) {
+			return ""
+		}
+
+		var value []byte
+		switch {
+		case data[pos] == '(':
+			value, _ = readPDFLiteral(data, pos)
+		case data[pos] == '<' && pos+1 < len(data) && data[pos+1] != '<':
+			value, _ = readPDFHex(data, pos)
+		default:
+			continue
+		}
+
+		if text := strings.TrimSpace(decodePDFString(value)); text != "" {
+			return text
+		}
+	}
+}
+
+// Pulls the strings shown by text operators (Tj, TJ, ' and ") out of a
+// content stream, starting a new line on text positioning operators
+func pdfStreamText(stream []byte) string {
+	var out strings.Builder
+	var operands []string
+	inText := false
+	inArray := false
+
+	for i := 0; i < len(stream); {
+		ch := stream[i]
+		switch {
+		case ch == '(':
+			value, next := readPDFLiteral(stream, i)
+			operands = append(operands, decodePDFString(value))
+			i = next
+		case ch == '<' && i+1 < len(stream) && stream[i+1] != '<':
+			value, next := readPDFHex(stream, i)
+			operands = append(operands, decodePDFString(value))
+			i = next
+		case ch == '[':
+			inArray = true
+			i++
+		case ch == ']':
+			inArray = false
+			i++
+		case ch == '-' || ch == '.' || (ch >= '0' && ch <= '9'):
+			start := i
+			i++
+			for i < len(stream) && (stream[i] == '.' || (stream[i] >= '0' && stream[i] <= '9')) {
+				i++
+			}
+			// Large negative adjustments inside TJ arrays separate words
+			if inArray {
+				if n, err := strconv.ParseFloat(string(stream[start:i]), 64); err == nil && n <= -200 {
+					operands = append(operands, " ")
+				}
+			}
+		case ch == '%':
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case isPDFOperatorStart(ch):
+			start := i
+			for i < len(stream) && isPDFOperatorChar(stream[i]) {
+				i++
+			}
+			op := string(stream[start:i])
+			switch op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				out.WriteString("\n")
+			case "Tj", "TJ":
+				if inText {
+					out.WriteString(strings.Join(operands, ""))
+				}
+			case "'", "\"":
+				if inText {
+					out.WriteString("\n" + strings.Join(operands, ""))
+				}
+			case "T*", "Td", "TD":
+				if inText {
+					out.WriteString("\n")
+				}
+			}
+			operands = operands[:0]
+		default:
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+func readPDFLiteral(data []byte, pos int) ([]byte, int) {
+	var value []byte
+	depth := 0
+	i := pos
+	for i < len(data) {
+		ch := data[i]
+		switch {
+		case ch == '\\' && i+1 < len(data):
+			i++
+			switch esc := data[i]; esc {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if esc >= '0' && esc <= '7' {
+					octal := 0
+					j := 0
+					for j < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7' {
+						octal = octal*8 + int(data[i]-'0')
+						i++
+						j++
+					}
+					value = append(value, byte(octal))
+					continue
+				}
+				value = append(value, esc)
+			}
+		case ch == '(':
+			if depth > 0 {
+				value = append(value, ch)
+			}
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return value, i + 1
+			}
+			value = append(value, ch)
+		default:
+			value = append(value, ch)
+		}
+		i++
+	}
+	return value, i
+}
+
+func readPDFHex(data []byte, pos int) ([]byte, int) {
+	var value []byte
+	var digits []byte
+	i := pos + 1
+	for ; i < len(data) && data[i] != '>'; i++ {
+		if isHexDigit(data[i]) {
+			digits = append(digits, data[i])
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	for j := 0; j < len(digits); j += 2 {
+		value = append(value, hexValue(digits[j])<<4|hexValue(digits[j+1]))
+	}
+	return value, i + 1
+}
+
+// Decodes UTF-16BE strings marked with a byte order mark and treats anything
+// else as Latin-1, dropping control characters
+func decodePDFString(value []byte) string {
+	if len(value) >= 2 && value[0] == 0xFE && value[1] == 0xFF {
+		units := make([]uint16, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+
+	var sb strings.Builder
+	for _, b := range value {
+		r := rune(b)
+		if unicode.IsPrint(r) || r == '\n' || r == '\t' {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isPDFWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == '\f' || ch == 0
+}
+
+func isPDFOperatorStart(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '\'' || ch == '"'
+}
+
+func isPDFOperatorChar(ch byte) bool {
+	return isPDFOperatorStart(ch) || ch == '*'
+}
+
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func hexValue(ch byte) byte {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0'
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10
+	default:
+		return ch - 'A' + 10
+	}
+}
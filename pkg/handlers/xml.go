@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/user/gocrawler/pkg/parser"
+)
+
+// Extracts URLs from XML sitemaps, sitemap indexes, RSS and Atom feeds.
+// Sitemap <loc> and RSS <link> text as well as Atom <link href> attributes
+// become the result's links; sitemap <lastmod> dates are kept by link.
+type XMLHandler struct{}
+
+func (XMLHandler) Handle(ctx context.Context, body []byte, pageURL string) (*parser.Result, error) {
+	result := &parser.Result{
+		Links: make([]string, 0),
+		Kind:  "xml",
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	var current string
+	var text strings.Builder
+	// The <loc> and <lastmod> of the current sitemap <url> or <sitemap>
+	var entryLoc string
+	var entryModified time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			current = strings.ToLower(t.Name.Local)
+			text.Reset()
+			if current == "url" || current == "sitemap" {
+				entryLoc, entryModified = "", time.Time{}
+			}
+			if current == "link" {
+				for _, attr := range t.Attr {
+					if strings.ToLower(attr.Name.Local) == "href" {
+						addXMLLink(result, base, attr.Value)
+					}
+				}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			value := strings.TrimSpace(text.String())
+			switch name {
+			case "loc", "link":
+				if link, ok := addXMLLink(result, base, value); ok && name == "loc" {
+					entryLoc = link
+				}
+			case "lastmod":
+				entryModified = parseLastmod(value)
+			case "url", "sitemap":
+				if entryLoc != "" && !entryModified.IsZero() {
+					if result.LastModified == nil {
+						result.LastModified = make(map[string]time.Time)
+					}
+					result.LastModified[entryLoc] = entryModified
+				}
+			case "title":
+				if result.Title == "" {
+					result.Title = value
+				}
+			case "description", "subtitle":
+				if result.Description == "" {
+					result.Description = value
+				}
+			}
+			text.Reset()
+		}
+	}
+
+	return result, nil
+}
+
+func addXMLLink(result *parser.Result, base *url.URL, href string) (string, bool) {
+	if href == "" {
+		return "", false
+	}
+
+	resolved, err := parser.ResolveReference(base, href)
+	if err != nil {
+		return "", false
+	}
+
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+	result.Links = append(result.Links, resolved.String())
+	return resolved.String(), true
+}
+
+// W3C datetime precisions used in sitemaps, from a date to fractions of a
+// second
+var lastmodLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// Parses a sitemap lastmod; the zero time when it is missing or malformed
+func parseLastmod(value string) time.Time {
+	for _, layout := range lastmodLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,389 @@
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses cacheable without explicit freshness information (RFC 7231 6.1)
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// A private HTTP cache persisted to a directory. Responses are stored and
+// served according to Cache-Control and Expires, and stale entries with a
+// validator are revalidated with a conditional request.
+type Cache struct {
+	dir string
+	// Cached responses are fresh for at least this long, whatever their
+	// headers say
+	minTTL time.Duration
+
+	mutex sync.Mutex
+	stats Stats
+}
+
+type Stats struct {
+	Hits        int // served from the cache without a request
+	Revalidated int // served from the cache after a 304
+	Misses      int
+	Stored      int
+}
+
+// What is kept of a response; the body is stored next to it
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	// The request headers named by Vary, as sent
+	Vary     http.Header `json:"vary,omitempty"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+func Open(dir string, minTTL time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir, minTTL: minTTL}, nil
+}
+
+func (c *Cache) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+func (c *Cache) count(field *int) {
+	c.mutex.Lock()
+	*field++
+	c.mutex.Unlock()
+}
+
+// Returns a RoundTripper that answers from the cache where it can and sends
+// everything else through next
+func (c *Cache) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &transport{cache: c, next: next}
+}
+
+type transport struct {
+	cache *Cache
+	next  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.cache
+	// Credentials are not part of the key, so authenticated requests
+	// always go to the server
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	key := cacheKey(req.URL.String())
+	cached, body, err := c.load(key)
+	if err != nil || !cached.matches(req) {
+		c.count(&c.stats.Misses)
+		return t.store(key, req)
+	}
+
+	now := time.Now()
+	if cached.age(now) < c.freshness(cached) {
+		c.count(&c.stats.Hits)
+		return cached.response(req, body), nil
+	}
+
+	etag := cached.Header.Get("ETag")
+	lastModified := cached.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		c.count(&c.stats.Misses)
+		return t.store(key, req)
+	}
+
+	conditional := req.Clone(req.Context())
+	if etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := t.next.RoundTrip(conditional)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusNotModified {
+		c.count(&c.stats.Misses)
+		return c.tee(key, req, resp), nil
+	}
+	resp.Body.Close()
+
+	// The 304's headers update the stored ones (RFC 7234 4.3.4)
+	for name, values := range resp.Header {
+		cached.Header[name] = values
+	}
+	cached.StoredAt = now
+	if err := c.writeEntry(key, cached, nil); err != nil {
+		return nil, err
+	}
+	c.count(&c.stats.Revalidated)
+	return cached.response(req, body), nil
+}
+
+func (t *transport) store(key string, req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.cache.tee(key, req, resp), nil
+}
+
+// Arranges for a storable response to be written to the cache once its body
+// has been read to the end; bodies that are closed early are not stored
+func (c *Cache) tee(key string, req *http.Request, resp *http.Response) *http.Response {
+	if !storable(resp) {
+		return resp
+	}
+
+	e := &entry{
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header.Clone(),
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if e.Vary == nil {
+			e.Vary = make(http.Header)
+		}
+		e.Vary[name] = req.Header.Values(name)
+	}
+
+	resp.Body = &teeBody{
+		ReadCloser: resp.Body,
+		done: func(body []byte) {
+			if body == nil {
+				body = []byte{}
+			}
+			e.StoredAt = time.Now()
+			if c.writeEntry(key, e, body) == nil {
+				c.count(&c.stats.Stored)
+			}
+		},
+	}
+	return resp
+}
+
+type teeBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte)
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF && b.done != nil {
+		b.done(b.buf.Bytes())
+		b.done = nil
+	}
+	return n, err
+}
+
+func storable(resp *http.Response) bool {
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	directives := parseCacheControl(resp.Header)
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// How long an entry stays fresh (RFC 7234 4.2.1), from max-age, then
+// Expires, then a tenth of the time since Last-Modified
+func (c *Cache) freshness(e *entry) time.Duration {
+	var lifetime time.Duration
+	directives := parseCacheControl(e.Header)
+	_, noCache := directives["no-cache"]
+	date := e.date()
+
+	if maxAge, ok := directives["max-age"]; ok {
+		if seconds, err := strconv.Atoi(maxAge); err == nil {
+			lifetime = time.Duration(seconds) * time.Second
+		}
+	} else if expires := e.Header.Get("Expires"); expires != "" {
+		// Invalid dates, like "0", mean already expired
+		if at, err := http.ParseTime(expires); err == nil {
+			lifetime = at.Sub(date)
+		}
+	} else if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil {
+		lifetime = date.Sub(lastModified) / 10
+	}
+	if noCache {
+		lifetime = 0
+	}
+
+	if lifetime < c.minTTL {
+		lifetime = c.minTTL
+	}
+	return lifetime
+}
+
+func (e *entry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.StoredAt
+}
+
+// The entry's current age (RFC 7234 4.2.3), simplified to the Age it
+// arrived with plus the time it has been stored
+func (e *entry) age(now time.Time) time.Duration {
+	age := now.Sub(e.StoredAt)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// Reports whether the request sends the same values for the headers the
+// stored response varies on
+func (e *entry) matches(req *http.Request) bool {
+	for name, values := range e.Vary {
+		if strings.Join(values, ",") != strings.Join(req.Header.Values(name), ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entry) response(req *http.Request, body []byte) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-From-Cache", "1")
+	return &http.Response{
+		Status:        e.Status,
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// Parses Cache-Control into directive names, lowercased, and their values
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	return directives
+}
+
+func cacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(key, ext string) string {
+	return filepath.Join(c.dir, key[:2], key+ext)
+}
+
+func (c *Cache) load(key string) (*entry, []byte, error) {
+	data, err := os.ReadFile(c.path(key, ".json"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, nil, err
+	}
+	body, err := os.ReadFile(c.path(key, ".body"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &e, body, nil
+}
+
+// Writes an entry and, unless body is nil, its body. Each file is replaced
+// atomically so concurrent readers never see a partial entry.
+func (c *Cache) writeEntry(key string, e *entry, body []byte) error {
+	if err := os.MkdirAll(filepath.Dir(c.path(key, "")), 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if body != nil {
+		if err := writeFile(c.path(key, ".body"), body); err != nil {
+			return err
+		}
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return writeFile(c.path(key, ".json"), data)
+}
+
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package keywords
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// How much more a keyword in the title counts than one in the content
+const TitleWeight = 5
+
+// Scores pages by how often they mention a set of keywords or phrases
+type Scorer struct {
+	keywords []string
+}
+
+// Keywords are matched case-insensitively as whole words; a keyword may be
+// a phrase of several words
+func New(keywords []string) *Scorer {
+	s := &Scorer{}
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+		if keyword != "" {
+			s.keywords = append(s.keywords, keyword)
+		}
+	}
+	return s
+}
+
+// Reads one keyword or phrase per line, ignoring blank lines and # comments
+func Load(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keywords file: %w", err)
+	}
+	defer file.Close()
+
+	var keywords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keywords = append(keywords, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read keywords file: %w", err)
+	}
+	return keywords, nil
+}
+
+func (s *Scorer) Empty() bool {
+	return s == nil || len(s.keywords) == 0
+}
+
+// Returns the number of keyword occurrences in the content plus TitleWeight
+// times those in the title
+func (s *Scorer) Score(title, content string) int {
+	if s.Empty() {
+		return 0
+	}
+
+	title, content = normalize(title), normalize(content)
+	score := 0
+	for _, keyword := range s.keywords {
+		score += TitleWeight*count(title, keyword) + count(content, keyword)
+	}
+	return score
+}
+
+// Lower-cases the text and collapses whitespace so phrases match across
+// line breaks
+func normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// Counts occurrences of keyword in text that are not part of a longer word
+func count(text, keyword string) int {
+	n := 0
+	for start := 0; ; {
+		i := strings.Index(text[start:], keyword)
+		if i < 0 {
+			return n
+		}
+		i += start
+		end := i + len(keyword)
+		if boundaryBefore(text, i) && boundaryAfter(text, end) {
+			n++
+			start = end
+		} else {
+			start = i + 1
+		}
+	}
+}
+
+func boundaryBefore(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func boundaryAfter(text string, i int) bool {
+	if i >= len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Reports whether opts ask for nothing the streaming link extractor can't
+// provide
+func (opts Options) linksOnly() bool {
+	return opts.Level == LevelLinks && !opts.ScriptLinks && !opts.Forms && !opts.FollowForms &&
+		!opts.Headings && !opts.Quality && !opts.Technologies && !opts.Social && len(opts.Selectors) == 0
+}
+
+// A link whose anchor text is still being read
+type openAnchor struct {
+	href, rel string
+	text      strings.Builder
+}
+
+// Pulls the title and the links ParseWithOptions would find (anchors,
+// image-map areas, frames and meta refreshes) out of a page with the
+// streaming tokenizer, without building a document. The canonical, AMP and
+// hreflang declarations are read too, since the crawler's scope and dedup
+// rules depend on them.
+func extractLinksFast(ctx context.Context, r io.Reader, baseURL string, opts Options) (*Result, error) {
+	result := &Result{Links: make([]string, 0)}
+	addLink := func(href, text, rel string) {}
+	if opts.ExtractLinks {
+		addLink = linkAdder(baseURL, opts.Validator, result)
+	}
+
+	var anchor *openAnchor
+	closeAnchor := func() {
+		if anchor != nil {
+			addLink(anchor.href, strings.Join(strings.Fields(anchor.text.String()), " "), anchor.rel)
+			anchor = nil
+		}
+	}
+	// Like the document parser, the text of every title element, SVG ones
+	// included
+	var title strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(&contextReader{ctx: ctx, r: r})
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, err
+			}
+			closeAnchor()
+			result.Title = title.String()
+			return result, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "a":
+				// An unclosed anchor ends where the next one starts
+				closeAnchor()
+				attrs := tagAttrs(z, hasAttr)
+				if href, ok := attrs["href"]; ok {
+					anchor = &openAnchor{href: href, rel: strings.Join(strings.Fields(attrs["rel"]), " ")}
+				}
+			case "area":
+				attrs := tagAttrs(z, hasAttr)
+				if href, ok := attrs["href"]; ok {
+					addLink(href, strings.Join(strings.Fields(attrs["alt"]), " "), strings.Join(strings.Fields(attrs["rel"]), " "))
+				}
+			case "iframe", "frame":
+				attrs := tagAttrs(z, hasAttr)
+				if src, ok := attrs["src"]; ok {
+					addLink(src, "", "")
+				}
+			case "meta":
+				attrs := tagAttrs(z, hasAttr)
+				if strings.EqualFold(strings.TrimSpace(attrs["http-equiv"]), "refresh") {
+					addLink(refreshURL(attrs["content"]), "", "")
+				}
+			case "link":
+				readLinkTag(tagAttrs(z, hasAttr), baseURL, result)
+			case "html":
+				attrs := tagAttrs(z, hasAttr)
+				_, amp := attrs["amp"]
+				_, bolt := attrs["⚡"]
+				result.AMP = result.AMP || amp || bolt
+			case "title":
+				inTitle = true
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "a":
+				closeAnchor()
+			case "title":
+				inTitle = false
+			}
+
+		case html.TextToken:
+			if anchor != nil {
+				anchor.text.Write(z.Text())
+			}
+			if inTitle {
+				title.Write(z.Text())
+			}
+		}
+	}
+}
+
+// Fills the canonical, AMP and hreflang fields from a <link>, keeping the
+// first canonical and amphtml like the document parser
+func readLinkTag(attrs map[string]string, baseURL string, result *Result) {
+	href := attrs["href"]
+	if strings.TrimSpace(href) == "" {
+		return
+	}
+
+	switch rel := attrs["rel"]; {
+	case rel == "canonical" && result.Canonical == "":
+		if canonical, err := resolveURL(baseURL, href); err == nil {
+			result.Canonical = canonical
+		}
+	case rel == "amphtml" && result.AMPURL == "":
+		if ampURL, err := resolveURL(baseURL, href); err == nil {
+			result.AMPURL = ampURL
+		}
+	default:
+		lang := strings.ToLower(strings.TrimSpace(attrs["hreflang"]))
+		if lang == "" {
+			return
+		}
+		for _, word := range strings.Fields(rel) {
+			if word == "alternate" {
+				if alternate, err := resolveURL(baseURL, href); err == nil {
+					result.Alternates = append(result.Alternates, Alternate{Lang: lang, URL: alternate})
+				}
+				return
+			}
+		}
+	}
+}
+
+// Reads the current tag's attributes; the first of a repeated name wins
+func tagAttrs(z *html.Tokenizer, hasAttr bool) map[string]string {
+	attrs := make(map[string]string)
+	for hasAttr {
+		var key, value []byte
+		key, value, hasAttr = z.TagAttr()
+		if _, ok := attrs[string(key)]; !ok {
+			attrs[string(key)] = string(value)
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A <form> found on a page
+type Form struct {
+	Action string // absolute URL; the page itself when the form has none
+	Method string // upper case, GET by default
+	Fields []FormField
+}
+
+type FormField struct {
+	Name string
+	Type string // input type, or select/textarea
+}
+
+func extractForms(doc *goquery.Document, baseURL string) []Form {
+	var forms []Form
+	doc.Find("form").Each(func(i int, s *goquery.Selection) {
+		action, _ := s.Attr("action")
+		resolved, err := resolveURL(baseURL, action)
+		if err != nil {
+			return
+		}
+
+		method, _ := s.Attr("method")
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" {
+			method = "GET"
+		}
+
+		form := Form{Action: resolved, Method: method}
+		s.Find("input, select, textarea").Each(func(i int, field *goquery.Selection) {
+			name, _ := field.Attr("name")
+			if name == "" {
+				return
+			}
+
+			fieldType := goquery.NodeName(field)
+			if fieldType == "input" {
+				fieldType = "text"
+				if t, ok := field.Attr("type"); ok && strings.TrimSpace(t) != "" {
+					fieldType = strings.ToLower(strings.TrimSpace(t))
+				}
+			}
+			form.Fields = append(form.Fields, FormField{Name: name, Type: fieldType})
+		})
+		forms = append(forms, form)
+	})
+	return forms
+}
+
+// Returns the URL a GET form submits to when left empty, or "" for other
+// methods. Buttons and file inputs don't add parameters.
+func (f Form) EmptySubmission() string {
+	if f.Method != "GET" {
+		return ""
+	}
+
+	target, err := url.Parse(f.Action)
+	if err != nil {
+		return ""
+	}
+
+	query := target.Query()
+	for _, field := range f.Fields {
+		switch field.Type {
+		case "submit", "button", "reset", "image", "file":
+			continue
+		}
+		if !query.Has(field.Name) {
+			query.Set(field.Name, "")
+		}
+	}
+	target.RawQuery = query.Encode()
+	target.Fragment = ""
+	return target.String()
+}
@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// An h1-h6 heading, in document order
+type Heading struct {
+	Level int
+	Text  string
+}
+
+func extractHeadings(doc *goquery.Document) []Heading {
+	var headings []Heading
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		headings = append(headings, Heading{
+			Level: int(goquery.NodeName(s)[1] - '0'),
+			Text:  strings.Join(strings.Fields(s.Text()), " "),
+		})
+	})
+	return headings
+}
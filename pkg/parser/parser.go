@@ -1,24 +1,144 @@
 package parser
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"golang.org/x/net/html"
+
+	"github.com/user/gocrawler/pkg/urlvalidate"
 )
 
 // Represents the parsed data from a webpage
 type Result struct {
 	Title       string
 	Description string
+	Author      string
 	Content     string
 	Links       []string
+	// Where each link came from, in the order of Links
+	Anchors   []Anchor
+	Canonical string // absolute rel=canonical URL, if declared
+	Forms     []Form
+	Headings  []Heading
+	Quality   *Quality
+	Kind      string // html, xml, pdf, json or asset
+	// Sitemap lastmod dates by link, where given
+	LastModified map[string]time.Time
+	// Language variants declared with <link rel="alternate" hreflang>
+	Alternates []Alternate
+	// The page is an AMP page (<html amp> or <html ⚡>)
+	AMP bool
+	// Absolute URL of the page's AMP version, from rel=amphtml
+	AMPURL string
+	// Values extracted by Options.Selectors, by name
+	Fields map[string]string
+	// With Options.Technologies: the meta generator and absolute URLs of
+	// external scripts
+	Generator string
+	Scripts   []string
+	// With Options.Social: links to social media profiles
+	SocialProfiles []SocialProfile
+}
+
+// A link with the text and rel of the element it was found on; both are
+// empty for frames, refreshes, scripts and form submissions
+type Anchor struct {
+	URL  string
+	Text string
+	Rel  string
+}
+
+// A language variant of a page; Lang is lowercased, e.g. "en-gb" or
+// "x-default"
+type Alternate struct {
+	Lang string
+	URL  string
+}
+
+// How much of a page is extracted, each level adding to the one before
+const (
+	// The title and links, read with the streaming tokenizer without
+	// building a document, unless other options need the document
+	LevelLinks = "links"
+	// Also the description, author, canonical and AMP URLs, hreflang
+	// alternates, selectors and the optional extras (headings, forms, ...)
+	LevelMeta = "meta"
+	// Also the main content
+	LevelFull = "full"
+)
+
+// Reports whether level is one of the Level constants
+func IsLevel(level string) bool {
+	return level == LevelLinks || level == LevelMeta || level == LevelFull
+}
+
+// Controls what Parse extracts from a page
+type Options struct {
+	NewsOnly     bool
+	ExtractLinks bool
+	// How much of the page to extract, one of the Level constants; ""
+	// means LevelFull
+	Level string
+	// Also take URL literals from inline scripts and event handlers
+	ScriptLinks bool
+	// Record the page's forms; FollowForms also adds the empty submission
+	// URL of each GET form to the links
+	Forms       bool
+	FollowForms bool
+	// Record the h1-h6 outline
+	Headings bool
+	// Measure content-quality signals
+	Quality bool
+	// Record the generator and script URLs, for technology detection
+	Technologies bool
+	// Record links to social media profiles
+	Social bool
+	// Site-specific extraction; see Selector
+	Selectors []Selector
+
+	// Checks and repairs link references before they are resolved; nil
+	// uses them as found
+	Validator *urlvalidate.Validator
+}
+
+func Parse(ctx context.Context, htmlContent string, baseURL string, extractNewsContent bool, extractLinks bool) (*Result, error) {
+	return ParseWithOptions(ctx, htmlContent, baseURL, Options{
+		NewsOnly:     extractNewsContent,
+		ExtractLinks: extractLinks,
+	})
+}
+
+// Parses a page. A done ctx stops the parse between stages, or while the
+// document is still being read, with ctx.Err().
+func ParseWithOptions(ctx context.Context, htmlContent string, baseURL string, opts Options) (*Result, error) {
+	return parseReader(ctx, strings.NewReader(htmlContent), len(htmlContent), baseURL, opts)
 }
 
-func Parse(htmlContent string, baseURL string, extractNewsContent bool, extractLinks bool) (*Result, error) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+// Like ParseWithOptions, but reads the page straight from body instead of
+// copying it into a string first. body isn't kept.
+func ParseBytes(ctx context.Context, body []byte, baseURL string, opts Options) (*Result, error) {
+	return parseReader(ctx, bytes.NewReader(body), len(body), baseURL, opts)
+}
+
+// size is the length of the document r reads
+func parseReader(ctx context.Context, r io.Reader, size int, baseURL string, opts Options) (*Result, error) {
+	if opts.linksOnly() {
+		return extractLinksFast(ctx, r, baseURL, opts)
+	}
+	extractNewsContent, extractLinks := opts.NewsOnly, opts.ExtractLinks
+
+	doc, err := goquery.NewDocumentFromReader(&contextReader{ctx: ctx, r: r})
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
@@ -42,7 +162,87 @@ func Parse(htmlContent string, baseURL string, extractNewsContent bool, extractL
 		})
 	}
 
-	if extractNewsContent {
+	if content, exists := doc.Find("meta[name='author']").First().Attr("content"); exists {
+		result.Author = strings.TrimSpace(content)
+	}
+
+	if href, exists := doc.Find("link[rel='canonical']").First().Attr("href"); exists && strings.TrimSpace(href) != "" {
+		if canonical, err := resolveURL(baseURL, href); err == nil {
+			result.Canonical = canonical
+		}
+	}
+
+	root := doc.Find("html").First()
+	if _, ok := root.Attr("amp"); ok {
+		result.AMP = true
+	} else if _, ok := root.Attr("⚡"); ok {
+		result.AMP = true
+	}
+	if href, exists := doc.Find("link[rel='amphtml']").First().Attr("href"); exists && strings.TrimSpace(href) != "" {
+		if ampURL, err := resolveURL(baseURL, href); err == nil {
+			result.AMPURL = ampURL
+		}
+	}
+
+	doc.Find("link[rel~='alternate'][hreflang][href]").Each(func(i int, s *goquery.Selection) {
+		lang, _ := s.Attr("hreflang")
+		href, _ := s.Attr("href")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || strings.TrimSpace(href) == "" {
+			return
+		}
+		if alternate, err := resolveURL(baseURL, href); err == nil {
+			result.Alternates = append(result.Alternates, Alternate{Lang: lang, URL: alternate})
+		}
+	})
+
+	if opts.Headings {
+		result.Headings = extractHeadings(doc)
+	}
+
+	if opts.Social {
+		result.SocialProfiles = extractSocialProfiles(doc, baseURL)
+	}
+
+	if opts.Technologies {
+		if content, exists := doc.Find("meta[name='generator']").First().Attr("content"); exists {
+			result.Generator = strings.TrimSpace(content)
+		}
+		doc.Find("script[src]").Each(func(i int, s *goquery.Selection) {
+			src, _ := s.Attr("src")
+			if script, err := resolveURL(baseURL, src); err == nil && strings.TrimSpace(src) != "" {
+				result.Scripts = append(result.Scripts, script)
+			}
+		})
+	}
+
+	if opts.Quality {
+		quality := measureQuality(doc, size)
+		result.Quality = &quality
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opts.Forms || opts.FollowForms {
+		forms := extractForms(doc, baseURL)
+		if opts.Forms {
+			result.Forms = forms
+		}
+		if opts.FollowForms && extractLinks {
+			for _, form := range forms {
+				submission := form.EmptySubmission()
+				if strings.HasPrefix(submission, "http://") || strings.HasPrefix(submission, "https://") {
+					result.Links = append(result.Links, submission)
+					result.Anchors = append(result.Anchors, Anchor{URL: submission})
+				}
+			}
+		}
+	}
+
+	fullContent := opts.Level == "" || opts.Level == LevelFull
+	if fullContent && extractNewsContent {
 		articleBody := doc.Find("[itemprop='articleBody']").Text()
 		if articleBody != "" {
 			result.Content = articleBody
@@ -70,7 +270,7 @@ func Parse(htmlContent string, baseURL string, extractNewsContent bool, extractL
 				}
 			}
 		}
-	} else {
+	} else if fullContent {
 		var mainContent strings.Builder
 		doc.Find("p").Each(func(i int, s *goquery.Selection) {
 			text := strings.TrimSpace(s.Text())
@@ -82,77 +282,166 @@ func Parse(htmlContent string, baseURL string, extractNewsContent bool, extractL
 		result.Content = mainContent.String()
 	}
 
+	if len(opts.Selectors) > 0 {
+		applySelectors(doc, opts.Selectors, result)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	if extractLinks {
-		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
-			href, exists := s.Attr("href")
-			if !exists || href == "" || strings.HasPrefix(href, "#") {
-				return
-			}
+		addLink := linkAdder(baseURL, opts.Validator, result)
 
-			absoluteURL, err := resolveURL(baseURL, href)
-			if err != nil {
-				return
+		// Anchors and image-map areas, then frames, which legacy sites use
+		// for navigation
+		doc.Find("a[href], area[href]").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			rel, _ := s.Attr("rel")
+			text := s.Text()
+			if goquery.NodeName(s) == "area" {
+				text, _ = s.Attr("alt")
 			}
-
-			if !strings.HasPrefix(absoluteURL, "http://") && !strings.HasPrefix(absoluteURL, "https://") {
+			addLink(href, strings.Join(strings.Fields(text), " "), strings.Join(strings.Fields(rel), " "))
+		})
+		doc.Find("iframe[src], frame[src]").Each(func(i int, s *goquery.Selection) {
+			src, _ := s.Attr("src")
+			addLink(src, "", "")
+		})
+		doc.Find("meta[http-equiv]").Each(func(i int, s *goquery.Selection) {
+			if equiv, _ := s.Attr("http-equiv"); !strings.EqualFold(strings.TrimSpace(equiv), "refresh") {
 				return
 			}
+			content, _ := s.Attr("content")
+			addLink(refreshURL(content), "", "")
+		})
 
-			if shouldSkipURL(absoluteURL) {
-				return
+		if opts.ScriptLinks {
+			for _, ref := range scriptLinks(doc) {
+				addLink(ref, "", "")
 			}
-
-			result.Links = append(result.Links, absoluteURL)
-		})
+		}
 	}
 
 	return result, nil
 }
 
+// Fails reads once ctx is done, so parsing a huge document can be abandoned
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Returns a function that adds a link found on the page to result, with its
+// anchor text and rel, once it resolves to an http(s) URL
+func linkAdder(baseURL string, validator *urlvalidate.Validator, result *Result) func(href, text, rel string) {
+	baseScheme := ""
+	if base, err := url.Parse(baseURL); err == nil {
+		baseScheme = base.Scheme
+	}
+
+	return func(href, text, rel string) {
+		if href == "" || strings.HasPrefix(href, "#") {
+			return
+		}
+
+		href, ok := validator.Check(href, baseScheme)
+		if !ok {
+			return
+		}
+
+		absoluteURL, err := resolveURL(baseURL, href)
+		if err != nil {
+			return
+		}
+
+		if !strings.HasPrefix(absoluteURL, "http://") && !strings.HasPrefix(absoluteURL, "https://") {
+			return
+		}
+
+		result.Links = append(result.Links, absoluteURL)
+		result.Anchors = append(result.Anchors, Anchor{URL: absoluteURL, Text: text, Rel: rel})
+	}
+}
+
+// Returns the target of a meta refresh, e.g. "5; url=/next" gives "/next"
+func refreshURL(content string) string {
+	_, target, found := strings.Cut(content, ";")
+	if !found {
+		// A bare delay refreshes the page itself
+		return ""
+	}
+
+	target = strings.TrimSpace(target)
+	if len(target) >= 3 && strings.EqualFold(target[:3], "url") {
+		rest := strings.TrimSpace(target[3:])
+		if strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	return strings.Trim(target, `"'`)
+}
+
 func resolveURL(baseURL, href string) (string, error) {
 	base, err := url.Parse(baseURL)
 	if err != nil {
 		return "", err
 	}
 
-	relative, err := url.Parse(href)
+	resolvedURL, err := ResolveReference(base, href)
 	if err != nil {
 		return "", err
 	}
-
-	resolvedURL := base.ResolveReference(relative)
 	return resolvedURL.String(), nil
 }
 
-func shouldSkipURL(rawURL string) bool {
-	skipExtensions := []string{
-		".pdf", ".jpg", ".jpeg", ".png", ".gif", ".css", ".js",
-		".ico", ".svg", ".xml", ".json", ".mp3", ".mp4", ".avi",
-		".mov", ".mpg", ".mpeg", ".zip", ".tar", ".gz", ".rar",
-	}
-
-	lowercaseURL := strings.ToLower(rawURL)
+// Resolves a link found on the base page the way browsers do: surrounding
+// whitespace and embedded tabs/newlines are dropped, and protocol-relative
+// references (//host/path, also written with backslashes) take the base
+// page's scheme.
+func ResolveReference(base *url.URL, href string) (*url.URL, error) {
+	href = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(href))
 
-	for _, ext := range skipExtensions {
-		if strings.HasSuffix(lowercaseURL, ext) {
-			return true
+	if len(href) >= 2 && isSlash(href[0]) && isSlash(href[1]) {
+		rest := strings.TrimLeft(href, "/\\")
+		// Backslashes count as slashes up to the query or fragment
+		if end := strings.IndexAny(rest, "?#"); end >= 0 {
+			rest = strings.ReplaceAll(rest[:end], "\\", "/") + rest[end:]
+		} else {
+			rest = strings.ReplaceAll(rest, "\\", "/")
 		}
-	}
 
-	skipPatterns := []string{
-		"/cdn-cgi/", "/wp-admin/", "/wp-includes/",
-		"javascript:", "mailto:", "tel:", "sms:",
-		"/feed/", "/rss/", "/print/", "/search?",
-		"login", "logout", "signin", "signup", "register",
+		resolved, err := url.Parse(base.Scheme + "://" + rest)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Host == "" {
+			return nil, fmt.Errorf("protocol-relative link without host: %q", href)
+		}
+		return resolved, nil
 	}
 
-	for _, pattern := range skipPatterns {
-		if strings.Contains(lowercaseURL, pattern) {
-			return true
-		}
+	relative, err := url.Parse(href)
+	if err != nil {
+		return nil, err
 	}
+	return base.ResolveReference(relative), nil
+}
 
-	return false
+func isSlash(ch byte) bool {
+	return ch == '/' || ch == '\\'
 }
 
 func StripTags(html string) string {
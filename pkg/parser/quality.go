@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Content-quality signals for telling thin or boilerplate pages apart
+type Quality struct {
+	WordCount int
+	// Visible text characters per character of HTML, 0-1
+	TextRatio float64
+	LinkCount int
+	// Share of the visible text inside navigation, headers, footers,
+	// sidebars and similar page furniture, 0-100
+	BoilerplatePercent float64
+}
+
+// Elements and class/id fragments that mark page furniture
+var (
+	boilerplateElements  = map[string]bool{"nav": true, "header": true, "footer": true, "aside": true}
+	boilerplateFragments = []string{"nav", "menu", "footer", "header", "sidebar", "breadcrumb", "cookie", "banner", "share", "social"}
+)
+
+func measureQuality(doc *goquery.Document, htmlLength int) Quality {
+	var q Quality
+	q.LinkCount = doc.Find("a[href]").Length()
+
+	var textChars, boilerplateChars int
+	var walk func(n *html.Node, boilerplate bool)
+	walk = func(n *html.Node, boilerplate bool) {
+		switch n.Type {
+		case html.TextNode:
+			text := strings.TrimSpace(n.Data)
+			if text == "" {
+				return
+			}
+			textChars += len(text)
+			q.WordCount += len(strings.Fields(text))
+			if boilerplate {
+				boilerplateChars += len(text)
+			}
+			return
+		case html.ElementNode:
+			switch n.Data {
+			case "script", "style", "noscript", "template", "head":
+				return
+			}
+			boilerplate = boilerplate || isBoilerplate(n)
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, boilerplate)
+		}
+	}
+	for _, root := range doc.Nodes {
+		walk(root, false)
+	}
+
+	if htmlLength > 0 {
+		q.TextRatio = float64(textChars) / float64(htmlLength)
+	}
+	if textChars > 0 {
+		q.BoilerplatePercent = 100 * float64(boilerplateChars) / float64(textChars)
+	}
+	return q
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateElements[n.Data] {
+		return true
+	}
+
+	for _, attr := range n.Attr {
+		if attr.Key == "role" && (attr.Val == "navigation" || attr.Val == "banner" || attr.Val == "contentinfo") {
+			return true
+		}
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		value := strings.ToLower(attr.Val)
+		for _, fragment := range boilerplateFragments {
+			if strings.Contains(value, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
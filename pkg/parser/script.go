@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// Navigation calls whose argument may be any relative URL
+	locationPattern = regexp.MustCompile(`(?:location(?:\.href)?\s*=|location\.(?:assign|replace)\s*\(|window\.open\s*\()\s*['"]([^'"\s]+)['"]`)
+	// Other string literals, accepted only when absolute or root-relative
+	literalPattern = regexp.MustCompile(`['"]((?:https?:)?/[^'"\s]*)['"]`)
+	// Characters that don't occur in real URL literals but do in regular
+	// expressions, templates and markup
+	notURLChars = `<>{}\$*()^|`
+)
+
+// Finds URL literals in inline scripts and on* event attributes. Any string
+// passed to location, location.assign/replace or window.open is taken;
+// other literals only when they are absolute http(s) URLs or root-relative
+// paths that look like URLs.
+func scriptLinks(doc *goquery.Document) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	scan := func(code string) {
+		for _, match := range locationPattern.FindAllStringSubmatch(code, -1) {
+			add(match[1])
+		}
+		for _, match := range literalPattern.FindAllStringSubmatch(code, -1) {
+			if looksLikeURL(match[1]) {
+				add(match[1])
+			}
+		}
+	}
+
+	doc.Find("script").Each(func(i int, s *goquery.Selection) {
+		if _, external := s.Attr("src"); external {
+			return
+		}
+		if scriptType, ok := s.Attr("type"); ok && !isJavaScriptType(scriptType) {
+			return
+		}
+		scan(s.Text())
+	})
+
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		for _, attr := range s.Nodes[0].Attr {
+			if strings.HasPrefix(strings.ToLower(attr.Key), "on") {
+				scan(attr.Val)
+			}
+		}
+	})
+
+	return refs
+}
+
+func looksLikeURL(literal string) bool {
+	if strings.ContainsAny(literal, notURLChars) || len(literal) > 2048 {
+		return false
+	}
+
+	if strings.HasPrefix(literal, "http://") || strings.HasPrefix(literal, "https://") {
+		host := strings.SplitN(strings.SplitN(literal, "://", 2)[1], "/", 2)[0]
+		return strings.Contains(host, ".") || strings.HasPrefix(host, "localhost")
+	}
+
+	// Root-relative paths; "//" (protocol-relative or a comment) and paths
+	// without a letter, such as "/" or "/2/", are too ambiguous
+	if !strings.HasPrefix(literal, "/") || strings.HasPrefix(literal, "//") {
+		return false
+	}
+	return strings.IndexFunc(literal, func(r rune) bool {
+		return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+	}) >= 0
+}
+
+func isJavaScriptType(scriptType string) bool {
+	scriptType = strings.ToLower(strings.TrimSpace(scriptType))
+	return scriptType == "" || scriptType == "module" || strings.Contains(scriptType, "javascript") || strings.Contains(scriptType, "ecmascript")
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Extracts a named value with a CSS selector: the text of the matching
+// elements or, with Attr, the value of that attribute
+type Selector struct {
+	Name string
+	CSS  string
+	Attr string
+}
+
+// Parses "name=selector" or "name=selector@attr", e.g.
+// "published=time[datetime]@datetime"
+func ParseSelector(spec string) (Selector, error) {
+	name, css, ok := strings.Cut(spec, "=")
+	name, css = strings.TrimSpace(name), strings.TrimSpace(css)
+	if !ok || name == "" || css == "" {
+		return Selector{}, fmt.Errorf("invalid selector %q, expected name=selector[@attr]", spec)
+	}
+
+	selector := Selector{Name: name, CSS: css}
+	if at := strings.LastIndex(css, "@"); at > 0 && !strings.ContainsAny(css[at:], " []>+~:") {
+		selector.CSS, selector.Attr = strings.TrimSpace(css[:at]), css[at+1:]
+	}
+	return selector, nil
+}
+
+// Applies the selectors: title, description, author and content replace
+// the built-in values when they match; other names are returned as fields.
+// Several matches are joined with newlines for content and ", " otherwise.
+func applySelectors(doc *goquery.Document, selectors []Selector, result *Result) {
+	for _, selector := range selectors {
+		var values []string
+		doc.Find(selector.CSS).Each(func(i int, s *goquery.Selection) {
+			value := strings.Join(strings.Fields(s.Text()), " ")
+			if selector.Attr != "" {
+				value, _ = s.Attr(selector.Attr)
+				value = strings.TrimSpace(value)
+			}
+			if value != "" {
+				values = append(values, value)
+			}
+		})
+		if len(values) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(selector.Name) {
+		case "title":
+			result.Title = strings.Join(values, ", ")
+		case "description":
+			result.Description = strings.Join(values, ", ")
+		case "author":
+			result.Author = strings.Join(values, ", ")
+		case "content":
+			result.Content = strings.Join(values, "\n")
+		default:
+			if result.Fields == nil {
+				result.Fields = make(map[string]string)
+			}
+			result.Fields[selector.Name] = strings.Join(values, ", ")
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// A link to a social media profile, normalized so every way of writing it
+// gives the same URL
+type SocialProfile struct {
+	Network string // x, linkedin, facebook, instagram or youtube
+	Handle  string // e.g. "acme", "company/acme", "@acme"
+	URL     string
+}
+
+// Hosts of each network, without www., m. and mobile. prefixes
+var socialHosts = map[string]string{
+	"twitter.com":   "x",
+	"x.com":         "x",
+	"linkedin.com":  "linkedin",
+	"facebook.com":  "facebook",
+	"fb.com":        "facebook",
+	"instagram.com": "instagram",
+	"youtube.com":   "youtube",
+}
+
+// First path segments that are features of the network, not profiles
+var socialNonProfiles = map[string]map[string]bool{
+	"x": {
+		"intent": true, "share": true, "home": true, "hashtag": true, "search": true,
+		"i": true, "login": true, "signup": true, "explore": true, "settings": true,
+	},
+	"facebook": {
+		"sharer": true, "sharer.php": true, "share.php": true, "dialog": true, "plugins": true,
+		"tr": true, "login": true, "login.php": true, "watch": true, "groups": true, "events": true,
+	},
+	"instagram": {
+		"p": true, "reel": true, "explore": true, "accounts": true, "stories": true,
+	},
+}
+
+// Links to social profiles on the page, deduplicated, in page order
+func extractSocialProfiles(doc *goquery.Document, baseURL string) []SocialProfile {
+	var profiles []SocialProfile
+	seen := make(map[string]bool)
+	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		absolute, err := resolveURL(baseURL, href)
+		if err != nil {
+			return
+		}
+		if profile, ok := socialProfile(absolute); ok && !seen[profile.URL] {
+			seen[profile.URL] = true
+			profiles = append(profiles, profile)
+		}
+	})
+	return profiles
+}
+
+// Recognizes a profile URL, e.g. http://www.twitter.com/Acme?lang=en gives
+// x, "acme", https://x.com/acme
+func socialProfile(rawURL string) (SocialProfile, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return SocialProfile{}, false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "mobile.", "mbasic."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	network, ok := socialHosts[host]
+	if !ok {
+		return SocialProfile{}, false
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return SocialProfile{}, false
+	}
+	first := strings.ToLower(segments[0])
+
+	var handle string
+	switch network {
+	case "linkedin":
+		// Members, companies and schools
+		if len(segments) < 2 || (first != "in" && first != "company" && first != "school") {
+			return SocialProfile{}, false
+		}
+		handle = first + "/" + strings.ToLower(segments[1])
+	case "youtube":
+		switch {
+		case strings.HasPrefix(segments[0], "@"):
+			handle = strings.ToLower(segments[0])
+		case (first == "channel" || first == "c" || first == "user") && len(segments) >= 2:
+			// Channel IDs are case-sensitive
+			handle = first + "/" + segments[1]
+			if first != "channel" {
+				handle = strings.ToLower(handle)
+			}
+		default:
+			return SocialProfile{}, false
+		}
+	case "facebook":
+		if socialNonProfiles[network][first] {
+			return SocialProfile{}, false
+		}
+		if first == "profile.php" {
+			id := u.Query().Get("id")
+			if id == "" {
+				return SocialProfile{}, false
+			}
+			handle = "profile.php?id=" + id
+		} else {
+			handle = first
+		}
+	default:
+		if socialNonProfiles[network][first] {
+			return SocialProfile{}, false
+		}
+		handle = strings.TrimPrefix(first, "@")
+	}
+
+	canonicalHost := host
+	if network == "x" {
+		canonicalHost = "x.com"
+	} else if network == "facebook" {
+		canonicalHost = "facebook.com"
+	}
+	return SocialProfile{
+		Network: network,
+		Handle:  handle,
+		URL:     "https://" + canonicalHost + "/" + handle,
+	}, true
+}
@@ -0,0 +1,229 @@
+package queryparams
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Tracking parameters that never change a page's content
+var TrackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_ga", "yclid"}
+
+// Session ID parameters of common server frameworks
+var SessionParams = []string{"phpsessid", "jsessionid", "sid", "sessionid", "session_id", "aspsessionid*", "cfid", "cftoken"}
+
+const (
+	// Remove the listed parameters
+	Strip = "strip"
+	// Remove every parameter except the listed ones
+	Keep = "keep"
+	// Order parameters by name, so reordered links are one URL
+	Sort = "sort"
+)
+
+// One rule: an action for the parameters of URLs on matching hosts
+type Rule struct {
+	// "example.com", "*.example.com" (subdomains only) or "*" (all hosts)
+	Host   string
+	Action string
+	// Parameter names, case-insensitive; a trailing "*" matches a prefix
+	Params []string
+}
+
+// Rewrites URL query strings by per-host rules. Every rule matching a URL's
+// host is applied, in order.
+type Policy struct {
+	rules     []Rule
+	rewritten atomic.Int64
+}
+
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{}
+	for _, rule := range rules {
+		if err := p.Add(rule); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Reads a rules file with one rule per line: host, action and, for strip
+// and keep, a comma-separated parameter list, e.g.:
+//
+//	# host, action, parameters
+//	*                 strip utm_*,fbclid,gclid
+//	shop.example.com  keep  id,page
+//	*.example.com     sort
+//
+// Blank lines and lines starting with # are ignored. The word "tracking" or
+// "session" in a list stands for TrackingParams or SessionParams.
+func Load(filename string) (*Policy, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query parameter rules: %w", err)
+	}
+	defer file.Close()
+
+	p := &Policy{}
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		rule := Rule{Host: fields[0]}
+		if len(fields) > 1 {
+			rule.Action = fields[1]
+		}
+		if len(fields) > 2 {
+			rule.Params = ParseList(strings.Join(fields[2:], ""))
+		}
+		if err := p.Add(rule); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, lineNum, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query parameter rules: %w", err)
+	}
+	return p, nil
+}
+
+// Splits a comma-separated parameter list, expanding "tracking" and
+// "session"
+func ParseList(list string) []string {
+	var params []string
+	for _, param := range strings.Split(list, ",") {
+		switch param = strings.TrimSpace(param); param {
+		case "":
+		case "tracking":
+			params = append(params, TrackingParams...)
+		case "session":
+			params = append(params, SessionParams...)
+		default:
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+func (p *Policy) Add(rule Rule) error {
+	switch rule.Action {
+	case Strip, Keep:
+		if len(rule.Params) == 0 {
+			return fmt.Errorf("%s rule for %s needs a parameter list", rule.Action, rule.Host)
+		}
+	case Sort:
+	default:
+		return fmt.Errorf("unknown action %q (want strip, keep or sort)", rule.Action)
+	}
+	if rule.Host == "" {
+		return fmt.Errorf("rule without a host")
+	}
+
+	rule.Host = strings.ToLower(rule.Host)
+	params := make([]string, len(rule.Params))
+	for i, param := range rule.Params {
+		params[i] = strings.ToLower(param)
+	}
+	rule.Params = params
+	p.rules = append(p.rules, rule)
+	return nil
+}
+
+// Returns the URL with its query rewritten by the matching rules; URLs
+// the rules leave alone are returned unchanged
+func (p *Policy) Rewrite(rawURL string) string {
+	if len(p.rules) == 0 {
+		return rawURL
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.RawQuery == "" {
+		return rawURL
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	params := strings.Split(parsedURL.RawQuery, "&")
+	for _, rule := range p.rules {
+		if rule.matchesHost(host) {
+			params = rule.apply(params)
+		}
+	}
+
+	query := strings.Join(params, "&")
+	if query == parsedURL.RawQuery {
+		return rawURL
+	}
+	p.rewritten.Add(1)
+	parsedURL.RawQuery = query
+	parsedURL.ForceQuery = false
+	return parsedURL.String()
+}
+
+// Number of URLs Rewrite has changed
+func (p *Policy) Rewritten() int64 {
+	return p.rewritten.Load()
+}
+
+func (r Rule) matchesHost(host string) bool {
+	switch {
+	case r.Host == "*":
+		return true
+	case strings.HasPrefix(r.Host, "*."):
+		return strings.HasSuffix(host, r.Host[1:])
+	default:
+		return host == r.Host
+	}
+}
+
+func (r Rule) apply(params []string) []string {
+	if r.Action == Sort {
+		sorted := append([]string(nil), params...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return paramName(sorted[i]) < paramName(sorted[j])
+		})
+		return sorted
+	}
+
+	kept := params[:0:0]
+	for _, param := range params {
+		if param == "" {
+			continue
+		}
+		if r.matchesParam(paramName(param)) == (r.Action == Keep) {
+			kept = append(kept, param)
+		}
+	}
+	return kept
+}
+
+func (r Rule) matchesParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range r.Params {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// The unescaped name of a "name=value" query parameter
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
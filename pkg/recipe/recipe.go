@@ -0,0 +1,162 @@
+package recipe
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A site's crawl settings, packaged as a YAML file so a team can share
+// them instead of long flag lists. Settings left out keep the flag
+// defaults, and flags given on the command line win over the recipe.
+type Recipe struct {
+	Name        string `yaml:"-"`
+	Description string `yaml:"description"`
+	// Seed URLs in the seeds-file syntax, e.g.
+	// "https://example.com/blog depth=3 tag=blog"
+	Seeds  []string `yaml:"seeds"`
+	Scope  Scope    `yaml:"scope"`
+	Rate   Rate     `yaml:"rate"`
+	Output Output   `yaml:"output"`
+	// Field name to CSS selector, with an optional @attr, as for -select
+	Selectors map[string]string `yaml:"selectors"`
+	// Any other flags, by name without the dash
+	Flags map[string]string `yaml:"flags"`
+}
+
+type Scope struct {
+	Depth        *int   `yaml:"depth"`
+	MaxPages     *int   `yaml:"max_pages"`
+	StayOnDomain *bool  `yaml:"stay_on_domain"`
+	Filter       string `yaml:"filter"`
+	// Entries in the -allowlist and -denylist syntax
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+type Rate struct {
+	Delay         string `yaml:"delay"`
+	DelayJitter   string `yaml:"delay_jitter"`
+	Workers       *int   `yaml:"workers"`
+	RespectRobots *bool  `yaml:"robots"`
+}
+
+type Output struct {
+	Format string `yaml:"format"`
+	File   string `yaml:"file"`
+	// CSV columns to write, in order
+	Columns []string `yaml:"columns"`
+}
+
+// A flag and the value a recipe gives it
+type Setting struct {
+	Flag  string
+	Value string
+}
+
+// Loads a recipe by name from dir (<dir>/<name>.yaml or .yml), or from a
+// path to a YAML file
+func Load(dir, name string) (*Recipe, error) {
+	path := name
+	if ext := filepath.Ext(name); ext != ".yaml" && ext != ".yml" {
+		path = filepath.Join(dir, name+".yaml")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = filepath.Join(dir, name+".yml")
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe: %w", err)
+	}
+
+	var r Recipe
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&r); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe %s: %w", path, err)
+	}
+	r.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &r, nil
+}
+
+// Loads every recipe in dir, sorted by name
+func List(dir string) ([]*Recipe, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	recipes := make([]*Recipe, 0, len(paths))
+	for _, path := range paths {
+		r, err := Load(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, r)
+	}
+	return recipes, nil
+}
+
+// The flags the recipe sets, in a stable order. Seeds and the allow and
+// deny entries have no flag form and are read from the recipe directly.
+func (r *Recipe) Settings() []Setting {
+	var settings []Setting
+	add := func(flag, value string) {
+		if value != "" {
+			settings = append(settings, Setting{Flag: flag, Value: value})
+		}
+	}
+
+	if r.Scope.Depth != nil {
+		add("depth", strconv.Itoa(*r.Scope.Depth))
+	}
+	if r.Scope.MaxPages != nil {
+		add("max", strconv.Itoa(*r.Scope.MaxPages))
+	}
+	if r.Scope.StayOnDomain != nil {
+		add("stay-domain", strconv.FormatBool(*r.Scope.StayOnDomain))
+	}
+	add("filter", r.Scope.Filter)
+
+	add("delay", r.Rate.Delay)
+	add("delay-jitter", r.Rate.DelayJitter)
+	if r.Rate.Workers != nil {
+		add("workers", strconv.Itoa(*r.Rate.Workers))
+	}
+	if r.Rate.RespectRobots != nil {
+		add("robots", strconv.FormatBool(*r.Rate.RespectRobots))
+	}
+
+	add("format", r.Output.Format)
+	add("output", r.Output.File)
+	add("csv-columns", strings.Join(r.Output.Columns, ","))
+
+	for _, name := range sortedKeys(r.Selectors) {
+		add("select", name+"="+r.Selectors[name])
+	}
+	for _, name := range sortedKeys(r.Flags) {
+		add(strings.TrimLeft(name, "-"), r.Flags[name])
+	}
+	return settings
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
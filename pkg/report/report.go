@@ -0,0 +1,164 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Aggregated counts for one group of pages
+type Breakdown struct {
+	Key    string
+	Pages  int
+	Errors int
+	Bytes  int64
+}
+
+// Number of crawled pages linking to an external domain
+type DomainCount struct {
+	Domain string
+	Pages  int
+}
+
+// Structural overview of a crawl
+type Report struct {
+	Total           Breakdown
+	ByDepth         []Breakdown
+	BySection       []Breakdown
+	ExternalDomains []DomainCount
+}
+
+// Reads the records of a JSON crawl output file
+func Load(filename string) ([]storage.PageData, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open crawl output: %w", err)
+	}
+	defer file.Close()
+
+	var pages []storage.PageData
+	if err := json.NewDecoder(file).Decode(&pages); err != nil {
+		return nil, fmt.Errorf("failed to decode crawl output: %w", err)
+	}
+	return pages, nil
+}
+
+func Build(pages []storage.PageData) *Report {
+	byDepth := make(map[int]*Breakdown)
+	bySection := make(map[string]*Breakdown)
+	byDomain := make(map[string]int)
+	report := &Report{Total: Breakdown{Key: "total"}}
+
+	for _, page := range pages {
+		depthGroup, ok := byDepth[page.Depth]
+		if !ok {
+			depthGroup = &Breakdown{Key: strconv.Itoa(page.Depth)}
+			byDepth[page.Depth] = depthGroup
+		}
+
+		section := Section(page.URL)
+		sectionGroup, ok := bySection[section]
+		if !ok {
+			sectionGroup = &Breakdown{Key: section}
+			bySection[section] = sectionGroup
+		}
+
+		for _, group := range []*Breakdown{&report.Total, depthGroup, sectionGroup} {
+			if page.Error != "" {
+				group.Errors++
+			} else {
+				group.Pages++
+			}
+			group.Bytes += page.Size
+		}
+
+		for _, domain := range page.ExternalDomains {
+			byDomain[domain]++
+		}
+	}
+
+	depths := make([]int, 0, len(byDepth))
+	for depth := range byDepth {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for _, depth := range depths {
+		report.ByDepth = append(report.ByDepth, *byDepth[depth])
+	}
+
+	for _, group := range bySection {
+		report.BySection = append(report.BySection, *group)
+	}
+	sort.Slice(report.BySection, func(i, j int) bool {
+		a, b := report.BySection[i], report.BySection[j]
+		if a.Pages+a.Errors != b.Pages+b.Errors {
+			return a.Pages+a.Errors > b.Pages+b.Errors
+		}
+		return a.Key < b.Key
+	})
+
+	for domain, pages := range byDomain {
+		report.ExternalDomains = append(report.ExternalDomains, DomainCount{Domain: domain, Pages: pages})
+	}
+	sort.Slice(report.ExternalDomains, func(i, j int) bool {
+		a, b := report.ExternalDomains[i], report.ExternalDomains[j]
+		if a.Pages != b.Pages {
+			return a.Pages > b.Pages
+		}
+		return a.Domain < b.Domain
+	})
+
+	return report
+}
+
+// Returns the first path segment of a URL, e.g. "/blog" for
+// https://example.com/blog/post, or "/" for the site root
+func Section(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "/"
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(parsedURL.Path, "/"), "/")
+	return "/" + segment
+}
+
+func (r *Report) Write(w io.Writer) error {
+	fmt.Fprintf(w, "Pages: %d  Errors: %d  Bytes: %d\n\n", r.Total.Pages, r.Total.Errors, r.Total.Bytes)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	writeTable(tw, "Depth", r.ByDepth)
+	fmt.Fprintln(tw)
+	writeTable(tw, "Section", r.BySection)
+
+	// Only present when the crawl ran with -external-domains
+	if len(r.ExternalDomains) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "External domain\tPages\t\n")
+		for _, domain := range r.ExternalDomains {
+			fmt.Fprintf(tw, "%s\t%d\t\n", domain.Domain, domain.Pages)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeTable(w io.Writer, title string, groups []Breakdown) {
+	fmt.Fprintf(w, "%s\tPages\tErrors\tBytes\tAvg bytes\t\n", title)
+	for _, group := range groups {
+		avg := int64(0)
+		if group.Pages > 0 {
+			avg = group.Bytes / int64(group.Pages)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t\n", group.Key, group.Pages, group.Errors, group.Bytes, avg)
+	}
+}
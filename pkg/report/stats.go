@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Number of records with one value of a field
+type Count struct {
+	Key   string
+	Pages int
+}
+
+// Outcome summary of a crawl: how its fetches went rather than the site's
+// structure
+type Stats struct {
+	Pages        int
+	Errors       int
+	Bytes        int64
+	StatusCodes  []Count
+	ContentTypes []Count
+	ErrorClasses []Count
+	Hosts        []Count
+}
+
+func BuildStats(pages []storage.PageData) *Stats {
+	stats := &Stats{}
+	statusCodes := make(map[string]int)
+	contentTypes := make(map[string]int)
+	errorClasses := make(map[string]int)
+	hosts := make(map[string]int)
+
+	for _, page := range pages {
+		if page.Error != "" {
+			stats.Errors++
+			class := page.ErrorClass
+			if class == "" {
+				class = "other"
+			}
+			errorClasses[class]++
+		} else {
+			stats.Pages++
+		}
+		stats.Bytes += page.Size
+
+		status := "none"
+		if page.StatusCode != 0 {
+			status = strconv.Itoa(page.StatusCode)
+		}
+		statusCodes[status]++
+
+		if page.ContentType != "" {
+			mediaType, _, _ := strings.Cut(page.ContentType, ";")
+			contentTypes[strings.TrimSpace(mediaType)]++
+		}
+
+		if parsedURL, err := url.Parse(page.URL); err == nil && parsedURL.Host != "" {
+			hosts[parsedURL.Host]++
+		}
+	}
+
+	stats.StatusCodes = sortedCounts(statusCodes)
+	stats.ContentTypes = sortedCounts(contentTypes)
+	stats.ErrorClasses = sortedCounts(errorClasses)
+	stats.Hosts = sortedCounts(hosts)
+	return stats
+}
+
+// Most records first
+func sortedCounts(counts map[string]int) []Count {
+	sorted := make([]Count, 0, len(counts))
+	for key, pages := range counts {
+		sorted = append(sorted, Count{Key: key, Pages: pages})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Pages != sorted[j].Pages {
+			return sorted[i].Pages > sorted[j].Pages
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+func (s *Stats) Write(w io.Writer) error {
+	avg := int64(0)
+	if s.Pages > 0 {
+		avg = s.Bytes / int64(s.Pages)
+	}
+	fmt.Fprintf(w, "Pages: %d  Errors: %d  Bytes: %d  Avg bytes: %d\n\n", s.Pages, s.Errors, s.Bytes, avg)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	writeCounts(tw, "Status code", s.StatusCodes)
+	writeCounts(tw, "Content type", s.ContentTypes)
+	// Only present when the crawl ran with -error-records
+	writeCounts(tw, "Error class", s.ErrorClasses)
+	writeCounts(tw, "Host", s.Hosts)
+	return tw.Flush()
+}
+
+func writeCounts(w io.Writer, title string, counts []Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s\tPages\t\n", title)
+	for _, count := range counts {
+		fmt.Fprintf(w, "%s\t%d\t\n", count.Key, count.Pages)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,105 @@
+package robotstxt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// What is kept of a host's robots.txt on disk
+type diskEntry struct {
+	Origin     string                `json:"origin"`
+	FetchedAt  time.Time             `json:"fetched_at"`
+	CrawlDelay time.Duration         `json:"crawl_delay"`
+	NotFound   bool                  `json:"not_found,omitempty"`
+	Groups     map[string][]diskRule `json:"groups"`
+}
+
+type diskRule struct {
+	Path  string `json:"path"`
+	Allow bool   `json:"allow"`
+}
+
+// A RobotsCache that also keeps every host's rules in dir, one file per
+// origin, so later runs within the expiration don't fetch them again
+func NewDiskCache(dir string, expiration time.Duration) (*RobotsCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create robots cache directory: %w", err)
+	}
+	rc := NewRobotsCache(expiration)
+	rc.dir = dir
+	return rc, nil
+}
+
+func (rc *RobotsCache) diskPath(origin string) string {
+	sum := sha256.Sum256([]byte(origin))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Reads an origin's rules from the cache directory; nil when there are none
+// or they can't be read
+func (rc *RobotsCache) loadDisk(origin string) *RobotsData {
+	content, err := os.ReadFile(rc.diskPath(origin))
+	if err != nil {
+		return nil
+	}
+	var entry diskEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Origin != origin {
+		return nil
+	}
+
+	data := &RobotsData{
+		rules:      make(map[string][]Rule, len(entry.Groups)),
+		createdAt:  entry.FetchedAt,
+		crawlDelay: entry.CrawlDelay,
+		notFound:   entry.NotFound,
+	}
+	for agent, rules := range entry.Groups {
+		data.rules[agent] = make([]Rule, 0, len(rules))
+		for _, rule := range rules {
+			data.rules[agent] = append(data.rules[agent], Rule{path: rule.Path, allow: rule.Allow, userAgent: agent})
+		}
+	}
+	return data
+}
+
+// Writes an origin's rules to the cache directory, replacing the file
+// atomically. Failures only cost a fetch next time, so they are ignored.
+func (rc *RobotsCache) saveDisk(origin string, data *RobotsData) {
+	entry := diskEntry{
+		Origin:     origin,
+		FetchedAt:  data.createdAt,
+		CrawlDelay: data.crawlDelay,
+		NotFound:   data.notFound,
+		Groups:     make(map[string][]diskRule, len(data.rules)),
+	}
+	for agent, rules := range data.rules {
+		entry.Groups[agent] = make([]diskRule, 0, len(rules))
+		for _, rule := range rules {
+			entry.Groups[agent] = append(entry.Groups[agent], diskRule{Path: rule.path, Allow: rule.allow})
+		}
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(rc.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), rc.diskPath(origin))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
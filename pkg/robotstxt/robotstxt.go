@@ -1,25 +1,68 @@
 package robotstxt
 
 import (
+	"container/list"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/user/gocrawler/pkg/sitekey"
 )
 
+// Caches robots.txt rules per origin (scheme + host + port), so http and
+// https, and www and the apex domain, are fetched and cached separately.
+// With a size limit, the least recently used origins are evicted first.
 type RobotsCache struct {
-	cache      map[string]*RobotsData
-	mutex      sync.RWMutex
+	entries    map[string]*list.Element // of *cacheEntry
+	lru        *list.List               // most recently used first
+	maxEntries int
+	// Fetches in progress, by origin
+	pending    map[string]*pendingFetch
+	stats      Stats
+	mutex      sync.Mutex
 	expiration time.Duration
+	// Directory the rules are also kept in, for NewDiskCache
+	dir string
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// A size limit that holds the rules of wide crawls without growing forever
+const DefaultMaxEntries = 10000
+
+type cacheEntry struct {
+	origin string
+	data   *RobotsData
+}
+
+// A robots.txt fetch other workers can wait for; data and err are set
+// before done is closed
+type pendingFetch struct {
+	done chan struct{}
+	data *RobotsData
+	err  error
+}
+
+type Stats struct {
+	Hits      int // answered from memory
+	DiskHits  int // read from the NewDiskCache directory
+	Misses    int // fetched
+	Waited    int // waited for another worker's fetch of the same host
+	Evictions int
+	Entries   int
 }
 
 type RobotsData struct {
 	rules      map[string][]Rule
 	createdAt  time.Time
 	crawlDelay time.Duration
+	// The host answered 404 for its robots.txt
+	notFound bool
 }
 
 type Rule struct {
@@ -30,32 +73,79 @@ type Rule struct {
 
 func NewRobotsCache(expiration time.Duration) *RobotsCache {
 	return &RobotsCache{
-		cache:      make(map[string]*RobotsData),
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		pending:    make(map[string]*pendingFetch),
 		expiration: expiration,
 	}
 }
 
+// Keeps at most max origins in memory, evicting the least recently used;
+// 0 means no limit
+func (rc *RobotsCache) SetMaxEntries(max int) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.maxEntries = max
+	rc.evict()
+}
+
+func (rc *RobotsCache) Stats() Stats {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	stats := rc.stats
+	stats.Entries = rc.lru.Len()
+	return stats
+}
+
+// One crawl's view of a RobotsCache, which may be shared by several crawls,
+// so the client and apex fallback of one crawl never apply to another
+type Checker struct {
+	cache       *RobotsCache
+	client      *http.Client
+	inheritApex bool
+}
+
+// Fetches robots.txt with client, so the requests go through the same
+// transport, timeout and redirect checks as the crawl's page requests;
+// without one, a client with a 10s timeout is used. With inheritApex, a
+// subdomain whose robots.txt is missing (404) follows the robots.txt of its
+// apex domain instead of allowing everything. Only enable that for sites
+// you know share rules.
+func (rc *RobotsCache) Checker(client *http.Client, inheritApex bool) *Checker {
+	if client == nil {
+		client = defaultClient
+	}
+	return &Checker{cache: rc, client: client, inheritApex: inheritApex}
+}
+
 func (rc *RobotsCache) IsAllowed(rawURL, userAgent string) (bool, time.Duration, error) {
+	return rc.Checker(nil, false).IsAllowed(rawURL, userAgent)
+}
+
+func (ch *Checker) IsAllowed(rawURL, userAgent string) (bool, time.Duration, error) {
+	rc := ch.cache
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
 		return false, 0, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
-	host := parsedURL.Scheme + "://" + parsedURL.Host
+	scheme := strings.ToLower(parsedURL.Scheme)
+	hostname := strings.ToLower(parsedURL.Hostname())
 
-	rc.mutex.RLock()
-	robotsData, exists := rc.cache[host]
-	rc.mutex.RUnlock()
+	robotsData, err := rc.robotsFor(origin(scheme, hostname, parsedURL.Port()), userAgent, ch.client)
+	if err != nil {
+		return true, 1 * time.Second, err
+	}
 
-	if !exists || time.Since(robotsData.createdAt) > rc.expiration {
-		robotsData, err = rc.fetchAndParse(host, userAgent)
-		if err != nil {
-			return true, 1 * time.Second, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	if robotsData.notFound && ch.inheritApex {
+		if apex := apexDomain(hostname); apex != hostname {
+			// The apex rules are cached under the apex origin, so every
+			// subdomain without its own robots.txt shares one fetch
+			apexData, err := rc.robotsFor(origin(scheme, apex, parsedURL.Port()), userAgent, ch.client)
+			if err == nil {
+				robotsData = apexData
+			}
 		}
-
-		rc.mutex.Lock()
-		rc.cache[host] = robotsData
-		rc.mutex.Unlock()
 	}
 
 	path := parsedURL.Path
@@ -63,40 +153,162 @@ func (rc *RobotsCache) IsAllowed(rawURL, userAgent string) (bool, time.Duration,
 		path = "/"
 	}
 
-	allowed := rc.checkRules(robotsData, path, userAgent)
-	if allowed != nil {
-		return *allowed, robotsData.crawlDelay, nil
+	allowed, _ := robotsData.Match(path, userAgent)
+	return allowed, robotsData.crawlDelay, nil
+}
+
+func (rc *RobotsCache) robotsFor(host, userAgent string, client *http.Client) (*RobotsData, error) {
+	robotsData, exists := rc.get(host)
+	if exists && time.Since(robotsData.createdAt) <= rc.expiration {
+		rc.count(&rc.stats.Hits)
+		return robotsData, nil
 	}
 
-	allowed = rc.checkRules(robotsData, path, "*")
-	if allowed != nil {
-		return *allowed, robotsData.crawlDelay, nil
+	// Workers reaching a new host together share one fetch
+	rc.mutex.Lock()
+	if call, ok := rc.pending[host]; ok {
+		rc.stats.Waited++
+		rc.mutex.Unlock()
+		<-call.done
+		return call.data, call.err
+	}
+	// Another worker's fetch may have finished since the lookup above
+	if element, ok := rc.entries[host]; ok {
+		if data := element.Value.(*cacheEntry).data; time.Since(data.createdAt) <= rc.expiration {
+			rc.lru.MoveToFront(element)
+			rc.stats.Hits++
+			rc.mutex.Unlock()
+			return data, nil
+		}
 	}
+	call := &pendingFetch{done: make(chan struct{})}
+	rc.pending[host] = call
+	rc.mutex.Unlock()
+
+	call.data, call.err = rc.load(host, userAgent, client, !exists)
 
-	return true, robotsData.crawlDelay, nil
+	rc.mutex.Lock()
+	delete(rc.pending, host)
+	rc.mutex.Unlock()
+	close(call.done)
+	return call.data, call.err
 }
 
-func (rc *RobotsCache) checkRules(data *RobotsData, path, userAgent string) *bool {
-	rules, exists := data.rules[userAgent]
-	if !exists {
-		return nil
+// Reads a host's rules from the cache directory, unless checkDisk is false,
+// or fetches them, and caches them
+func (rc *RobotsCache) load(host, userAgent string, client *http.Client, checkDisk bool) (*RobotsData, error) {
+	if rc.dir != "" && checkDisk {
+		if robotsData := rc.loadDisk(host); robotsData != nil && time.Since(robotsData.createdAt) <= rc.expiration {
+			rc.count(&rc.stats.DiskHits)
+			rc.put(host, robotsData)
+			return robotsData, nil
+		}
 	}
 
-	for _, rule := range rules {
-		if strings.HasPrefix(path, rule.path) {
-			return &rule.allow
-		}
+	rc.count(&rc.stats.Misses)
+	robotsData, err := fetchAndParse(client, host, userAgent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
 	}
 
-	return nil
+	rc.put(host, robotsData)
+	if rc.dir != "" {
+		rc.saveDisk(host, robotsData)
+	}
+	return robotsData, nil
 }
 
-func (rc *RobotsCache) fetchAndParse(host, userAgent string) (*RobotsData, error) {
-	robotsURL := host + "/robots.txt"
+func (rc *RobotsCache) count(field *int) {
+	rc.mutex.Lock()
+	*field++
+	rc.mutex.Unlock()
+}
+
+// Looks up an origin, marking it as recently used
+func (rc *RobotsCache) get(origin string) (*RobotsData, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	element, ok := rc.entries[origin]
+	if !ok {
+		return nil, false
+	}
+	rc.lru.MoveToFront(element)
+	return element.Value.(*cacheEntry).data, true
+}
+
+func (rc *RobotsCache) put(origin string, data *RobotsData) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if element, ok := rc.entries[origin]; ok {
+		element.Value.(*cacheEntry).data = data
+		rc.lru.MoveToFront(element)
+		return
+	}
+	rc.entries[origin] = rc.lru.PushFront(&cacheEntry{origin: origin, data: data})
+	rc.evict()
+}
+
+// Drops least recently used origins down to the size limit; the mutex must
+// be held
+func (rc *RobotsCache) evict() {
+	for rc.maxEntries > 0 && rc.lru.Len() > rc.maxEntries {
+		oldest := rc.lru.Back()
+		rc.lru.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cacheEntry).origin)
+		rc.stats.Evictions++
+	}
+}
+
+// Cache key for a host, leaving out the scheme's default port
+func origin(scheme, hostname, port string) string {
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		hostname = net.JoinHostPort(hostname, port)
+	} else if strings.Contains(hostname, ":") {
+		hostname = "[" + hostname + "]"
+	}
+	return scheme + "://" + hostname
+}
+
+// The registered domain (eTLD+1) of a host: blog.example.co.uk ->
+// example.co.uk, while user.github.io stays user.github.io
+func apexDomain(hostname string) string {
+	return sitekey.Domain.Key(hostname)
+}
+
+// Reports whether userAgent may fetch path, checking the user agent's own
+// group and then the * group. Returns the rule that decided, or nil when no
+// rule matched and the path is allowed.
+func (data *RobotsData) Match(path, userAgent string) (bool, *Rule) {
+	for _, agent := range []string{userAgent, "*"} {
+		for i, rule := range data.rules[agent] {
+			if strings.HasPrefix(path, rule.path) {
+				return rule.allow, &data.rules[agent][i]
+			}
+		}
+	}
+	return true, nil
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// The Crawl-delay for the site, 1s when it sets none
+func (data *RobotsData) CrawlDelay() time.Duration {
+	return data.crawlDelay
+}
+
+// The rule as written in robots.txt, with its group: "Disallow: /private/
+// (User-agent: *)"
+func (r *Rule) String() string {
+	directive := "Disallow"
+	if r.allow {
+		directive = "Allow"
 	}
+	return fmt.Sprintf("%s: %s (User-agent: %s)", directive, r.path, r.userAgent)
+}
+
+func fetchAndParse(client *http.Client, host, userAgent string) (*RobotsData, error) {
+	robotsURL := host + "/robots.txt"
 
 	req, err := http.NewRequest("GET", robotsURL, nil)
 	if err != nil {
@@ -110,6 +322,11 @@ func (rc *RobotsCache) fetchAndParse(host, userAgent string) (*RobotsData, error
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		data, err := defaultRobotsData()
+		data.notFound = true
+		return data, err
+	}
 	if resp.StatusCode != http.StatusOK {
 		return defaultRobotsData()
 	}
@@ -122,6 +339,11 @@ func (rc *RobotsCache) fetchAndParse(host, userAgent string) (*RobotsData, error
 	return parseRobotsTxt(string(body)), nil
 }
 
+// Parses robots.txt content
+func Parse(content string) *RobotsData {
+	return parseRobotsTxt(content)
+}
+
 func parseRobotsTxt(content string) *RobotsData {
 	data := &RobotsData{
 		rules:      make(map[string][]Rule),
@@ -0,0 +1,258 @@
+// Package script runs a user-supplied Starlark function on every record
+// before it is saved, to transform or drop results and add custom fields
+// without recompiling the crawler.
+//
+// The script defines process(page). page is a dict with the record's url,
+// title, description, author, content, links, depth, status_code,
+// content_type, tags and fields (a dict of strings). process returns the
+// page, changed or not, to save it, None to save it unchanged, or False to
+// drop it. Keys the page doesn't have are added to its fields.
+//
+//	def process(page):
+//	    if "/tag/" in page["url"]:
+//	        return False
+//	    page["words"] = str(len(page["content"].split()))
+//	    return page
+package script
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync/atomic"
+
+	"go.starlark.net/starlark"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Bounds the work one call may do, so a runaway loop fails the page instead
+// of hanging the crawl
+const maxSteps = 10_000_000
+
+// A loaded script; process may be called from several workers at once
+type Script struct {
+	path    string
+	process starlark.Callable
+	dropped atomic.Int64
+}
+
+func Load(path string) (*Script, error) {
+	thread := newThread(path)
+	globals, err := starlark.ExecFile(thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load script: %w", err)
+	}
+
+	process, ok := globals["process"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define process(page)", path)
+	}
+	globals.Freeze()
+	return &Script{path: path, process: process}, nil
+}
+
+func newThread(path string) *starlark.Thread {
+	return &starlark.Thread{
+		Name: path,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Printf("%s: %s", path, msg)
+		},
+	}
+}
+
+// Runs process on a record. Reports false when the script drops it.
+func (s *Script) Process(ctx context.Context, page storage.PageData) (storage.PageData, bool, error) {
+	thread := newThread(s.path)
+	thread.SetMaxExecutionSteps(maxSteps)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel("crawl stopped")
+		case <-done:
+		}
+	}()
+
+	result, err := starlark.Call(thread, s.process, starlark.Tuple{toDict(page)}, nil)
+	if err != nil {
+		return page, false, fmt.Errorf("script failed on %s: %w", page.URL, err)
+	}
+
+	switch result := result.(type) {
+	case starlark.NoneType:
+		return page, true, nil
+	case starlark.Bool:
+		if !result {
+			s.dropped.Add(1)
+		}
+		return page, bool(result), nil
+	case *starlark.Dict:
+		page, err := fromDict(page, result)
+		if err != nil {
+			return page, false, fmt.Errorf("script failed on %s: %w", page.URL, err)
+		}
+		return page, true, nil
+	default:
+		return page, false, fmt.Errorf("script failed on %s: process returned %s, want dict, None or bool", page.URL, result.Type())
+	}
+}
+
+// Number of records the script has dropped
+func (s *Script) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Wraps a storage so every record passes through the script first
+func (s *Script) Wrap(next storage.Storage) storage.Storage {
+	return &scriptStorage{script: s, next: next}
+}
+
+type scriptStorage struct {
+	script *Script
+	next   storage.Storage
+}
+
+func (s *scriptStorage) Save(ctx context.Context, data storage.PageData) error {
+	data, keep, err := s.script.Process(ctx, data)
+	if err != nil || !keep {
+		return err
+	}
+	return s.next.Save(ctx, data)
+}
+
+func (s *scriptStorage) Close() error {
+	return s.next.Close()
+}
+
+func toDict(page storage.PageData) *starlark.Dict {
+	dict := starlark.NewDict(12)
+	dict.SetKey(starlark.String("url"), starlark.String(page.URL))
+	dict.SetKey(starlark.String("title"), starlark.String(page.Title))
+	dict.SetKey(starlark.String("description"), starlark.String(page.Description))
+	dict.SetKey(starlark.String("author"), starlark.String(page.Author))
+	dict.SetKey(starlark.String("content"), starlark.String(page.Content))
+	dict.SetKey(starlark.String("links"), stringList(page.Links))
+	dict.SetKey(starlark.String("depth"), starlark.MakeInt(page.Depth))
+	dict.SetKey(starlark.String("status_code"), starlark.MakeInt(page.StatusCode))
+	dict.SetKey(starlark.String("content_type"), starlark.String(page.ContentType))
+	dict.SetKey(starlark.String("tags"), stringList(page.Tags))
+
+	fields := starlark.NewDict(len(page.Fields))
+	names := make([]string, 0, len(page.Fields))
+	for name := range page.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields.SetKey(starlark.String(name), starlark.String(page.Fields[name]))
+	}
+	dict.SetKey(starlark.String("fields"), fields)
+	return dict
+}
+
+func stringList(values []string) *starlark.List {
+	elems := make([]starlark.Value, len(values))
+	for i, value := range values {
+		elems[i] = starlark.String(value)
+	}
+	return starlark.NewList(elems)
+}
+
+// Copies the editable values of a returned dict back into the record. The
+// URL, depth and status code are the crawl's and can't be changed.
+func fromDict(page storage.PageData, dict *starlark.Dict) (storage.PageData, error) {
+	fields := make(map[string]string, len(page.Fields))
+	for name, value := range page.Fields {
+		fields[name] = value
+	}
+
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return page, fmt.Errorf("page key %s is not a string", item[0])
+		}
+		value := item[1]
+
+		var err error
+		switch key {
+		case "url", "depth", "status_code":
+		case "title":
+			page.Title, err = asString(key, value)
+		case "description":
+			page.Description, err = asString(key, value)
+		case "author":
+			page.Author, err = asString(key, value)
+		case "content":
+			page.Content, err = asString(key, value)
+		case "content_type":
+			page.ContentType, err = asString(key, value)
+		case "links":
+			page.Links, err = asStrings(key, value)
+		case "tags":
+			page.Tags, err = asStrings(key, value)
+		case "fields":
+			fieldDict, ok := value.(*starlark.Dict)
+			if !ok {
+				return page, fmt.Errorf("fields is %s, want dict", value.Type())
+			}
+			fields = make(map[string]string, fieldDict.Len())
+			for _, field := range fieldDict.Items() {
+				name, ok := starlark.AsString(field[0])
+				if !ok {
+					return page, fmt.Errorf("field name %s is not a string", field[0])
+				}
+				fields[name] = fieldValue(field[1])
+			}
+		default:
+			fields[key] = fieldValue(value)
+		}
+		if err != nil {
+			return page, err
+		}
+	}
+
+	page.Fields = fields
+	if len(fields) == 0 {
+		page.Fields = nil
+	}
+	return page, nil
+}
+
+func asString(key string, value starlark.Value) (string, error) {
+	s, ok := starlark.AsString(value)
+	if !ok {
+		return "", fmt.Errorf("%s is %s, want string", key, value.Type())
+	}
+	return s, nil
+}
+
+func asStrings(key string, value starlark.Value) ([]string, error) {
+	iterable, ok := value.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("%s is %s, want list", key, value.Type())
+	}
+
+	var values []string
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var elem starlark.Value
+	for iter.Next(&elem) {
+		s, ok := starlark.AsString(elem)
+		if !ok {
+			return nil, fmt.Errorf("%s holds %s, want strings", key, elem.Type())
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// Strings are kept as they are; other values are stored in Starlark syntax
+func fieldValue(value starlark.Value) string {
+	if s, ok := starlark.AsString(value); ok {
+		return s
+	}
+	return value.String()
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	_ "github.com/blevesearch/bleve/v2/search/highlight/highlighter/ansi"
+)
+
+// Pages are indexed in batches of this many documents
+const batchSize = 100
+
+// A page as stored in the index
+type Document struct {
+	URL         string
+	Title       string
+	Description string
+	Content     string
+	CrawledAt   time.Time
+}
+
+// Full-text index of crawled pages, searchable by title, description,
+// content, url and host
+type Index struct {
+	index bleve.Index
+	batch *bleve.Batch
+	mutex sync.Mutex
+}
+
+// Opens the index at path for adding pages, creating it if needed. Pages
+// already in an existing index are replaced when crawled again.
+func Create(path string) (*Index, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, newMapping())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	return &Index{index: index, batch: index.NewBatch()}, nil
+}
+
+// Opens an existing index for searching
+func Open(path string) (*Index, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	index, err := bleve.OpenUsing(path, map[string]interface{}{"read_only": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	return &Index{index: index}, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = standard.Name
+	text.Store = true
+	text.IncludeTermVectors = true
+
+	exact := bleve.NewTextFieldMapping()
+	exact.Analyzer = keyword.Name
+	exact.Store = true
+
+	page := bleve.NewDocumentMapping()
+	page.AddFieldMappingsAt("url", exact)
+	page.AddFieldMappingsAt("host", exact)
+	page.AddFieldMappingsAt("title", text)
+	page.AddFieldMappingsAt("description", text)
+	page.AddFieldMappingsAt("content", text)
+	page.AddFieldMappingsAt("crawled_at", bleve.NewDateTimeFieldMapping())
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = page
+	indexMapping.DefaultAnalyzer = standard.Name
+	return indexMapping
+}
+
+func (ix *Index) Add(doc Document) error {
+	host := ""
+	if parsedURL, err := url.Parse(doc.URL); err == nil {
+		host = strings.ToLower(parsedURL.Hostname())
+	}
+
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+
+	err := ix.batch.Index(doc.URL, map[string]interface{}{
+		"url":         doc.URL,
+		"host":        host,
+		"title":       doc.Title,
+		"description": doc.Description,
+		"content":     doc.Content,
+		"crawled_at":  doc.CrawledAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", doc.URL, err)
+	}
+
+	if ix.batch.Size() >= batchSize {
+		return ix.flush()
+	}
+	return nil
+}
+
+// Must be called with ix.mutex held
+func (ix *Index) flush() error {
+	if ix.batch == nil || ix.batch.Size() == 0 {
+		return nil
+	}
+	if err := ix.index.Batch(ix.batch); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	ix.batch.Reset()
+	return nil
+}
+
+func (ix *Index) Close() error {
+	ix.mutex.Lock()
+	defer ix.mutex.Unlock()
+
+	err := ix.flush()
+	return errors.Join(err, ix.index.Close())
+}
+
+type Hit struct {
+	URL   string
+	Title string
+	Score float64
+	// Matching content with the terms marked, when found
+	Fragment string
+}
+
+// Runs a query in bleve's query string syntax, e.g. `crawler +host:example.com
+// title:go`, returning the best limit hits and the total number of matches
+func (ix *Index) Search(query string, limit int) ([]Hit, uint64, error) {
+	request := bleve.NewSearchRequestOptions(bleve.NewQueryStringQuery(query), limit, 0, false)
+	request.Fields = []string{"url", "title"}
+	request.Highlight = bleve.NewHighlightWithStyle("ansi")
+	request.Highlight.AddField("content")
+
+	result, err := ix.index.Search(request)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search failed: %w", err)
+	}
+
+	hits := make([]Hit, 0, len(result.Hits))
+	for _, match := range result.Hits {
+		hit := Hit{URL: match.ID, Score: match.Score}
+		if title, ok := match.Fields["title"].(string); ok {
+			hit.Title = title
+		}
+		if fragments := match.Fragments["content"]; len(fragments) > 0 {
+			hit.Fragment = strings.Join(strings.Fields(fragments[0]), " ")
+		}
+		hits = append(hits, hit)
+	}
+	return hits, result.Total, nil
+}
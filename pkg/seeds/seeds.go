@@ -0,0 +1,92 @@
+package seeds
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A seed URL with optional overrides of the global crawl settings
+type Seed struct {
+	URL       string
+	Tag       string
+	MaxDepth  int // -1 inherits the global maximum depth
+	URLFilter string
+}
+
+// Reads a seeds file. Each non-empty line holds a URL followed by optional
+// key=value overrides, e.g.:
+//
+//	https://example.com/blog depth=3 filter=/blog/ tag=blog
+//
+// Lines starting with # are ignored. Seeds without a tag are tagged with
+// their own URL so that results stay attributable.
+func Load(filename string) ([]Seed, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open seeds file: %w", err)
+	}
+	defer file.Close()
+
+	var seeds []Seed
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		seed, err := ParseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("seeds file line %d: %w", lineNum, err)
+		}
+		seeds = append(seeds, seed)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read seeds file: %w", err)
+	}
+
+	return seeds, nil
+}
+
+// Parses one seeds-file line: a URL and optional key=value overrides
+func ParseLine(line string) (Seed, error) {
+	fields := strings.Fields(line)
+	seed := Seed{
+		URL:      fields[0],
+		MaxDepth: -1,
+	}
+
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Seed{}, fmt.Errorf("invalid override %q, expected key=value", field)
+		}
+
+		switch strings.ToLower(key) {
+		case "depth":
+			depth, err := strconv.Atoi(value)
+			if err != nil || depth < 0 {
+				return Seed{}, fmt.Errorf("invalid depth %q", value)
+			}
+			seed.MaxDepth = depth
+		case "filter":
+			seed.URLFilter = value
+		case "tag":
+			seed.Tag = value
+		default:
+			return Seed{}, fmt.Errorf("unknown override %q", key)
+		}
+	}
+
+	if seed.Tag == "" {
+		seed.Tag = seed.URL
+	}
+
+	return seed, nil
+}
@@ -0,0 +1,71 @@
+package seendb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var seenBucket = []byte("seen")
+
+// Persists the time each URL was last fetched so that repeated crawls can
+// skip pages fetched in earlier runs
+type DB struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open seen database: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(seenBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize seen database: %w", err)
+	}
+
+	return &DB{db: db}, nil
+}
+
+func (d *DB) LastFetched(key string) (time.Time, bool) {
+	var fetchedAt time.Time
+	found := false
+
+	d.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(seenBucket).Get([]byte(key))
+		if len(value) == 8 {
+			fetchedAt = time.Unix(0, int64(binary.BigEndian.Uint64(value)))
+			found = true
+		}
+		return nil
+	})
+
+	return fetchedAt, found
+}
+
+// Reports whether key was fetched at or after since
+func (d *DB) FetchedSince(key string, since time.Time) bool {
+	fetchedAt, ok := d.LastFetched(key)
+	return ok && !fetchedAt.Before(since)
+}
+
+// Records a fetch. Concurrent calls are coalesced into shared transactions.
+func (d *DB) MarkFetched(key string, fetchedAt time.Time) error {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(fetchedAt.UnixNano()))
+
+	return d.db.Batch(func(tx *bolt.Tx) error {
+		return tx.Bucket(seenBucket).Put([]byte(key), value)
+	})
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
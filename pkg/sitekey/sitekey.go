@@ -0,0 +1,65 @@
+package sitekey
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// How hosts are grouped into sites for politeness limits, stats and the
+// same-site check
+type Grouping string
+
+const (
+	// Every host (with its port) is its own site
+	Host Grouping = "host"
+	// Hosts sharing a registered domain (eTLD+1) form one site, so
+	// www.example.co.uk and cdn.example.co.uk are both example.co.uk
+	Domain Grouping = "domain"
+)
+
+func Parse(s string) (Grouping, error) {
+	switch Grouping(s) {
+	case "", Host:
+		return Host, nil
+	case Domain:
+		return Domain, nil
+	default:
+		return "", fmt.Errorf("unknown site grouping %q (want host or domain)", s)
+	}
+}
+
+// Returns the site of a host, which may include a port
+func (g Grouping) Key(host string) string {
+	host = strings.ToLower(host)
+	if g != Domain {
+		return host
+	}
+
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	// IP addresses and single-label hosts like localhost have no
+	// registered domain
+	if net.ParseIP(strings.Trim(hostname, "[]")) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(hostname)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// Returns the site of a URL's host, or "" when the URL doesn't parse
+func (g Grouping) KeyOfURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return g.Key(parsedURL.Host)
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// Replaces URLs and domains with keyed hashes and drops the page text
+// (title, description, author, content) before passing records on. Links
+// are hashed the same way as page URLs, so the link graph survives intact,
+// and depth, timing, tags, content type and size are kept as they are.
+type AnonymizeStorage struct {
+	next Storage
+	key  []byte
+}
+
+// The same key gives the same identifiers, so runs can be compared; keep it
+// secret, since anyone holding it can confirm a guessed URL.
+func NewAnonymizeStorage(next Storage, key []byte) *AnonymizeStorage {
+	return &AnonymizeStorage{next: next, key: key}
+}
+
+// Returns the identifier that replaces a URL or domain
+func (a *AnonymizeStorage) ID(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (a *AnonymizeStorage) Save(ctx context.Context, data PageData) error {
+	id := a.ID(data.URL)
+	if data.Error != "" {
+		data.Error = strings.ReplaceAll(data.Error, data.URL, id)
+		// DNS and connection errors name the host on its own
+		if parsedURL, err := url.Parse(data.URL); err == nil && parsedURL.Hostname() != "" {
+			data.Error = strings.ReplaceAll(data.Error, parsedURL.Hostname(), id)
+		}
+	}
+
+	data.URL = id
+	if data.ParentURL != "" {
+		data.ParentURL = a.ID(data.ParentURL)
+	}
+	data.Links = a.ids(data.Links)
+	if data.Anchors != nil {
+		anchors := make([]Anchor, len(data.Anchors))
+		for i, anchor := range data.Anchors {
+			anchors[i] = Anchor{URL: a.ID(anchor.URL), Rel: anchor.Rel}
+		}
+		data.Anchors = anchors
+	}
+	data.ExternalDomains = a.ids(data.ExternalDomains)
+	data.Aliases = a.ids(data.Aliases)
+	if data.CanonicalURL != "" {
+		data.CanonicalURL = a.ID(data.CanonicalURL)
+	}
+	if data.AMPURL != "" {
+		data.AMPURL = a.ID(data.AMPURL)
+	}
+	if data.Redirects != nil {
+		redirects := make([]Redirect, len(data.Redirects))
+		for i, redirect := range data.Redirects {
+			redirects[i] = Redirect{URL: a.ID(redirect.URL), StatusCode: redirect.StatusCode}
+		}
+		data.Redirects = redirects
+	}
+	if data.FinalURL != "" {
+		data.FinalURL = a.ID(data.FinalURL)
+	}
+	if data.Alternates != nil {
+		alternates := make([]Alternate, len(data.Alternates))
+		for i, alternate := range data.Alternates {
+			alternates[i] = Alternate{Lang: alternate.Lang, URL: a.ID(alternate.URL)}
+		}
+		data.Alternates = alternates
+	}
+	if data.SocialProfiles != nil {
+		profiles := make([]SocialProfile, len(data.SocialProfiles))
+		for i, profile := range data.SocialProfiles {
+			id := a.ID(profile.URL)
+			profiles[i] = SocialProfile{Network: profile.Network, Handle: id, URL: id}
+		}
+		data.SocialProfiles = profiles
+	}
+	data.Title = ""
+	data.Description = ""
+	data.Author = ""
+	data.Content = ""
+	data.Headings = nil
+	data.Fields = nil
+	data.RawHTML = ""
+	data.RawHTMLEncoding = ""
+	data.RawHTMLFile = ""
+	if data.Forms != nil {
+		forms := make([]Form, len(data.Forms))
+		for i, form := range data.Forms {
+			form.Action = a.ID(form.Action)
+			forms[i] = form
+		}
+		data.Forms = forms
+	}
+	return a.next.Save(ctx, data)
+}
+
+func (a *AnonymizeStorage) ids(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	ids := make([]string, len(values))
+	for i, value := range values {
+		ids[i] = a.ID(value)
+	}
+	return ids
+}
+
+func (a *AnonymizeStorage) Close() error {
+	return a.next.Close()
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// What AsyncStorage does with a record when its queue is full
+const (
+	OverflowBlock = "block" // wait for room, slowing the crawl down
+	OverflowDrop  = "drop"  // discard the record and count it
+)
+
+// Implemented by storages that write several records more cheaply at once
+type BatchSaver interface {
+	SaveBatch(ctx context.Context, records []PageData) error
+}
+
+// Saves records on a background goroutine so slow sinks don't hold up the
+// workers. Records wait in a bounded queue and are written in batches of up
+// to batchSize if next is a BatchSaver, one by one otherwise; when the queue is full, Save blocks or drops the record
+// according to the overflow policy. Write errors surface from Close.
+type AsyncStorage struct {
+	next      Storage
+	queue     chan PageData
+	batchSize int
+	overflow  string
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mutex    sync.Mutex
+	dropped  int
+	failed   int
+	firstErr error
+}
+
+func NewAsyncStorage(next Storage, queueSize, batchSize int, overflow string) (*AsyncStorage, error) {
+	if overflow != OverflowBlock && overflow != OverflowDrop {
+		return nil, fmt.Errorf("unknown overflow policy %q (want block or drop)", overflow)
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	a := &AsyncStorage{
+		next:      next,
+		queue:     make(chan PageData, queueSize),
+		batchSize: batchSize,
+		overflow:  overflow,
+		done:      make(chan struct{}),
+	}
+	go a.run()
+	return a, nil
+}
+
+// With the block policy, Save waits for room until ctx is done
+func (a *AsyncStorage) Save(ctx context.Context, data PageData) error {
+	if a.overflow == OverflowBlock {
+		select {
+		case a.queue <- data:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case a.queue <- data:
+	default:
+		a.mutex.Lock()
+		a.dropped++
+		a.mutex.Unlock()
+	}
+	return nil
+}
+
+func (a *AsyncStorage) run() {
+	defer close(a.done)
+
+	batch := make([]PageData, 0, a.batchSize)
+	for record := range a.queue {
+		batch = append(batch[:0], record)
+	fill:
+		for len(batch) < a.batchSize {
+			select {
+			case record, ok := <-a.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, record)
+			default:
+				break fill
+			}
+		}
+		a.write(batch)
+	}
+}
+
+// Queued records are written even after the crawl that saved them is
+// cancelled, so they are not passed its context
+func (a *AsyncStorage) write(batch []PageData) {
+	ctx := context.Background()
+	if saver, ok := a.next.(BatchSaver); ok {
+		if err := saver.SaveBatch(ctx, batch); err != nil {
+			a.recordError(len(batch), err)
+		}
+		return
+	}
+
+	for _, record := range batch {
+		if err := a.next.Save(ctx, record); err != nil {
+			a.recordError(1, err)
+		}
+	}
+}
+
+func (a *AsyncStorage) recordError(records int, err error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.failed += records
+	if a.firstErr == nil {
+		a.firstErr = err
+	}
+}
+
+// Returns how many records were dropped on overflow and how many failed to
+// save so far
+func (a *AsyncStorage) Stats() (dropped, failed int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.dropped, a.failed
+}
+
+// Writes the queued records and closes the underlying storage
+func (a *AsyncStorage) Close() error {
+	a.closeOnce.Do(func() { close(a.queue) })
+	<-a.done
+
+	var errs []error
+	a.mutex.Lock()
+	if a.firstErr != nil {
+		errs = append(errs, fmt.Errorf("%d records failed to save, first error: %w", a.failed, a.firstErr))
+	}
+	a.mutex.Unlock()
+
+	errs = append(errs, a.next.Close())
+	return errors.Join(errs...)
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"github.com/user/gocrawler/pkg/search"
+)
+
+// Indexes pages into a local full-text search index; query it with the
+// search subcommand
+type BleveStorage struct {
+	index *search.Index
+}
+
+func NewBleveStorage(path string) (*BleveStorage, error) {
+	index, err := search.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BleveStorage{index: index}, nil
+}
+
+func (b *BleveStorage) Save(ctx context.Context, data PageData) error {
+	return b.index.Add(search.Document{
+		URL:         data.URL,
+		Title:       data.Title,
+		Description: data.Description,
+		Content:     data.Content,
+		CrawledAt:   data.CrawledAt,
+	})
+}
+
+func (b *BleveStorage) Close() error {
+	return b.index.Close()
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Compression extensions recognised on output file names
+var compressionExtensions = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// Appends the extension of the compression ("gzip" or "zstd") unless the
+// filename already has it; "" and "none" leave it unchanged
+func CompressedFilename(filename, compression string) (string, error) {
+	if compression == "" || compression == "none" {
+		return filename, nil
+	}
+
+	ext, ok := compressionExtensions[compression]
+	if !ok {
+		return "", fmt.Errorf("unsupported compression: %s (want none, gzip or zstd)", compression)
+	}
+	if strings.HasSuffix(strings.ToLower(filename), ext) {
+		return filename, nil
+	}
+	return filename + ext, nil
+}
+
+// Splits off the extension, keeping a compression extension together with
+// the one before it: results.json.gz -> results, .json.gz
+func SplitExt(filename string) (string, string) {
+	base := filename
+	compressionExt := ""
+	for _, ext := range compressionExtensions {
+		if strings.HasSuffix(strings.ToLower(base), ext) {
+			compressionExt = base[len(base)-len(ext):]
+			base = base[:len(base)-len(ext)]
+			break
+		}
+	}
+
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext), ext + compressionExt
+}
@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How the Links column is encoded
+const (
+	LinksJSON  = "json"  // a JSON array
+	LinksComma = "comma" // comma-joined, ambiguous for URLs containing commas
+	LinksEdges = "edges" // left out; written to a separate edge file instead
+)
+
+// Which CSV columns are written and how links are encoded
+type CSVOptions struct {
+	// Column names in output order; nil writes CSVColumns
+	Columns []string
+	// Columns to leave out, e.g. Content
+	Exclude []string
+	// LinksJSON (default), LinksComma or LinksEdges
+	Links string
+}
+
+type csvColumn struct {
+	name  string
+	value func(data PageData, links string) string
+}
+
+var csvColumns = []csvColumn{
+	{"URL", func(d PageData, _ string) string { return d.URL }},
+	{"Title", func(d PageData, _ string) string { return d.Title }},
+	{"Description", func(d PageData, _ string) string { return d.Description }},
+	{"Content", func(d PageData, _ string) string { return d.Content }},
+	{"Links", func(_ PageData, links string) string { return links }},
+	{"CrawledAt", func(d PageData, _ string) string { return d.CrawledAt.Format(time.RFC3339) }},
+	{"Depth", func(d PageData, _ string) string { return strconv.Itoa(d.Depth) }},
+	{"SeedTag", func(d PageData, _ string) string { return d.SeedTag }},
+	{"AuthRequired", func(d PageData, _ string) string { return strconv.FormatBool(d.AuthRequired) }},
+	{"RunID", func(d PageData, _ string) string { return d.RunID }},
+	{"ContentType", func(d PageData, _ string) string { return d.ContentType }},
+	{"Author", func(d PageData, _ string) string { return d.Author }},
+	{"Size", func(d PageData, _ string) string { return strconv.FormatInt(d.Size, 10) }},
+	{"Error", func(d PageData, _ string) string { return d.Error }},
+	{"ContentKind", func(d PageData, _ string) string { return d.ContentKind }},
+	{"Tags", func(d PageData, _ string) string { return strings.Join(d.Tags, ",") }},
+	{"ExternalDomains", func(d PageData, _ string) string { return strings.Join(d.ExternalDomains, ",") }},
+	{"Sequence", func(d PageData, _ string) string { return strconv.Itoa(d.Sequence) }},
+	{"ParentURL", func(d PageData, _ string) string { return d.ParentURL }},
+	{"Forms", func(d PageData, _ string) string { return formsColumn(d.Forms) }},
+	{"Headings", func(d PageData, _ string) string { return headingsColumn(d.Headings) }},
+	{"HeadingCounts", func(d PageData, _ string) string { return headingCountsColumn(d.HeadingCounts) }},
+	{"RawHTML", func(d PageData, _ string) string { return d.RawHTML }},
+	{"RawHTMLEncoding", func(d PageData, _ string) string { return d.RawHTMLEncoding }},
+	{"RawHTMLFile", func(d PageData, _ string) string { return d.RawHTMLFile }},
+	{"WordCount", qualityColumn(func(q *Quality) string { return strconv.Itoa(q.WordCount) })},
+	{"TextRatio", qualityColumn(func(q *Quality) string { return strconv.FormatFloat(q.TextRatio, 'f', 3, 64) })},
+	{"LinkCount", qualityColumn(func(q *Quality) string { return strconv.Itoa(q.LinkCount) })},
+	{"BoilerplatePercent", qualityColumn(func(q *Quality) string { return strconv.FormatFloat(q.BoilerplatePercent, 'f', 1, 64) })},
+	{"KeywordScore", func(d PageData, _ string) string {
+		if d.KeywordScore == nil {
+			return ""
+		}
+		return strconv.Itoa(*d.KeywordScore)
+	}},
+	{"StatusCode", func(d PageData, _ string) string { return strconv.Itoa(d.StatusCode) }},
+	{"ErrorClass", func(d PageData, _ string) string { return d.ErrorClass }},
+	{"Attempts", func(d PageData, _ string) string { return strconv.Itoa(d.Attempts) }},
+	{"AMP", func(d PageData, _ string) string { return strconv.FormatBool(d.AMP) }},
+	{"CanonicalURL", func(d PageData, _ string) string { return d.CanonicalURL }},
+	{"AMPURL", func(d PageData, _ string) string { return d.AMPURL }},
+	{"Fields", func(d PageData, _ string) string { return fieldsColumn(d.Fields) }},
+	{"Redirects", func(d PageData, _ string) string { return redirectsColumn(d.Redirects) }},
+	{"FinalURL", func(d PageData, _ string) string { return d.FinalURL }},
+	{"Technologies", func(d PageData, _ string) string { return strings.Join(d.Technologies, ",") }},
+	{"SocialProfiles", func(d PageData, _ string) string {
+		urls := make([]string, len(d.SocialProfiles))
+		for i, profile := range d.SocialProfiles {
+			urls[i] = profile.URL
+		}
+		return strings.Join(urls, " ")
+	}},
+	{"Alternates", func(d PageData, _ string) string { return alternatesColumn(d.Alternates) }},
+	{"Aliases", func(d PageData, _ string) string { return strings.Join(d.Aliases, " ") }},
+}
+
+// Names of all CSV columns, in the default order
+func CSVColumns() []string {
+	names := make([]string, len(csvColumns))
+	for i, column := range csvColumns {
+		names[i] = column.name
+	}
+	return names
+}
+
+type CSVStorage struct {
+	out     io.WriteCloser
+	writer  *csv.Writer
+	mutex   sync.Mutex
+	columns []csvColumn
+	links   string
+}
+
+func NewCSVStorage(filename string) (*CSVStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	return newCSVStorage(out, CSVOptions{})
+}
+
+func newCSVStorage(out io.WriteCloser, opts CSVOptions) (*CSVStorage, error) {
+	columns, err := selectCSVColumns(opts)
+	if err != nil {
+		out.Close()
+		return nil, err
+	}
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.name
+	}
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write(headers); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+	writer.Flush()
+
+	links := opts.Links
+	if links == "" {
+		links = LinksJSON
+	}
+
+	return &CSVStorage{
+		out:     out,
+		writer:  writer,
+		columns: columns,
+		links:   links,
+	}, nil
+}
+
+func selectCSVColumns(opts CSVOptions) ([]csvColumn, error) {
+	switch opts.Links {
+	case "", LinksJSON, LinksComma, LinksEdges:
+	default:
+		return nil, fmt.Errorf("unknown CSV links encoding %q (want json, comma or edges)", opts.Links)
+	}
+
+	byName := make(map[string]csvColumn, len(csvColumns))
+	for _, column := range csvColumns {
+		byName[strings.ToLower(column.name)] = column
+	}
+
+	names := opts.Columns
+	if names == nil {
+		names = CSVColumns()
+	}
+
+	excluded := make(map[string]bool)
+	for _, name := range opts.Exclude {
+		if _, ok := byName[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		excluded[strings.ToLower(name)] = true
+	}
+	if opts.Links == LinksEdges {
+		excluded["links"] = true
+	}
+
+	var columns []csvColumn
+	for _, name := range names {
+		column, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if !excluded[strings.ToLower(column.name)] {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no CSV columns selected")
+	}
+	return columns, nil
+}
+
+func (c *CSVStorage) Save(ctx context.Context, data PageData) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.write(data); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// Writes the records with a single flush
+func (c *CSVStorage) SaveBatch(ctx context.Context, records []PageData) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, data := range records {
+		if err := c.write(data); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// Must be called with c.mutex held
+func (c *CSVStorage) write(data PageData) error {
+	links := ""
+	switch c.links {
+	case LinksJSON:
+		if len(data.Links) > 0 {
+			encoded, err := json.Marshal(data.Links)
+			if err != nil {
+				return fmt.Errorf("failed to encode links: %w", err)
+			}
+			links = string(encoded)
+		}
+	case LinksComma:
+		links = strings.Join(data.Links, ",")
+	}
+
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = column.value(data, links)
+	}
+
+	if err := c.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+	return nil
+}
+
+func qualityColumn(value func(q *Quality) string) func(PageData, string) string {
+	return func(d PageData, _ string) string {
+		if d.Quality == nil {
+			return ""
+		}
+		return value(d.Quality)
+	}
+}
+
+// Forms as "METHOD action name:type ...", separated by semicolons
+func formsColumn(forms []Form) string {
+	parts := make([]string, len(forms))
+	for i, form := range forms {
+		fields := []string{form.Method, form.Action}
+		for _, field := range form.Fields {
+			fields = append(fields, field.Name+":"+field.Type)
+		}
+		parts[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(parts, ";")
+}
+
+// Extracted fields as a JSON object
+func fieldsColumn(fields map[string]string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+// Redirects as "status url", separated by semicolons
+func redirectsColumn(redirects []Redirect) string {
+	parts := make([]string, len(redirects))
+	for i, redirect := range redirects {
+		parts[i] = strconv.Itoa(redirect.StatusCode) + " " + redirect.URL
+	}
+	return strings.Join(parts, ";")
+}
+
+// Variants as "hreflang url", separated by semicolons
+func alternatesColumn(alternates []Alternate) string {
+	parts := make([]string, len(alternates))
+	for i, alternate := range alternates {
+		parts[i] = alternate.Lang + " " + alternate.URL
+	}
+	return strings.Join(parts, ";")
+}
+
+// The outline as one "h2 Text" line per heading
+func headingsColumn(headings []Heading) string {
+	lines := make([]string, len(headings))
+	for i, heading := range headings {
+		lines[i] = fmt.Sprintf("h%d %s", heading.Level, heading.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Counts as "h1=1,h2=4", in level order
+func headingCountsColumn(counts map[string]int) string {
+	var parts []string
+	for level := 1; level <= 6; level++ {
+		key := fmt.Sprintf("h%d", level)
+		if count, ok := counts[key]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%d", key, count))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *CSVStorage) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writer.Flush()
+	return c.out.Close()
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Writes every page's links to a separate CSV edge file, one row per link
+// (from_url, to_url, anchor_text, rel, depth of the linking page), and passes
+// the page on to the wrapped storage
+type EdgeStorage struct {
+	next   Storage
+	out    io.WriteCloser
+	writer *csv.Writer
+	mutex  sync.Mutex
+}
+
+func NewEdgeStorage(next Storage, filename string) (*EdgeStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create edge file: %w", err)
+	}
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"from_url", "to_url", "anchor_text", "rel", "depth"}); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("failed to write edge header: %w", err)
+	}
+
+	return &EdgeStorage{next: next, out: out, writer: writer}, nil
+}
+
+// The edge file written next to filename: results.csv.gz -> results-links.csv.gz
+func EdgeFilename(filename string) string {
+	base, ext := SplitExt(filename)
+	compressionExt := ""
+	for _, e := range compressionExtensions {
+		if strings.HasSuffix(strings.ToLower(ext), e) {
+			compressionExt = ext[len(ext)-len(e):]
+		}
+	}
+	return base + "-links.csv" + compressionExt
+}
+
+func (e *EdgeStorage) Save(ctx context.Context, data PageData) error {
+	// Pages from handlers that don't record anchors only have their links
+	anchors := data.Anchors
+	if anchors == nil {
+		anchors = make([]Anchor, len(data.Links))
+		for i, link := range data.Links {
+			anchors[i] = Anchor{URL: link}
+		}
+	}
+
+	depth := strconv.Itoa(data.Depth)
+	e.mutex.Lock()
+	for _, anchor := range anchors {
+		if err := e.writer.Write([]string{data.URL, anchor.URL, anchor.Text, anchor.Rel, depth}); err != nil {
+			e.mutex.Unlock()
+			return fmt.Errorf("failed to write edge: %w", err)
+		}
+	}
+	e.writer.Flush()
+	err := e.writer.Error()
+	e.mutex.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to write edges: %w", err)
+	}
+	return e.next.Save(ctx, data)
+}
+
+func (e *EdgeStorage) Close() error {
+	e.mutex.Lock()
+	e.writer.Flush()
+	err := e.writer.Error()
+	if closeErr := e.out.Close(); err == nil {
+		err = closeErr
+	}
+	e.mutex.Unlock()
+
+	return errors.Join(err, e.next.Close())
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// What ExecStorage does with a record when its command fails
+const (
+	ExecWarn = "warn" // log the failure and save the record anyway
+	ExecDrop = "drop" // log the failure and leave the record out
+	ExecStop = "stop" // fail the record and stop the crawl
+)
+
+// What ExecStorage does with the command's output
+const (
+	ExecNotify = "notify" // pass it through to stdout
+	ExecFilter = "filter" // save the JSON record it prints instead; no output drops the record
+)
+
+// Pipes every record as JSON to a shell command's stdin before saving it,
+// running up to concurrency commands at once. A command fails when it exits
+// non-zero, runs past the timeout or, as a filter, prints something other
+// than a record.
+type ExecStorage struct {
+	next      Storage
+	command   string
+	mode      string
+	onFailure string
+	timeout   time.Duration
+	slots     chan struct{}
+	stopped   chan struct{}
+	stopOnce  sync.Once
+
+	mutex   sync.Mutex
+	failed  int
+	dropped int
+}
+
+func NewExecStorage(next Storage, command, mode, onFailure string, concurrency int, timeout time.Duration) (*ExecStorage, error) {
+	if mode != ExecNotify && mode != ExecFilter {
+		return nil, fmt.Errorf("unknown exec output mode %q (want notify or filter)", mode)
+	}
+	if onFailure != ExecWarn && onFailure != ExecDrop && onFailure != ExecStop {
+		return nil, fmt.Errorf("unknown exec failure policy %q (want warn, drop or stop)", onFailure)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &ExecStorage{
+		next:      next,
+		command:   command,
+		mode:      mode,
+		onFailure: onFailure,
+		timeout:   timeout,
+		slots:     make(chan struct{}, concurrency),
+		stopped:   make(chan struct{}),
+	}, nil
+}
+
+func (e *ExecStorage) Save(ctx context.Context, data PageData) error {
+	select {
+	case e.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	output, err := e.run(ctx, data)
+	<-e.slots
+
+	if err == nil && e.mode == ExecFilter {
+		if len(bytes.TrimSpace(output)) == 0 {
+			e.count(&e.dropped)
+			return nil
+		}
+		var filtered PageData
+		if err = json.Unmarshal(output, &filtered); err != nil {
+			err = fmt.Errorf("failed to parse command output: %w", err)
+		} else {
+			data = filtered
+		}
+	}
+
+	if err != nil {
+		e.count(&e.failed)
+		err = fmt.Errorf("exec hook failed on %s: %w", data.URL, err)
+		switch e.onFailure {
+		case ExecStop:
+			e.stopOnce.Do(func() {
+				close(e.stopped)
+			})
+			return err
+		case ExecDrop:
+			log.Print(err)
+			return nil
+		default:
+			log.Print(err)
+		}
+	}
+	return e.next.Save(ctx, data)
+}
+
+// Runs the command on one record, returning its stdout in filter mode
+func (e *ExecStorage) run(ctx context.Context, data PageData) ([]byte, error) {
+	input, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", e.command)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stderr = os.Stderr
+	// Don't wait on children of the shell that outlive it holding stdout
+	cmd.WaitDelay = time.Second
+	var stdout bytes.Buffer
+	if e.mode == ExecFilter {
+		cmd.Stdout = &stdout
+	} else {
+		cmd.Stdout = os.Stdout
+	}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %v", e.timeout)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func (e *ExecStorage) count(field *int) {
+	e.mutex.Lock()
+	*field++
+	e.mutex.Unlock()
+}
+
+// Closed when a command fails under the stop policy
+func (e *ExecStorage) Stopped() <-chan struct{} {
+	return e.stopped
+}
+
+// Returns how many commands failed and how many records a filter dropped
+func (e *ExecStorage) Stats() (failed, dropped int) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.failed, e.dropped
+}
+
+func (e *ExecStorage) Close() error {
+	return e.next.Close()
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type edge struct {
+	source string
+	target string
+}
+
+// Exports the link graph as a CSV edge list (source, target, weight).
+// Repeated links between the same pair of pages are collapsed into a single
+// edge whose weight is the number of times the link was seen.
+type GraphStorage struct {
+	out    io.WriteCloser
+	mutex  sync.Mutex
+	weight map[edge]int
+}
+
+func NewGraphStorage(filename string) (*GraphStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create graph file: %w", err)
+	}
+
+	return &GraphStorage{
+		out:    out,
+		weight: make(map[edge]int),
+	}, nil
+}
+
+func (g *GraphStorage) Save(ctx context.Context, data PageData) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, link := range data.Links {
+		g.weight[edge{source: data.URL, target: link}]++
+	}
+	return nil
+}
+
+func (g *GraphStorage) Close() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	edges := make([]edge, 0, len(g.weight))
+	for e := range g.weight {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].source != edges[j].source {
+			return edges[i].source < edges[j].source
+		}
+		return edges[i].target < edges[j].target
+	})
+
+	writer := csv.NewWriter(g.out)
+	if err := writer.Write([]string{"source", "target", "weight"}); err != nil {
+		g.out.Close()
+		return fmt.Errorf("failed to write graph header: %w", err)
+	}
+
+	for _, e := range edges {
+		if err := writer.Write([]string{e.source, e.target, strconv.Itoa(g.weight[e])}); err != nil {
+			g.out.Close()
+			return fmt.Errorf("failed to write graph edge: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		g.out.Close()
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+
+	return g.out.Close()
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Describes a single crawl run so that outputs can be merged and traced back
+// to the configuration that produced them
+type RunMetadata struct {
+	RunID        string            `json:"run_id"`
+	Version      string            `json:"crawler_version"`
+	StartTime    time.Time         `json:"start_time"`
+	EndTime      time.Time         `json:"end_time"`
+	PagesCrawled int               `json:"pages_crawled"`
+	Config       map[string]string `json:"config"`
+}
+
+func MetadataFilename(outputFile string) string {
+	return outputFile + ".meta.json"
+}
+
+// Reads the metadata written next to a crawl output
+func ReadRunMetadata(outputFile string) (RunMetadata, error) {
+	var meta RunMetadata
+	data, err := os.ReadFile(MetadataFilename(outputFile))
+	if err != nil {
+		return meta, fmt.Errorf("failed to read metadata file: %w", err)
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("failed to decode metadata file: %w", err)
+	}
+	return meta, nil
+}
+
+// Writes the run metadata as a sidecar file next to the crawl output, leaving
+// the output format itself unchanged for existing consumers
+func WriteRunMetadata(outputFile string, meta RunMetadata) error {
+	file, err := os.Create(MetadataFilename(outputFile))
+	if err != nil {
+		return fmt.Errorf("failed to create metadata file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(meta); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode run metadata: %w", err)
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// A storage with a name to report its errors under
+type Sink struct {
+	Name    string
+	Storage Storage
+}
+
+// Writes every record to several sinks. A failing sink doesn't keep the
+// record from the others; its errors are returned labelled with its name and
+// counted per sink.
+type MultiStorage struct {
+	sinks  []Sink
+	mutex  sync.Mutex
+	errors map[string]int
+}
+
+func NewMultiStorage(sinks ...Sink) *MultiStorage {
+	return &MultiStorage{
+		sinks:  sinks,
+		errors: make(map[string]int),
+	}
+}
+
+func (m *MultiStorage) Save(ctx context.Context, data PageData) error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.Storage.Save(ctx, data); err != nil {
+			m.mutex.Lock()
+			m.errors[sink.Name]++
+			m.mutex.Unlock()
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns how many records each sink failed to save
+func (m *MultiStorage) Errors() map[string]int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	counts := make(map[string]int, len(m.errors))
+	for name, count := range m.errors {
+		counts[name] = count
+	}
+	return counts
+}
+
+func (m *MultiStorage) Close() error {
+	var errs []error
+	for _, sink := range m.sinks {
+		if err := sink.Storage.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
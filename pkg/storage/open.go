@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+)
+
+// Formats accepted by Open
+var Formats = []string{"json", "ndjson", "csv", "sitemap", "graph", "hosts", "bleve", "xml", "html"}
+
+// Output name that writes records to Options.Stdout instead of a file
+const Stdout = "-"
+
+type Options struct {
+	SitemapBaseURL string
+
+	// Where records go when the output name is Stdout
+	Stdout io.Writer
+
+	// Split json, ndjson and csv output into numbered shards of at most
+	// this many records or bytes (0 = no limit)
+	ShardRecords int
+	ShardBytes   int64
+
+	// Compress the output: "gzip" or "zstd" ("" or "none" = uncompressed).
+	// The matching extension is appended to the filename if missing.
+	Compress string
+
+	// Column selection and links encoding of csv output
+	CSV CSVOptions
+}
+
+// Creates the file storage for an output format, or the storage of a
+// registered format
+func Open(format, filename string, opts Options) (Storage, error) {
+	if opener, ok := registered(format); ok {
+		return opener(filename, opts)
+	}
+	if filename == Stdout {
+		return openStdout(format, opts)
+	}
+
+	filename, err := CompressedFilename(filename, opts.Compress)
+	if err != nil {
+		return nil, err
+	}
+	if format == "sitemap" && opts.Compress == "zstd" {
+		return nil, fmt.Errorf("sitemaps can only be gzip-compressed")
+	}
+	if format == "bleve" && opts.Compress != "" && opts.Compress != "none" {
+		return nil, fmt.Errorf("search indexes can't be compressed")
+	}
+
+	if format == "csv" {
+		// Sharded output opens its first file lazily, so check up front
+		if _, err := selectCSVColumns(opts.CSV); err != nil {
+			return nil, err
+		}
+	}
+
+	if newRecordStorage, ok := recordFormats[format]; ok {
+		store, err := openRecords(format, filename, opts, newRecordStorage)
+		if err != nil {
+			return nil, err
+		}
+		if format == "csv" && opts.CSV.Links == LinksEdges {
+			edges, err := NewEdgeStorage(store, EdgeFilename(filename))
+			if err != nil {
+				store.Close()
+				return nil, err
+			}
+			return edges, nil
+		}
+		return store, nil
+	}
+
+	switch format {
+	case "sitemap":
+		return NewSitemapStorage(filename, opts.SitemapBaseURL)
+	case "graph":
+		return NewGraphStorage(filename)
+	case "hosts":
+		return NewHostStorage(filename)
+	case "bleve":
+		return NewBleveStorage(filename)
+	case "html":
+		return NewReportStorage(filename)
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// Streams records to opts.Stdout; only unsharded, uncompressed record
+// formats can be
+func openStdout(format string, opts Options) (Storage, error) {
+	newRecordStorage, ok := recordFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%s output can't be written to stdout", format)
+	}
+	if opts.ShardRecords > 0 || opts.ShardBytes > 0 {
+		return nil, fmt.Errorf("output written to stdout can't be sharded")
+	}
+	if opts.Compress != "" && opts.Compress != "none" {
+		return nil, fmt.Errorf("output written to stdout can't be compressed")
+	}
+	if format == "csv" && opts.CSV.Links == LinksEdges {
+		return nil, fmt.Errorf("csv edge files are named after the output file, which stdout doesn't have")
+	}
+	return newRecordStorage(nopWriteCloser{opts.Stdout}, opts)
+}
+
+// Leaves stdout open when the storage is closed
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func openRecords(format, filename string, opts Options, newRecordStorage func(io.WriteCloser, Options) (Storage, error)) (Storage, error) {
+	newStorage := func(out io.WriteCloser) (Storage, error) {
+		return newRecordStorage(out, opts)
+	}
+	if opts.ShardRecords > 0 || opts.ShardBytes > 0 {
+		return NewShardedStorage(filename, opts.ShardRecords, opts.ShardBytes, newStorage), nil
+	}
+
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s file: %w", format, err)
+	}
+	return newStorage(out)
+}
+
+// Formats written record by record, which can be sharded
+var recordFormats = map[string]func(out io.WriteCloser, opts Options) (Storage, error){
+	"json": func(out io.WriteCloser, _ Options) (Storage, error) {
+		return newJSONStorage(out), nil
+	},
+	"ndjson": func(out io.WriteCloser, _ Options) (Storage, error) {
+		return newNDJSONStorage(out), nil
+	},
+	"csv": func(out io.WriteCloser, opts Options) (Storage, error) {
+		return newCSVStorage(out, opts.CSV)
+	},
+	"xml": func(out io.WriteCloser, _ Options) (Storage, error) {
+		return newXMLStorage(out), nil
+	},
+}
+
+// Reports whether the format stores whole records (json, ndjson, csv, xml)
+func IsRecordFormat(format string) bool {
+	_, ok := recordFormats[format]
+	return ok
+}
+
+func IsFormat(format string) bool {
+	if _, ok := registered(format); ok {
+		return true
+	}
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// How often buffered output is flushed and synced to disk
+const syncInterval = 5 * time.Second
+
+// Output file that counts the bytes written to it and compresses them when
+// the name ends in .gz (gzip) or .zst (zstd).
+//
+// Data goes to <name>.tmp, which is flushed and fsynced every syncInterval
+// so a crashed crawl leaves its records so far in the temp file. Close
+// finalizes the compressed stream and atomically renames the temp file to
+// the final name; after a failed write the temp file is kept instead.
+type outputFile struct {
+	filename   string
+	file       *os.File
+	compressor compressor
+	written    int64
+	lastSync   time.Time
+	err        error
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func createOutput(filename string) (*outputFile, error) {
+	file, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	out := &outputFile{
+		filename: filename,
+		file:     file,
+		lastSync: time.Now(),
+	}
+	switch lower := strings.ToLower(filename); {
+	case strings.HasSuffix(lower, ".gz"):
+		out.compressor = gzip.NewWriter(file)
+	case strings.HasSuffix(lower, ".zst"):
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+		out.compressor = encoder
+	}
+	return out, nil
+}
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	var n int
+	var err error
+	if o.compressor != nil {
+		n, err = o.compressor.Write(p)
+	} else {
+		n, err = o.file.Write(p)
+	}
+	o.written += int64(n)
+
+	if err == nil && time.Since(o.lastSync) >= syncInterval {
+		err = o.sync()
+	}
+	if err != nil && o.err == nil {
+		o.err = err
+	}
+	return n, err
+}
+
+func (o *outputFile) sync() error {
+	o.lastSync = time.Now()
+	if o.compressor != nil {
+		if err := o.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+	return o.file.Sync()
+}
+
+func (o *outputFile) Close() error {
+	var compressErr error
+	if o.compressor != nil {
+		compressErr = o.compressor.Close()
+	}
+	err := errors.Join(compressErr, o.file.Sync(), o.file.Close())
+
+	if o.err != nil || err != nil {
+		return errors.Join(err, fmt.Errorf("incomplete output left in %s", o.file.Name()))
+	}
+	if err := os.Rename(o.file.Name(), o.filename); err != nil {
+		return fmt.Errorf("failed to move output into place: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankTolerance  = 1e-9
+)
+
+// Passes records through to the wrapped storage, keeping only the link
+// graph between them, and on Close computes PageRank and in-degree over the
+// links between the crawled pages and writes them, best first, to a CSV file
+// (url, pagerank, inlinks). Links marked nofollow and links to pages outside
+// the crawl don't count.
+type PageRankStorage struct {
+	next  Storage
+	out   io.WriteCloser
+	mutex sync.Mutex
+	graph linkGraph
+	// Every page's score, best first, once closed
+	scores []PageScore
+}
+
+type PageScore struct {
+	URL      string
+	PageRank float64
+	InLinks  int
+}
+
+func NewPageRankStorage(next Storage, filename string) (*PageRankStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PageRank file: %w", err)
+	}
+	return &PageRankStorage{next: next, out: out, graph: newLinkGraph()}, nil
+}
+
+func (p *PageRankStorage) Save(ctx context.Context, data PageData) error {
+	p.mutex.Lock()
+	p.graph.add(data)
+	p.mutex.Unlock()
+	return p.next.Save(ctx, data)
+}
+
+func (p *PageRankStorage) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ranks, inLinks := p.graph.pageRank()
+	p.scores = make([]PageScore, len(p.graph.pages))
+	for i, page := range p.graph.pages {
+		p.scores[i] = PageScore{URL: p.graph.urls[page.url], PageRank: ranks[i], InLinks: inLinks[i]}
+	}
+	sort.SliceStable(p.scores, func(i, j int) bool { return p.scores[i].PageRank > p.scores[j].PageRank })
+	p.graph = linkGraph{}
+
+	writer := csv.NewWriter(p.out)
+	writer.Write([]string{"url", "pagerank", "inlinks"})
+	for _, score := range p.scores {
+		writer.Write([]string{score.URL, strconv.FormatFloat(score.PageRank, 'g', 6, 64), strconv.Itoa(score.InLinks)})
+	}
+	writer.Flush()
+
+	return errors.Join(writer.Error(), p.out.Close(), p.next.Close())
+}
+
+// The n best-scored pages, after Close
+func (p *PageRankStorage) Top(n int) []PageScore {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if n > len(p.scores) {
+		n = len(p.scores)
+	}
+	return p.scores[:n]
+}
+
+// The crawled pages and their followed links, with every URL stored once
+type linkGraph struct {
+	ids   map[string]int
+	urls  []string
+	pages []graphPage
+}
+
+type graphPage struct {
+	url   int
+	final int // -1 when the page didn't redirect
+	links []int
+}
+
+func newLinkGraph() linkGraph {
+	return linkGraph{ids: make(map[string]int)}
+}
+
+func (g *linkGraph) id(rawURL string) int {
+	if id, ok := g.ids[rawURL]; ok {
+		return id
+	}
+	g.ids[rawURL] = len(g.urls)
+	g.urls = append(g.urls, rawURL)
+	return len(g.urls) - 1
+}
+
+func (g *linkGraph) add(data PageData) {
+	page := graphPage{url: g.id(data.URL), final: -1}
+	if data.FinalURL != "" {
+		page.final = g.id(data.FinalURL)
+	}
+	links := pageLinks(data)
+	page.links = make([]int, len(links))
+	for i, link := range links {
+		page.links[i] = g.id(link)
+	}
+	g.pages = append(g.pages, page)
+}
+
+// Scores the pages by power iteration. Ranks sum to 1; pages without
+// outgoing links share theirs among all pages.
+func (g *linkGraph) pageRank() ([]float64, []int) {
+	n := len(g.pages)
+	// Links may point at the URL a page redirected to
+	index := make(map[int]int, n)
+	for i, page := range g.pages {
+		if page.final >= 0 {
+			index[page.final] = i
+		}
+	}
+	for i, page := range g.pages {
+		index[page.url] = i
+	}
+
+	out := make([][]int, n)
+	inLinks := make([]int, n)
+	for i, page := range g.pages {
+		seen := make(map[int]bool)
+		for _, link := range page.links {
+			j, ok := index[link]
+			if !ok || j == i || seen[j] {
+				continue
+			}
+			seen[j] = true
+			out[i] = append(out[i], j)
+			inLinks[j]++
+		}
+	}
+
+	ranks := make([]float64, n)
+	for i := range ranks {
+		ranks[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iteration := 0; iteration < pageRankIterations; iteration++ {
+		dangling := 0.0
+		for i, targets := range out {
+			if len(targets) == 0 {
+				dangling += ranks[i]
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, targets := range out {
+			share := pageRankDamping * ranks[i] / float64(len(targets))
+			for _, j := range targets {
+				next[j] += share
+			}
+		}
+
+		delta := 0.0
+		for i := range ranks {
+			delta += math.Abs(next[i] - ranks[i])
+		}
+		ranks, next = next, ranks
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+	return ranks, inLinks
+}
+
+// The page's followed links, from its anchors where it has them
+func pageLinks(data PageData) []string {
+	if data.Anchors == nil {
+		return data.Links
+	}
+	links := make([]string, 0, len(data.Anchors))
+	for _, anchor := range data.Anchors {
+		if !strings.Contains(strings.ToLower(anchor.Rel), "nofollow") {
+			links = append(links, anchor.URL)
+		}
+	}
+	return links
+}
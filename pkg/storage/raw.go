@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Encoding of PageData.RawHTML when it is compressed
+const RawHTMLGzipBase64 = "gzip+base64"
+
+// Returns the body gzipped and base64-encoded, for embedding in records
+func EncodeRawHTML(body []byte) (string, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return "", fmt.Errorf("failed to compress raw HTML: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress raw HTML: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Directory of raw page bodies named by the SHA-256 of their URL, e.g.
+// 3f/3fa2...c1.html, so a record's file can be found from its URL alone
+type RawHTMLDir struct {
+	dir string
+}
+
+func NewRawHTMLDir(dir string) (*RawHTMLDir, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create raw HTML directory: %w", err)
+	}
+	return &RawHTMLDir{dir: dir}, nil
+}
+
+// Returns the path the body of the URL is stored at
+func (r *RawHTMLDir) Path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(r.dir, name[:2], name+".html")
+}
+
+// Writes the body, replacing an earlier copy, and returns its path
+func (r *RawHTMLDir) Write(rawURL string, body []byte) (string, error) {
+	path := r.Path(rawURL)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create raw HTML directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to write raw HTML: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write raw HTML: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Reads the records of a json or ndjson output file, decompressing it when
+// the name ends in .gz or .zst
+func ReadRecords(filename string) ([]PageData, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	var in io.Reader = file
+	switch lower := strings.ToLower(filename); {
+	case strings.HasSuffix(lower, ".gz"):
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		defer gz.Close()
+		in = gz
+	case strings.HasSuffix(lower, ".zst"):
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		defer decoder.Close()
+		in = decoder
+	}
+
+	reader := bufio.NewReader(in)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	var records []PageData
+	decoder := json.NewDecoder(reader)
+	if first == '[' {
+		if err := decoder.Decode(&records); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filename, err)
+		}
+		return records, nil
+	}
+
+	for {
+		var record PageData
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filename, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
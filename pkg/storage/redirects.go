@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Issues reported by RedirectAuditStorage, in report order
+const (
+	IssueRedirectLoop      = "redirect-loop"
+	IssueLongRedirectChain = "long-redirect-chain"
+	IssueHTTPSToHTTP       = "https-to-http"
+	IssueCanonicalScheme   = "canonical-scheme-mismatch"
+	IssueCanonicalOffSite  = "canonical-off-site"
+)
+
+var redirectIssueOrder = []string{
+	IssueRedirectLoop,
+	IssueLongRedirectChain,
+	IssueHTTPSToHTTP,
+	IssueCanonicalScheme,
+	IssueCanonicalOffSite,
+}
+
+// Error class of records that failed with a redirect loop
+const redirectLoopClass = "redirect-loop"
+
+// Checks every record's redirects and canonical URL and, on Close, writes a
+// redirect audit CSV (issue, url, detail) of redirect loops, chains of more
+// than maxHops redirects, redirects from https to http, canonical URLs on the
+// other scheme and canonical URLs on another host. Loops are only seen in
+// error records.
+type RedirectAuditStorage struct {
+	next     Storage
+	filename string
+	maxHops  int
+
+	mutex  sync.Mutex
+	issues []auditIssue
+	counts map[string]int
+}
+
+func NewRedirectAuditStorage(next Storage, filename string, maxHops int) *RedirectAuditStorage {
+	return &RedirectAuditStorage{
+		next:     next,
+		filename: filename,
+		maxHops:  maxHops,
+		counts:   make(map[string]int),
+	}
+}
+
+func (r *RedirectAuditStorage) Save(ctx context.Context, data PageData) error {
+	issues := r.check(data)
+	if len(issues) > 0 {
+		r.mutex.Lock()
+		r.issues = append(r.issues, issues...)
+		for _, issue := range issues {
+			r.counts[issue.issue]++
+		}
+		r.mutex.Unlock()
+	}
+	return r.next.Save(ctx, data)
+}
+
+func (r *RedirectAuditStorage) check(data PageData) []auditIssue {
+	if data.ErrorClass == redirectLoopClass {
+		return []auditIssue{{issue: IssueRedirectLoop, url: data.URL, detail: data.Error}}
+	}
+	if data.Error != "" {
+		return nil
+	}
+
+	var issues []auditIssue
+	if len(data.Redirects) > 0 {
+		hops := make([]string, 0, len(data.Redirects)+1)
+		for _, redirect := range data.Redirects {
+			hops = append(hops, redirect.URL)
+		}
+		hops = append(hops, data.FinalURL)
+		chain := strings.Join(hops, " -> ")
+
+		if len(data.Redirects) > r.maxHops {
+			detail := fmt.Sprintf("%d redirects: %s", len(data.Redirects), chain)
+			issues = append(issues, auditIssue{issue: IssueLongRedirectChain, url: data.URL, detail: detail})
+		}
+		for i := 1; i < len(hops); i++ {
+			if strings.HasPrefix(hops[i-1], "https:") && strings.HasPrefix(hops[i], "http:") {
+				issues = append(issues, auditIssue{issue: IssueHTTPSToHTTP, url: data.URL, detail: chain})
+				break
+			}
+		}
+	}
+
+	if data.CanonicalURL == "" {
+		return issues
+	}
+	pageURL := data.URL
+	if data.FinalURL != "" {
+		pageURL = data.FinalURL
+	}
+	page, err := url.Parse(pageURL)
+	if err != nil {
+		return issues
+	}
+	canonical, err := url.Parse(data.CanonicalURL)
+	if err != nil {
+		return issues
+	}
+	switch {
+	case !strings.EqualFold(canonical.Hostname(), page.Hostname()):
+		issues = append(issues, auditIssue{issue: IssueCanonicalOffSite, url: data.URL, detail: data.CanonicalURL})
+	case canonical.Scheme != page.Scheme:
+		issues = append(issues, auditIssue{issue: IssueCanonicalScheme, url: data.URL, detail: data.CanonicalURL})
+	}
+	return issues
+}
+
+// Number of pages with each issue so far
+func (r *RedirectAuditStorage) Counts() map[string]int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counts := make(map[string]int, len(r.counts))
+	for issue, count := range r.counts {
+		counts[issue] = count
+	}
+	return counts
+}
+
+func (r *RedirectAuditStorage) Close() error {
+	r.mutex.Lock()
+	issues := r.issues
+	sortIssues(issues, redirectIssueOrder)
+	r.mutex.Unlock()
+
+	return errors.Join(writeAudit(r.filename, "redirect audit", issues), r.next.Close())
+}
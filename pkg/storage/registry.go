@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"sync"
+)
+
+// Creates a storage for an output target: a filename, URL or connection
+// string, depending on the format
+type Opener func(target string, opts Options) (Storage, error)
+
+var (
+	registry      = make(map[string]Opener)
+	registryMutex sync.RWMutex
+)
+
+// Makes a custom format, such as a database or search service sink,
+// available to Open and IsFormat. Registering a built-in format replaces it.
+func Register(format string, opener Opener) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[format] = opener
+}
+
+func registered(format string) (Opener, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	opener, ok := registry[format]
+	return opener, ok
+}
+
+func init() {
+	Register("webhook", func(target string, opts Options) (Storage, error) {
+		return NewWebhookStorage(target), nil
+	})
+}
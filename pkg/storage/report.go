@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Writes a standalone HTML report when closed: a summary, a sortable table
+// of pages with status codes and titles, and the broken links found, i.e.
+// links to pages that failed or returned an error status
+type ReportStorage struct {
+	out   io.WriteCloser
+	mutex sync.Mutex
+	pages []reportPage
+}
+
+type reportPage struct {
+	URL         string
+	Title       string
+	StatusCode  int
+	Depth       int
+	ContentType string
+	Size        int64
+	Error       string
+	Links       []string
+}
+
+type brokenLink struct {
+	Source     string
+	Target     string
+	StatusCode int
+	Error      string
+}
+
+func NewReportStorage(filename string) (*ReportStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report file: %w", err)
+	}
+	return &ReportStorage{out: out}, nil
+}
+
+func (r *ReportStorage) Save(ctx context.Context, data PageData) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.pages = append(r.pages, reportPage{
+		URL:         data.URL,
+		Title:       data.Title,
+		StatusCode:  data.StatusCode,
+		Depth:       data.Depth,
+		ContentType: data.ContentType,
+		Size:        data.Size,
+		Error:       data.Error,
+		Links:       data.Links,
+	})
+	return nil
+}
+
+func (r *ReportStorage) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	failed := make(map[string]reportPage)
+	for _, page := range r.pages {
+		if page.Error != "" || page.StatusCode >= 400 {
+			failed[page.URL] = page
+		}
+	}
+
+	var broken []brokenLink
+	for _, page := range r.pages {
+		for _, link := range page.Links {
+			if target, ok := failed[link]; ok {
+				broken = append(broken, brokenLink{
+					Source:     page.URL,
+					Target:     link,
+					StatusCode: target.StatusCode,
+					Error:      target.Error,
+				})
+			}
+		}
+	}
+	sort.Slice(broken, func(i, j int) bool {
+		if broken[i].Target != broken[j].Target {
+			return broken[i].Target < broken[j].Target
+		}
+		return broken[i].Source < broken[j].Source
+	})
+
+	err := reportTemplate.Execute(r.out, map[string]interface{}{
+		"Generated": time.Now().Format(time.RFC1123),
+		"Pages":     r.pages,
+		"Failed":    len(failed),
+		"Broken":    broken,
+	})
+	if err != nil {
+		r.out.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return r.out.Close()
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Crawl report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+td.num { text-align: right; }
+tr.error td { background: #fdecea; }
+.summary span { margin-right: 2em; }
+</style>
+</head>
+<body>
+<h1>Crawl report</h1>
+<p class="summary">
+<span>Generated {{.Generated}}</span>
+<span>Pages: {{len .Pages}}</span>
+<span>Failed: {{.Failed}}</span>
+<span>Broken links: {{len .Broken}}</span>
+</p>
+
+<h2>Pages</h2>
+<table class="sortable">
+<thead><tr><th>URL</th><th>Status</th><th>Title</th><th>Depth</th><th>Type</th><th>Size</th><th>Links</th><th>Error</th></tr></thead>
+<tbody>
+{{range .Pages}}<tr{{if or .Error (ge .StatusCode 400)}} class="error"{{end}}><td><a href="{{.URL}}">{{.URL}}</a></td><td class="num">{{if .StatusCode}}{{.StatusCode}}{{end}}</td><td>{{.Title}}</td><td class="num">{{.Depth}}</td><td>{{.ContentType}}</td><td class="num">{{.Size}}</td><td class="num">{{len .Links}}</td><td>{{.Error}}</td></tr>
+{{end}}</tbody>
+</table>
+
+<h2>Broken links</h2>
+{{if .Broken}}<table class="sortable">
+<thead><tr><th>Linked from</th><th>Broken link</th><th>Status</th><th>Error</th></tr></thead>
+<tbody>
+{{range .Broken}}<tr><td><a href="{{.Source}}">{{.Source}}</a></td><td><a href="{{.Target}}">{{.Target}}</a></td><td class="num">{{if .StatusCode}}{{.StatusCode}}{{end}}</td><td>{{.Error}}</td></tr>
+{{end}}</tbody>
+</table>
+{{else}}<p>None found.</p>
+{{end}}
+<script>
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0];
+    var column = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var ascending = th.dataset.order !== "asc";
+    th.parentNode.querySelectorAll("th").forEach(function (h) { delete h.dataset.order; });
+    th.dataset.order = ascending ? "asc" : "desc";
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var order = !isNaN(nx) && !isNaN(ny) ? nx - ny : x.localeCompare(y);
+      return ascending ? order : -order;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Routes records to a separate storage per tag so that one crawl can feed
+// several datasets. Tag storages are created on first use by the factory.
+// Records without tags go to the default storage; records with several tags
+// are written to each of them.
+type TagRouter struct {
+	defaultStorage Storage
+	factory        func(tag string) (Storage, error)
+	mutex          sync.Mutex
+	routes         map[string]Storage
+}
+
+func NewTagRouter(defaultStorage Storage, factory func(tag string) (Storage, error)) *TagRouter {
+	return &TagRouter{
+		defaultStorage: defaultStorage,
+		factory:        factory,
+		routes:         make(map[string]Storage),
+	}
+}
+
+func (r *TagRouter) Save(ctx context.Context, data PageData) error {
+	if len(data.Tags) == 0 {
+		return r.defaultStorage.Save(ctx, data)
+	}
+
+	var errs []error
+	for _, tag := range data.Tags {
+		store, err := r.route(tag)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := store.Save(ctx, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *TagRouter) route(tag string) (Storage, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if store, ok := r.routes[tag]; ok {
+		return store, nil
+	}
+
+	store, err := r.factory(tag)
+	if err != nil {
+		return nil, err
+	}
+	r.routes[tag] = store
+	return store, nil
+}
+
+func (r *TagRouter) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	errs := []error{r.defaultStorage.Close()}
+	for _, store := range r.routes {
+		errs = append(errs, store.Close())
+	}
+	return errors.Join(errs...)
+}
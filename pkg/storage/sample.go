@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+)
+
+// Writes every record without its content to the inventory storage and a
+// uniform random sample of size full records to the sample storage, giving
+// a small quick-look file next to a complete but compact inventory. The
+// sample is chosen by reservoir sampling and written on Close.
+type SampleStorage struct {
+	inventory Storage
+	sample    Storage
+	size      int
+	mutex     sync.Mutex
+	seen      int
+	reservoir []PageData
+	rng       *rand.Rand
+}
+
+func NewSampleStorage(inventory, sample Storage, size int, seed int64) *SampleStorage {
+	return &SampleStorage{
+		inventory: inventory,
+		sample:    sample,
+		size:      size,
+		reservoir: make([]PageData, 0, size),
+		rng:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (s *SampleStorage) Save(ctx context.Context, data PageData) error {
+	s.mutex.Lock()
+	s.seen++
+	if len(s.reservoir) < s.size {
+		s.reservoir = append(s.reservoir, data)
+	} else if i := s.rng.Intn(s.seen); i < s.size {
+		s.reservoir[i] = data
+	}
+	s.mutex.Unlock()
+
+	data.Content = ""
+	return s.inventory.Save(ctx, data)
+}
+
+func (s *SampleStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var errs []error
+	for _, data := range s.reservoir {
+		if err := s.sample.Save(context.Background(), data); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+
+	errs = append(errs, s.sample.Close(), s.inventory.Close())
+	return errors.Join(errs...)
+}
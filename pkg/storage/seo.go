@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Longest meta description, in characters, before search results usually
+// cut it off
+const DefaultMaxDescription = 160
+
+// Issues reported by SEOAuditStorage, in report order
+const (
+	IssueDuplicateTitle       = "duplicate-title"
+	IssueDuplicateDescription = "duplicate-description"
+	IssueMissingTitle         = "missing-title"
+	IssueLongDescription      = "long-description"
+	IssueMissingH1            = "missing-h1"
+)
+
+var seoIssueOrder = []string{
+	IssueDuplicateTitle,
+	IssueDuplicateDescription,
+	IssueMissingTitle,
+	IssueLongDescription,
+	IssueMissingH1,
+}
+
+// Collects the title, meta description and h1 count of every HTML page and,
+// on Close, writes an SEO audit CSV (issue, url, detail) of duplicate titles
+// and descriptions, missing titles, overlong descriptions and pages without
+// an h1. Missing h1s are only found for records with headings.
+type SEOAuditStorage struct {
+	next           Storage
+	filename       string
+	maxDescription int
+
+	mutex  sync.Mutex
+	pages  []seoPage
+	counts map[string]int
+}
+
+type seoPage struct {
+	url         string
+	title       string
+	description string
+	h1s         int
+	headings    bool
+}
+
+// One row of an audit CSV
+type auditIssue struct {
+	issue  string
+	url    string
+	detail string
+}
+
+func NewSEOAuditStorage(next Storage, filename string, maxDescription int) *SEOAuditStorage {
+	return &SEOAuditStorage{
+		next:           next,
+		filename:       filename,
+		maxDescription: maxDescription,
+		counts:         make(map[string]int),
+	}
+}
+
+func (s *SEOAuditStorage) Save(ctx context.Context, data PageData) error {
+	if data.Error == "" && data.ContentKind == "html" {
+		page := seoPage{
+			url:         data.URL,
+			title:       collapseSpace(data.Title),
+			description: collapseSpace(data.Description),
+			h1s:         data.HeadingCounts["h1"],
+			headings:    data.HeadingCounts != nil,
+		}
+		s.mutex.Lock()
+		s.pages = append(s.pages, page)
+		s.mutex.Unlock()
+	}
+	return s.next.Save(ctx, data)
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Number of pages with each issue; complete once the storage is closed
+func (s *SEOAuditStorage) Counts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int, len(s.counts))
+	for issue, count := range s.counts {
+		counts[issue] = count
+	}
+	return counts
+}
+
+func (s *SEOAuditStorage) Close() error {
+	return errors.Join(s.writeReport(), s.next.Close())
+}
+
+func (s *SEOAuditStorage) writeReport() error {
+	s.mutex.Lock()
+	issues := s.audit()
+	for _, issue := range issues {
+		s.counts[issue.issue]++
+	}
+	s.mutex.Unlock()
+
+	return writeAudit(s.filename, "SEO audit", issues)
+}
+
+// Must be called with s.mutex held
+func (s *SEOAuditStorage) audit() []auditIssue {
+	var issues []auditIssue
+	byTitle := make(map[string][]string)
+	byDescription := make(map[string][]string)
+
+	for _, page := range s.pages {
+		if page.title == "" {
+			issues = append(issues, auditIssue{issue: IssueMissingTitle, url: page.url})
+		} else {
+			byTitle[page.title] = append(byTitle[page.title], page.url)
+		}
+
+		if page.description != "" {
+			byDescription[page.description] = append(byDescription[page.description], page.url)
+			if length := utf8.RuneCountInString(page.description); s.maxDescription > 0 && length > s.maxDescription {
+				detail := fmt.Sprintf("%d characters", length)
+				issues = append(issues, auditIssue{issue: IssueLongDescription, url: page.url, detail: detail})
+			}
+		}
+
+		if page.headings && page.h1s == 0 {
+			issues = append(issues, auditIssue{issue: IssueMissingH1, url: page.url})
+		}
+	}
+
+	issues = append(issues, duplicates(IssueDuplicateTitle, byTitle)...)
+	issues = append(issues, duplicates(IssueDuplicateDescription, byDescription)...)
+
+	sortIssues(issues, seoIssueOrder)
+	return issues
+}
+
+// One issue per page sharing a value with other pages; the value is the
+// detail, so the pages sharing it sort together
+func duplicates(issue string, byValue map[string][]string) []auditIssue {
+	var issues []auditIssue
+	for value, urls := range byValue {
+		if len(urls) < 2 {
+			continue
+		}
+		for _, url := range urls {
+			issues = append(issues, auditIssue{issue: issue, url: url, detail: value})
+		}
+	}
+	return issues
+}
+
+func writeAudit(filename, name string, issues []auditIssue) error {
+	out, err := createOutput(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	writer := csv.NewWriter(out)
+	writer.Write([]string{"issue", "url", "detail"})
+	for _, issue := range issues {
+		writer.Write([]string{issue.issue, issue.url, issue.detail})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return out.Close()
+}
+
+// Orders issues by their rank in order, then detail, then URL
+func sortIssues(issues []auditIssue, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, issue := range order {
+		rank[issue] = i
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.issue != b.issue {
+			return rank[a.issue] < rank[b.issue]
+		}
+		if a.detail != b.detail {
+			return a.detail < b.detail
+		}
+		return a.url < b.url
+	})
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Writes records to numbered shard files, starting a new shard once the
+// current one holds maxRecords records or maxBytes bytes (uncompressed);
+// a zero limit is not checked. Shards are compressed like the output name
+// (see createOutput).
+type ShardedStorage struct {
+	filename   string
+	newStorage func(out io.WriteCloser) (Storage, error)
+	maxRecords int
+	maxBytes   int64
+	mutex      sync.Mutex
+	current    Storage
+	out        *outputFile
+	records    int
+	shards     []string
+}
+
+func NewShardedStorage(filename string, maxRecords int, maxBytes int64, newStorage func(out io.WriteCloser) (Storage, error)) *ShardedStorage {
+	return &ShardedStorage{
+		filename:   filename,
+		newStorage: newStorage,
+		maxRecords: maxRecords,
+		maxBytes:   maxBytes,
+	}
+}
+
+func (s *ShardedStorage) Save(ctx context.Context, data PageData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.current == nil {
+		if err := s.openShard(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.current.Save(ctx, data); err != nil {
+		return err
+	}
+	s.records++
+
+	if (s.maxRecords > 0 && s.records >= s.maxRecords) || (s.maxBytes > 0 && s.out.written >= s.maxBytes) {
+		return s.closeShard()
+	}
+	return nil
+}
+
+// Returns the shard files written so far
+func (s *ShardedStorage) Shards() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.shards...)
+}
+
+func (s *ShardedStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// An empty crawl still produces one (empty) shard
+	if s.current == nil && len(s.shards) == 0 {
+		if err := s.openShard(); err != nil {
+			return err
+		}
+	}
+	return s.closeShard()
+}
+
+func (s *ShardedStorage) openShard() error {
+	filename := shardFilename(s.filename, len(s.shards)+1)
+	out, err := createOutput(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create shard: %w", err)
+	}
+
+	store, err := s.newStorage(out)
+	if err != nil {
+		return err
+	}
+
+	s.current = store
+	s.out = out
+	s.records = 0
+	s.shards = append(s.shards, filename)
+	return nil
+}
+
+func (s *ShardedStorage) closeShard() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	s.out = nil
+	return err
+}
+
+// results.json.gz -> results-0001.json.gz
+func shardFilename(filename string, n int) string {
+	base, ext := SplitExt(filename)
+	return fmt.Sprintf("%s-%04d%s", base, n, ext)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits from the sitemaps.org protocol for a single sitemap file
+const (
+	sitemapMaxURLs  = 50000
+	sitemapMaxBytes = 50 * 1024 * 1024
+)
+
+const (
+	sitemapHeader      = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	sitemapFooter      = "</urlset>\n"
+	sitemapIndexHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	sitemapIndexFooter = "</sitemapindex>\n"
+)
+
+// Writes crawled pages as an XML sitemap. Crawls that exceed the protocol
+// limits are split into numbered sitemaps referenced from a sitemap index
+// written to the output file. Output ending in .gz is gzip-compressed.
+type SitemapStorage struct {
+	file     *os.File
+	filename string
+	baseURL  string
+	compress bool
+	mutex    sync.Mutex
+	entries  []string
+}
+
+// baseURL is the public location the sitemap files will be served from and
+// is used for the index entries; when empty it defaults to the root of the
+// first crawled site.
+func NewSitemapStorage(filename, baseURL string) (*SitemapStorage, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sitemap file: %w", err)
+	}
+
+	if baseURL != "" && !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return &SitemapStorage{
+		file:     file,
+		filename: filename,
+		baseURL:  baseURL,
+		compress: strings.HasSuffix(strings.ToLower(filename), ".gz"),
+	}, nil
+}
+
+func (s *SitemapStorage) Save(ctx context.Context, data PageData) error {
+	// Protected pages don't belong in a public sitemap
+	if data.AuthRequired {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.baseURL == "" {
+		if parsedURL, err := url.Parse(data.URL); err == nil {
+			s.baseURL = parsedURL.Scheme + "://" + parsedURL.Host + "/"
+		}
+	}
+
+	var entry strings.Builder
+	entry.WriteString("  <url><loc>")
+	xml.EscapeText(&entry, []byte(data.URL))
+	entry.WriteString("</loc><lastmod>")
+	entry.WriteString(data.CrawledAt.UTC().Format(time.RFC3339))
+	entry.WriteString("</lastmod></url>\n")
+
+	s.entries = append(s.entries, entry.String())
+	return nil
+}
+
+func (s *SitemapStorage) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	parts := s.split()
+	if len(parts) <= 1 {
+		var content []byte
+		if len(parts) == 1 {
+			content = parts[0]
+		} else {
+			content = []byte(sitemapHeader + sitemapFooter)
+		}
+		if err := writeCompressed(s.file, content, s.compress); err != nil {
+			s.file.Close()
+			return fmt.Errorf("failed to write sitemap: %w", err)
+		}
+		return s.file.Close()
+	}
+
+	var index bytes.Buffer
+	index.WriteString(sitemapIndexHeader)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for i, part := range parts {
+		partName := s.partFilename(i + 1)
+		if err := writeCompressedFile(partName, part, s.compress); err != nil {
+			s.file.Close()
+			return fmt.Errorf("failed to write sitemap part: %w", err)
+		}
+
+		index.WriteString("  <sitemap><loc>")
+		xml.EscapeText(&index, []byte(s.baseURL+filepath.Base(partName)))
+		index.WriteString("</loc><lastmod>" + now + "</lastmod></sitemap>\n")
+	}
+	index.WriteString(sitemapIndexFooter)
+
+	if err := writeCompressed(s.file, index.Bytes(), s.compress); err != nil {
+		s.file.Close()
+		return fmt.Errorf("failed to write sitemap index: %w", err)
+	}
+	return s.file.Close()
+}
+
+// Groups the entries into complete sitemap documents within protocol limits
+func (s *SitemapStorage) split() [][]byte {
+	var parts [][]byte
+	var current bytes.Buffer
+	count := 0
+	overhead := len(sitemapHeader) + len(sitemapFooter)
+
+	for _, entry := range s.entries {
+		if count > 0 && (count >= sitemapMaxURLs || current.Len()+len(entry)+overhead > sitemapMaxBytes) {
+			current.WriteString(sitemapFooter)
+			parts = append(parts, append([]byte(nil), current.Bytes()...))
+			current.Reset()
+			count = 0
+		}
+		if count == 0 {
+			current.WriteString(sitemapHeader)
+		}
+		current.WriteString(entry)
+		count++
+	}
+
+	if count > 0 {
+		current.WriteString(sitemapFooter)
+		parts = append(parts, current.Bytes())
+	}
+	return parts
+}
+
+// sitemap.xml.gz -> sitemap-00001.xml.gz
+func (s *SitemapStorage) partFilename(n int) string {
+	base := strings.TrimSuffix(s.filename, filepath.Ext(s.filename))
+	if s.compress {
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	name := fmt.Sprintf("%s-%05d.xml", base, n)
+	if s.compress {
+		name += ".gz"
+	}
+	return name
+}
+
+func writeCompressedFile(filename string, content []byte, compress bool) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := writeCompressed(file, content, compress); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeCompressed(w io.Writer, content []byte, compress bool) error {
+	if !compress {
+		_, err := w.Write(content)
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(content); err != nil {
+		return err
+	}
+	return gz.Close()
+}
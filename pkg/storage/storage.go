@@ -1,53 +1,177 @@
 package storage
 
 import (
-	"encoding/csv"
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 type PageData struct {
-	URL         string    `json:"url"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Content     string    `json:"content,omitempty"`
-	Links       []string  `json:"links,omitempty"`
-	CrawledAt   time.Time `json:"crawled_at"`
-	Depth       int       `json:"depth"`
+	URL         string   `json:"url"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Author      string   `json:"author,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Links       []string `json:"links,omitempty"`
+	// Text and rel of each link, for edge files; kept even when Links is
+	// left out, and never written to records
+	Anchors []Anchor `json:"-"`
+	// Distinct hosts, other than the page's own, that the page links to
+	ExternalDomains []string  `json:"external_domains,omitempty"`
+	CrawledAt       time.Time `json:"crawled_at"`
+	Depth           int       `json:"depth"`
+	SeedTag         string    `json:"seed_tag,omitempty"`
+	Tags            []string  `json:"tags,omitempty"`
+	AuthRequired    bool      `json:"auth_required,omitempty"`
+	RunID           string    `json:"run_id,omitempty"`
+	ContentType     string    `json:"content_type,omitempty"`
+	ContentKind     string    `json:"content_kind,omitempty"`
+	Size            int64     `json:"size,omitempty"`
+	StatusCode      int       `json:"status_code,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	// For failed URLs: the class of the error (dns, timeout, http-status...)
+	// and how many fetches were tried
+	ErrorClass string `json:"error_class,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+	// Order in which the crawl saved the page, from 1, and the page it was
+	// discovered on; together they form the crawl tree
+	Sequence  int    `json:"sequence,omitempty"`
+	ParentURL string `json:"parent_url,omitempty"`
+	Forms     []Form `json:"forms,omitempty"`
+	// The h1-h6 outline and the number of headings per level ("h1": 1, ...)
+	Headings      []Heading      `json:"headings,omitempty"`
+	HeadingCounts map[string]int `json:"heading_counts,omitempty"`
+	// The unparsed page body, inline (plain or RawHTMLEncoding) or as a file
+	// in a RawHTMLDir
+	RawHTML         string   `json:"raw_html,omitempty"`
+	RawHTMLEncoding string   `json:"raw_html_encoding,omitempty"`
+	RawHTMLFile     string   `json:"raw_html_file,omitempty"`
+	Quality         *Quality `json:"quality,omitempty"`
+	// Keyword occurrences, title matches weighted higher; nil without
+	// keywords
+	KeywordScore *int `json:"keyword_score,omitempty"`
+	// Language variants of the page from its hreflang links
+	Alternates []Alternate `json:"alternates,omitempty"`
+	// The page's rel=canonical URL, for AMP pages the page they are a
+	// version of; for other pages, their AMP version
+	AMP          bool   `json:"amp,omitempty"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	AMPURL       string `json:"amp_url,omitempty"`
+	// Values extracted with -select, by name
+	Fields map[string]string `json:"fields,omitempty"`
+	// The redirects followed to fetch the page, in order, and the URL that
+	// finally answered; empty when the URL answered directly
+	Redirects []Redirect `json:"redirects,omitempty"`
+	FinalURL  string     `json:"final_url,omitempty"`
+	// With -technologies: the CMS, frameworks, analytics and servers
+	// detected, e.g. ["Nginx", "PHP", "WordPress"]
+	Technologies []string `json:"technologies,omitempty"`
+	// With -social: the social media profiles the page links to
+	SocialProfiles []SocialProfile `json:"social_profiles,omitempty"`
+	// With the aliases dedup strategy: URLs found, before the page was
+	// saved, to redirect to it or name it as their rel=canonical URL
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Content-quality signals of an HTML page
+type Quality struct {
+	WordCount          int     `json:"word_count"`
+	TextRatio          float64 `json:"text_ratio"` // visible text / HTML size, 0-1
+	LinkCount          int     `json:"link_count"`
+	BoilerplatePercent float64 `json:"boilerplate_percent"` // share of text in nav, footer etc.
+}
+
+// One redirect: the URL that answered with it and its status
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+type SocialProfile struct {
+	Network string `json:"network"`
+	Handle  string `json:"handle"`
+	URL     string `json:"url"`
+}
+
+type Alternate struct {
+	Lang string `json:"hreflang"`
+	URL  string `json:"url"`
+}
+
+type Anchor struct {
+	URL  string
+	Text string
+	Rel  string
+}
+
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+type Form struct {
+	Action string      `json:"action"`
+	Method string      `json:"method"`
+	Fields []FormField `json:"fields,omitempty"`
+}
+
+type FormField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
 }
 
 type Storage interface {
-	Save(data PageData) error
+	Save(ctx context.Context, data PageData) error
 	Close() error
 }
 
+// Writes records as a single JSON array, streamed to disk as they are saved
 type JSONStorage struct {
-	file      *os.File
-	encoder   *json.Encoder
-	mutex     sync.Mutex
-	dataItems []PageData
+	out     io.WriteCloser
+	mutex   sync.Mutex
+	records int
 }
 
 func NewJSONStorage(filename string) (*JSONStorage, error) {
-	file, err := os.Create(filename)
+	out, err := createOutput(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JSON file: %w", err)
 	}
+	return newJSONStorage(out), nil
+}
 
-	return &JSONStorage{
-		file:      file,
-		encoder:   json.NewEncoder(file),
-		dataItems: make([]PageData, 0),
-	}, nil
+func newJSONStorage(out io.WriteCloser) *JSONStorage {
+	return &JSONStorage{out: out}
 }
 
-func (j *JSONStorage) Save(data PageData) error {
+func (j *JSONStorage) Save(ctx context.Context, data PageData) error {
 	j.mutex.Lock()
 	defer j.mutex.Unlock()
-	j.dataItems = append(j.dataItems, data)
+
+	record, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON data: %w", err)
+	}
+
+	separator := ","
+	if j.records == 0 {
+		separator = "["
+	}
+	if _, err := io.WriteString(j.out, separator); err != nil {
+		return fmt.Errorf("failed to write JSON data: %w", err)
+	}
+	if _, err := j.out.Write(record); err != nil {
+		return fmt.Errorf("failed to write JSON data: %w", err)
+	}
+
+	j.records++
 	return nil
 }
 
@@ -55,83 +179,120 @@ func (j *JSONStorage) Close() error {
 	j.mutex.Lock()
 	defer j.mutex.Unlock()
 
-	if _, err := j.file.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to reset file position: %w", err)
-	}
-
-	if err := j.file.Truncate(0); err != nil {
-		return fmt.Errorf("failed to truncate file: %w", err)
+	closing := "]\n"
+	if j.records == 0 {
+		closing = "[]\n"
 	}
-
-	if err := json.NewEncoder(j.file).Encode(j.dataItems); err != nil {
-		return fmt.Errorf("failed to encode JSON data: %w", err)
+	if _, err := io.WriteString(j.out, closing); err != nil {
+		j.out.Close()
+		return fmt.Errorf("failed to write JSON data: %w", err)
 	}
 
-	return j.file.Close()
+	return j.out.Close()
 }
 
-type CSVStorage struct {
-	file    *os.File
-	writer  *csv.Writer
+// Writes one JSON record per line
+type NDJSONStorage struct {
+	out     io.WriteCloser
+	encoder *json.Encoder
 	mutex   sync.Mutex
-	headers []string
 }
 
-func NewCSVStorage(filename string) (*CSVStorage, error) {
-	file, err := os.Create(filename)
+func NewNDJSONStorage(filename string) (*NDJSONStorage, error) {
+	out, err := createOutput(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CSV file: %w", err)
+		return nil, fmt.Errorf("failed to create NDJSON file: %w", err)
+	}
+	return newNDJSONStorage(out), nil
+}
+
+func newNDJSONStorage(out io.WriteCloser) *NDJSONStorage {
+	return &NDJSONStorage{
+		out:     out,
+		encoder: json.NewEncoder(out),
 	}
+}
 
-	writer := csv.NewWriter(file)
-	headers := []string{"URL", "Title", "Description", "Content", "Links", "CrawledAt", "Depth"}
+func (n *NDJSONStorage) Save(ctx context.Context, data PageData) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
 
-	if err := writer.Write(headers); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to write CSV headers: %w", err)
+	if err := n.encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to write NDJSON record: %w", err)
 	}
-	writer.Flush()
+	return nil
+}
 
-	return &CSVStorage{
-		file:    file,
-		writer:  writer,
-		headers: headers,
-	}, nil
+func (n *NDJSONStorage) Close() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.out.Close()
 }
 
-func (c *CSVStorage) Save(data PageData) error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+// Collects the unique hostnames seen across crawled pages and their links
+type HostStorage struct {
+	out   io.WriteCloser
+	mutex sync.Mutex
+	hosts map[string]bool
+}
 
-	linksStr := ""
-	for i, link := range data.Links {
-		if i > 0 {
-			linksStr += ","
-		}
-		linksStr += link
+func NewHostStorage(filename string) (*HostStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host inventory file: %w", err)
 	}
 
-	record := []string{
-		data.URL,
-		data.Title,
-		data.Description,
-		data.Content,
-		linksStr,
-		data.CrawledAt.Format(time.RFC3339),
-		fmt.Sprintf("%d", data.Depth),
+	return &HostStorage{
+		out:   out,
+		hosts: make(map[string]bool),
+	}, nil
+}
+
+func (h *HostStorage) Save(ctx context.Context, data PageData) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.addHost(data.URL)
+	for _, link := range data.Links {
+		h.addHost(link)
 	}
+	return nil
+}
 
-	if err := c.writer.Write(record); err != nil {
-		return fmt.Errorf("failed to write CSV record: %w", err)
+func (h *HostStorage) addHost(rawURL string) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return
 	}
 
-	c.writer.Flush()
-	return c.writer.Error()
+	host := strings.ToLower(parsedURL.Hostname())
+	if host != "" {
+		h.hosts[host] = true
+	}
 }
 
-func (c *CSVStorage) Close() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.writer.Flush()
-	return c.file.Close()
+func (h *HostStorage) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	hosts := make([]string, 0, len(h.hosts))
+	for host := range h.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	writer := bufio.NewWriter(h.out)
+	for _, host := range hosts {
+		if _, err := writer.WriteString(host + "\n"); err != nil {
+			h.out.Close()
+			return fmt.Errorf("failed to write host inventory: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		h.out.Close()
+		return fmt.Errorf("failed to write host inventory: %w", err)
+	}
+
+	return h.out.Close()
 }
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + "<crawl>\n"
+	xmlFooter = "</crawl>\n"
+)
+
+// One <page> element of the XML export
+type xmlPage struct {
+	XMLName      xml.Name  `xml:"page"`
+	URL          string    `xml:"url"`
+	Title        string    `xml:"title,omitempty"`
+	Description  string    `xml:"description,omitempty"`
+	Author       string    `xml:"author,omitempty"`
+	StatusCode   int       `xml:"status,omitempty"`
+	ContentType  string    `xml:"content_type,omitempty"`
+	ContentKind  string    `xml:"content_kind,omitempty"`
+	Size         int64     `xml:"size,omitempty"`
+	Depth        int       `xml:"depth"`
+	CrawledAt    string    `xml:"crawled_at"`
+	ParentURL    string    `xml:"parent_url,omitempty"`
+	SeedTag      string    `xml:"seed_tag,omitempty"`
+	Tags         *xmlTags  `xml:"tags"`
+	AuthRequired bool      `xml:"auth_required,omitempty"`
+	Error        string    `xml:"error,omitempty"`
+	ErrorClass   string    `xml:"error_class,omitempty"`
+	Attempts     int       `xml:"attempts,omitempty"`
+	Content      string    `xml:"content,omitempty"`
+	Links        *xmlLinks `xml:"links"`
+}
+
+// Wrappers so that empty lists are left out entirely
+type xmlTags struct {
+	Tag []string `xml:"tag"`
+}
+
+type xmlLinks struct {
+	Link []string `xml:"link"`
+}
+
+// Writes pages as <page> elements of a single <crawl> document
+type XMLStorage struct {
+	out   io.WriteCloser
+	mutex sync.Mutex
+	err   error
+}
+
+func NewXMLStorage(filename string) (*XMLStorage, error) {
+	out, err := createOutput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XML file: %w", err)
+	}
+	return newXMLStorage(out), nil
+}
+
+func newXMLStorage(out io.WriteCloser) *XMLStorage {
+	x := &XMLStorage{out: out}
+	if _, err := io.WriteString(out, xmlHeader); err != nil {
+		x.err = fmt.Errorf("failed to write XML header: %w", err)
+	}
+	return x
+}
+
+func (x *XMLStorage) Save(ctx context.Context, data PageData) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if x.err != nil {
+		return x.err
+	}
+
+	page := xmlPage{
+		URL:          data.URL,
+		Title:        data.Title,
+		Description:  data.Description,
+		Author:       data.Author,
+		StatusCode:   data.StatusCode,
+		ContentType:  data.ContentType,
+		ContentKind:  data.ContentKind,
+		Size:         data.Size,
+		Depth:        data.Depth,
+		CrawledAt:    data.CrawledAt.Format(time.RFC3339),
+		ParentURL:    data.ParentURL,
+		SeedTag:      data.SeedTag,
+		AuthRequired: data.AuthRequired,
+		Error:        data.Error,
+		ErrorClass:   data.ErrorClass,
+		Attempts:     data.Attempts,
+		Content:      data.Content,
+	}
+	if len(data.Tags) > 0 {
+		page.Tags = &xmlTags{Tag: data.Tags}
+	}
+	if len(data.Links) > 0 {
+		page.Links = &xmlLinks{Link: data.Links}
+	}
+
+	record, err := xml.MarshalIndent(page, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode XML data: %w", err)
+	}
+
+	if _, err := x.out.Write(append(record, '\n')); err != nil {
+		return fmt.Errorf("failed to write XML data: %w", err)
+	}
+	return nil
+}
+
+func (x *XMLStorage) Close() error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if _, err := io.WriteString(x.out, xmlFooter); err != nil {
+		x.out.Close()
+		return fmt.Errorf("failed to write XML data: %w", err)
+	}
+	return x.out.Close()
+}
@@ -0,0 +1,72 @@
+package tagging
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Assigns Tag to URLs matching Pattern. Patterns starting with "/" are
+// matched against the URL path, anything else against the full URL; "*"
+// matches any run of characters, e.g. "/blog/*" or "*://docs.*".
+type Rule struct {
+	Pattern string
+	Tag     string
+	re      *regexp.Regexp
+}
+
+type Rules []Rule
+
+// Parses a rule in pattern=tag form
+func ParseRule(s string) (Rule, error) {
+	pattern, tag, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" || tag == "" {
+		return Rule{}, fmt.Errorf("expected pattern=tag, got %q", s)
+	}
+	return NewRule(pattern, tag)
+}
+
+func NewRule(pattern, tag string) (Rule, error) {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+	}
+
+	return Rule{Pattern: pattern, Tag: tag, re: re}, nil
+}
+
+func (r Rule) Matches(rawURL string) bool {
+	if !strings.HasPrefix(r.Pattern, "/") {
+		return r.re.MatchString(rawURL)
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	path := parsedURL.Path
+	if path == "" {
+		path = "/"
+	}
+	return r.re.MatchString(path)
+}
+
+// Returns the distinct tags of every rule matching the URL, in rule order
+func (rules Rules) Tags(rawURL string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if !seen[rule.Tag] && rule.Matches(rawURL) {
+			seen[rule.Tag] = true
+			tags = append(tags, rule.Tag)
+		}
+	}
+	return tags
+}
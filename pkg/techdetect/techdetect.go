@@ -0,0 +1,173 @@
+// Package techdetect recognizes the CMS, frameworks, analytics and servers
+// behind a page from its headers, cookies, meta generator, script URLs and
+// markup, in the manner of Wappalyzer.
+package techdetect
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// What a page gives away about how it was built
+type Page struct {
+	Header http.Header
+	// The content of <meta name="generator">
+	Generator string
+	// Absolute URLs of the page's external scripts
+	Scripts []string
+	HTML    []byte
+}
+
+// A technology and the signs of it. Every pattern is a lowercase substring;
+// an empty header pattern matches any value.
+type rule struct {
+	name      string
+	headers   map[string]string
+	cookies   []string // cookie name prefixes
+	generator []string
+	scripts   []string
+	html      []string
+	implies   []string
+}
+
+var rules = []rule{
+	// CMS and shops
+	{name: "WordPress", generator: []string{"wordpress"}, scripts: []string{"/wp-content/", "/wp-includes/"}, html: []string{"/wp-content/"}, implies: []string{"PHP"}},
+	{name: "WooCommerce", generator: []string{"woocommerce"}, scripts: []string{"/plugins/woocommerce/"}, cookies: []string{"woocommerce_"}, implies: []string{"WordPress"}},
+	{name: "Drupal", headers: map[string]string{"X-Generator": "drupal", "X-Drupal-Cache": ""}, generator: []string{"drupal"}, scripts: []string{"/sites/all/", "drupal.js"}, implies: []string{"PHP"}},
+	{name: "Joomla", generator: []string{"joomla"}, scripts: []string{"/media/jui/"}, implies: []string{"PHP"}},
+	{name: "Ghost", generator: []string{"ghost"}, headers: map[string]string{"X-Ghost-Cache-Status": ""}},
+	{name: "Hugo", generator: []string{"hugo"}},
+	{name: "Jekyll", generator: []string{"jekyll"}},
+	{name: "Wix", generator: []string{"wix.com"}, headers: map[string]string{"X-Wix-Request-Id": ""}, scripts: []string{"static.parastorage.com"}},
+	{name: "Squarespace", generator: []string{"squarespace"}, scripts: []string{"static1.squarespace.com"}},
+	{name: "Webflow", generator: []string{"webflow"}, html: []string{"data-wf-page"}},
+	{name: "Shopify", headers: map[string]string{"X-Shopid": "", "X-Shopify-Stage": ""}, scripts: []string{"cdn.shopify.com"}, html: []string{"shopify.theme"}},
+	{name: "Magento", cookies: []string{"mage-"}, scripts: []string{"/static/version", "mage/cookies"}, html: []string{"mage-init"}, implies: []string{"PHP"}},
+
+	// JavaScript frameworks and libraries
+	{name: "Next.js", headers: map[string]string{"X-Powered-By": "next.js"}, scripts: []string{"/_next/"}, html: []string{"__next_data__"}, implies: []string{"React"}},
+	{name: "Nuxt.js", scripts: []string{"/_nuxt/"}, html: []string{"window.__nuxt__"}, implies: []string{"Vue.js"}},
+	{name: "Gatsby", generator: []string{"gatsby"}, html: []string{"id=\"___gatsby\""}, implies: []string{"React"}},
+	{name: "React", scripts: []string{"react.production.min.js", "react-dom"}, html: []string{"data-reactroot"}},
+	{name: "Vue.js", scripts: []string{"vue.min.js", "vue.global"}, html: []string{"data-v-app"}},
+	{name: "Angular", html: []string{"ng-version="}},
+	{name: "Svelte", html: []string{"class=\"svelte-"}},
+	{name: "jQuery", scripts: []string{"jquery"}},
+	{name: "Bootstrap", scripts: []string{"bootstrap"}},
+
+	// Analytics, tags and ads
+	{name: "Google Analytics", scripts: []string{"google-analytics.com/", "googletagmanager.com/gtag/js"}, cookies: []string{"_ga"}},
+	{name: "Google Tag Manager", scripts: []string{"googletagmanager.com/gtm.js"}, html: []string{"googletagmanager.com/ns.html"}},
+	{name: "Google AdSense", scripts: []string{"pagead2.googlesyndication.com"}},
+	{name: "Facebook Pixel", scripts: []string{"connect.facebook.net"}},
+	{name: "Hotjar", scripts: []string{"static.hotjar.com"}},
+	{name: "Matomo", scripts: []string{"matomo.js", "piwik.js"}, cookies: []string{"_pk_id"}},
+	{name: "Plausible", scripts: []string{"plausible.io/js/"}},
+	{name: "HubSpot", scripts: []string{"js.hs-scripts.com", "js.hs-analytics.net"}, cookies: []string{"hubspotutk"}},
+	{name: "Segment", scripts: []string{"cdn.segment.com"}},
+	{name: "Stripe", scripts: []string{"js.stripe.com"}},
+	{name: "reCAPTCHA", scripts: []string{"google.com/recaptcha", "gstatic.com/recaptcha"}},
+
+	// Servers and languages
+	{name: "Nginx", headers: map[string]string{"Server": "nginx"}},
+	{name: "Apache", headers: map[string]string{"Server": "apache"}},
+	{name: "Microsoft IIS", headers: map[string]string{"Server": "microsoft-iis"}},
+	{name: "LiteSpeed", headers: map[string]string{"Server": "litespeed"}},
+	{name: "Caddy", headers: map[string]string{"Server": "caddy"}},
+	{name: "Varnish", headers: map[string]string{"X-Varnish": "", "Via": "varnish"}},
+	{name: "PHP", headers: map[string]string{"X-Powered-By": "php"}, cookies: []string{"phpsessid"}},
+	{name: "ASP.NET", headers: map[string]string{"X-Powered-By": "asp.net", "X-Aspnet-Version": ""}, cookies: []string{"asp.net_sessionid"}},
+	{name: "Express", headers: map[string]string{"X-Powered-By": "express"}, implies: []string{"Node.js"}},
+	{name: "Laravel", cookies: []string{"laravel_session"}, implies: []string{"PHP"}},
+	{name: "Django", cookies: []string{"django_language"}, implies: []string{"Python"}},
+	{name: "Ruby on Rails", headers: map[string]string{"X-Powered-By": "phusion passenger"}, cookies: []string{"_rails_session"}, implies: []string{"Ruby"}},
+	{name: "Java", cookies: []string{"jsessionid"}},
+}
+
+// Names of the technologies the page shows signs of, and those they imply,
+// sorted
+func Detect(page Page) []string {
+	cookies := cookieNames(page.Header)
+	generator := strings.ToLower(page.Generator)
+	scripts := make([]string, len(page.Scripts))
+	for i, script := range page.Scripts {
+		scripts[i] = strings.ToLower(script)
+	}
+	html := strings.ToLower(string(page.HTML))
+
+	found := make(map[string]bool)
+	for _, r := range rules {
+		if r.matches(page.Header, cookies, generator, scripts, html) {
+			found[r.name] = true
+		}
+	}
+
+	// Implications can chain: WooCommerce -> WordPress -> PHP
+	for changed := true; changed; {
+		changed = false
+		for _, r := range rules {
+			if !found[r.name] {
+				continue
+			}
+			for _, implied := range r.implies {
+				if !found[implied] {
+					found[implied] = true
+					changed = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r rule) matches(header http.Header, cookies []string, generator string, scripts []string, html string) bool {
+	for name, pattern := range r.headers {
+		if value := header.Get(name); value != "" && strings.Contains(strings.ToLower(value), pattern) {
+			return true
+		}
+	}
+	for _, prefix := range r.cookies {
+		for _, cookie := range cookies {
+			if strings.HasPrefix(cookie, prefix) {
+				return true
+			}
+		}
+	}
+	if generator != "" {
+		for _, pattern := range r.generator {
+			if strings.Contains(generator, pattern) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range r.scripts {
+		for _, script := range scripts {
+			if strings.Contains(script, pattern) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range r.html {
+		if strings.Contains(html, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lowercased names of the cookies the response sets
+func cookieNames(header http.Header) []string {
+	var names []string
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		names = append(names, strings.ToLower(cookie.Name))
+	}
+	return names
+}
@@ -0,0 +1,148 @@
+package urllist
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// A list of URLs for allow and deny rules. Each entry is an exact URL, a
+// prefix (ending in "*") or a regular expression (prefixed with "re:").
+// Exact and prefix entries, and the URLs matched against them, are
+// normalized first, so case, default ports, fragments, trailing slashes and
+// query order make no difference.
+type List struct {
+	exact    map[string]bool
+	prefixes []string
+	patterns []*regexp.Regexp
+}
+
+// Returns an empty list for entries added with Add
+func New() *List {
+	return &List{exact: make(map[string]bool)}
+}
+
+// Reads a list file with one entry per line, e.g.:
+//
+//	https://example.com/about
+//	https://example.com/private/*
+//	re:^https://example\.com/.*[?&]session=
+//
+// Blank lines and lines starting with # are ignored.
+func Load(filename string) (*List, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open URL list: %w", err)
+	}
+	defer file.Close()
+
+	list := New()
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := list.Add(line); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, lineNum, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read URL list: %w", err)
+	}
+	return list, nil
+}
+
+// Adds one entry in the file syntax
+func (l *List) Add(entry string) error {
+	switch {
+	case strings.HasPrefix(entry, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(entry, "re:"))
+		if err != nil {
+			return fmt.Errorf("invalid regular expression %q: %w", entry, err)
+		}
+		l.patterns = append(l.patterns, re)
+	case strings.HasSuffix(entry, "*"):
+		l.prefixes = append(l.prefixes, normalize(strings.TrimSuffix(entry, "*"), false))
+	default:
+		if l.exact == nil {
+			l.exact = make(map[string]bool)
+		}
+		l.exact[normalize(entry, true)] = true
+	}
+	return nil
+}
+
+// Reports whether any entry matches the URL. Regular expressions are tried
+// on both the URL as given and its normalized form.
+func (l *List) Match(rawURL string) bool {
+	if l.exact[normalize(rawURL, true)] {
+		return true
+	}
+	normalized := normalize(rawURL, false)
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(normalized, prefix) {
+			return true
+		}
+	}
+	for _, re := range l.patterns {
+		if re.MatchString(rawURL) || re.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lowercases the scheme and host, drops the default port, the fragment and
+// an empty query, and sorts the query parameters. With trimSlash, a
+// trailing slash is dropped from the path too ("/" for an empty one).
+// Strings that are not absolute URLs are returned unchanged.
+func normalize(rawURL string, trimSlash bool) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return rawURL
+	}
+
+	scheme := strings.ToLower(parsedURL.Scheme)
+	host := strings.ToLower(parsedURL.Hostname())
+	port := parsedURL.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	path := parsedURL.EscapedPath()
+	if trimSlash {
+		path = strings.TrimSuffix(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	normalized := scheme + "://" + host + path
+	if parsedURL.RawQuery != "" {
+		if query, err := url.ParseQuery(parsedURL.RawQuery); err == nil {
+			normalized += "?" + query.Encode()
+		} else {
+			normalized += "?" + parsedURL.RawQuery
+		}
+	}
+	return normalized
+}
+
+// Number of entries
+func (l *List) Len() int {
+	return len(l.exact) + len(l.prefixes) + len(l.patterns)
+}
@@ -0,0 +1,101 @@
+package urlpolicy
+
+import (
+	"net/url"
+	"strings"
+)
+
+// File extensions that are not worth fetching by default
+var DefaultSkipExtensions = []string{
+	".pdf", ".jpg", ".jpeg", ".png", ".gif", ".css", ".js",
+	".ico", ".svg", ".xml", ".json", ".mp3", ".mp4", ".avi",
+	".mov", ".mpg", ".mpeg", ".zip", ".tar", ".gz", ".rar",
+}
+
+// Image extensions, which are fetched when assets are being saved
+var ImageExtensions = []string{".jpg", ".jpeg", ".png", ".gif", ".svg", ".ico"}
+
+// Substrings identifying URLs that are not content pages by default
+var DefaultSkipPatterns = []string{
+	"/cdn-cgi/", "/wp-admin/", "/wp-includes/",
+	"javascript:", "mailto:", "tel:", "sms:",
+	"/feed/", "/rss/", "/print/", "/search?",
+	"/login", "/logout", "/signin", "/signup", "/register",
+}
+
+// Decides which discovered URLs are skipped before they reach the frontier
+type Policy struct {
+	skipExtensions []string
+	skipPatterns   []string
+}
+
+func New(skipExtensions, skipPatterns []string) *Policy {
+	return &Policy{
+		skipExtensions: lower(skipExtensions),
+		skipPatterns:   lower(skipPatterns),
+	}
+}
+
+func Default() *Policy {
+	return New(DefaultSkipExtensions, DefaultSkipPatterns)
+}
+
+// Reports whether a URL should not be crawled. Extensions are matched against
+// the end of the URL path, patterns anywhere in the URL (case-insensitively).
+func (p *Policy) Skip(rawURL string) bool {
+	lowercaseURL := strings.ToLower(rawURL)
+
+	path := lowercaseURL
+	if parsedURL, err := url.Parse(lowercaseURL); err == nil {
+		path = parsedURL.Path
+	}
+
+	for _, ext := range p.skipExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+
+	for _, pattern := range p.skipPatterns {
+		if strings.Contains(lowercaseURL, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the URLs that are not skipped
+func (p *Policy) Filter(urls []string) []string {
+	kept := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if !p.Skip(u) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// Returns list without the given entries
+func Without(list []string, remove ...string) []string {
+	removed := make(map[string]bool, len(remove))
+	for _, r := range remove {
+		removed[strings.ToLower(r)] = true
+	}
+
+	kept := make([]string, 0, len(list))
+	for _, item := range list {
+		if !removed[strings.ToLower(item)] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func lower(list []string) []string {
+	lowered := make([]string, len(list))
+	for i, item := range list {
+		lowered[i] = strings.ToLower(item)
+	}
+	return lowered
+}
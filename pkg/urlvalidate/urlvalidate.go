@@ -0,0 +1,251 @@
+package urlvalidate
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+type Mode int
+
+const (
+	// URLs are used as found
+	Off Mode = iota
+	// Violations of RFC 3986 are repaired where possible
+	Repair
+	// URLs violating RFC 3986 are rejected
+	Strict
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(s) {
+	case "", "off":
+		return Off, nil
+	case "repair":
+		return Repair, nil
+	case "strict":
+		return Strict, nil
+	default:
+		return Off, fmt.Errorf("unknown URL validation mode %q (want off, repair or strict)", s)
+	}
+}
+
+// Actions recorded in the validator statistics
+const (
+	ActionTrimWhitespace = "trim-whitespace"
+	ActionPercentEncode  = "percent-encode"
+	ActionFixPercent     = "fix-percent-escape"
+	ActionAddScheme      = "add-scheme"
+	ActionLowercaseHost  = "lowercase-host"
+	ActionIDNAHost       = "idna-host"
+	ActionInvalidHost    = "invalid-host"
+	ActionUnparseable    = "unparseable"
+)
+
+// Counts of repaired and rejected URLs by action
+type Stats struct {
+	Repaired map[string]int
+	Rejected map[string]int
+}
+
+// Checks URL references (absolute or relative) against the RFC 3986 syntax.
+// Safe for concurrent use.
+type Validator struct {
+	mode     Mode
+	mutex    sync.Mutex
+	repaired map[string]int
+	rejected map[string]int
+}
+
+func New(mode Mode) *Validator {
+	return &Validator{
+		mode:     mode,
+		repaired: make(map[string]int),
+		rejected: make(map[string]int),
+	}
+}
+
+func (v *Validator) Mode() Mode {
+	return v.mode
+}
+
+// Returns the reference to use and whether it is usable. baseScheme is the
+// scheme of the page the reference was found on and is added to
+// protocol-relative references such as //cdn.example.com/lib.js.
+//
+// Adding the scheme, lowercasing the scheme and host (both case-insensitive
+// in RFC 3986) and IDNA-encoding internationalized hosts only normalize a
+// valid reference, so Strict mode applies them too; it rejects references
+// that need whitespace trimmed or characters percent-encoded.
+func (v *Validator) Check(ref, baseScheme string) (string, bool) {
+	if v == nil || v.mode == Off {
+		return ref, true
+	}
+
+	var actions, normalized []string
+	fixed := ref
+
+	if trimmed := strings.TrimSpace(fixed); trimmed != fixed {
+		fixed = trimmed
+		actions = append(actions, ActionTrimWhitespace)
+	}
+
+	if strings.HasPrefix(fixed, "//") && baseScheme != "" {
+		fixed = baseScheme + ":" + fixed
+		normalized = append(normalized, ActionAddScheme)
+	}
+
+	fixed, idnaAction := encodeHost(fixed)
+	switch idnaAction {
+	case ActionIDNAHost:
+		normalized = append(normalized, idnaAction)
+	case ActionInvalidHost:
+		actions = append(actions, idnaAction)
+	}
+
+	encoded, encodeActions := encodeInvalid(fixed)
+	fixed = encoded
+	actions = append(actions, encodeActions...)
+
+	parsedURL, err := url.Parse(fixed)
+	if err != nil {
+		v.record(v.rejected, ActionUnparseable)
+		return "", false
+	}
+
+	if host := strings.ToLower(parsedURL.Host); host != parsedURL.Host || strings.ToLower(parsedURL.Scheme) != parsedURL.Scheme {
+		parsedURL.Host = host
+		parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+		fixed = parsedURL.String()
+		normalized = append(normalized, ActionLowercaseHost)
+	}
+
+	if len(actions) == 0 && len(normalized) == 0 {
+		return ref, true
+	}
+
+	if v.mode == Strict && len(actions) > 0 {
+		v.record(v.rejected, actions...)
+		return "", false
+	}
+
+	v.record(v.repaired, append(actions, normalized...)...)
+	return fixed, true
+}
+
+// Converts an internationalized host in an absolute or network-path
+// reference to its ASCII (punycode) form. Returns ActionIDNAHost when it
+// did, or ActionInvalidHost when the host isn't a valid domain name and is
+// left for percent-encoding.
+func encodeHost(ref string) (string, string) {
+	start := strings.Index(ref, "//")
+	if start < 0 || strings.ContainsAny(ref[:start], "/?#") {
+		return ref, ""
+	}
+	start += 2
+	end := len(ref)
+	if i := strings.IndexAny(ref[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+
+	authority := ref[start:end]
+	hostStart := strings.LastIndex(authority, "@") + 1
+	host := authority[hostStart:]
+	port := ""
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasPrefix(host, "[") {
+		host, port = host[:i], host[i:]
+	}
+	if isASCII(host) {
+		return ref, ""
+	}
+
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return ref, ActionInvalidHost
+	}
+	return ref[:start] + authority[:hostStart] + ascii + port + ref[end:], ActionIDNAHost
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *Validator) Stats() Stats {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	stats := Stats{
+		Repaired: make(map[string]int, len(v.repaired)),
+		Rejected: make(map[string]int, len(v.rejected)),
+	}
+	for action, count := range v.repaired {
+		stats.Repaired[action] = count
+	}
+	for action, count := range v.rejected {
+		stats.Rejected[action] = count
+	}
+	return stats
+}
+
+func (v *Validator) record(counts map[string]int, actions ...string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	for _, action := range actions {
+		counts[action]++
+	}
+}
+
+// Percent-encodes bytes outside the RFC 3986 character set and '%' signs
+// that don't start a valid escape
+func encodeInvalid(s string) (string, []string) {
+	var sb strings.Builder
+	encodedChars, fixedPercent := false, false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '%':
+			if i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+				sb.WriteByte(ch)
+			} else {
+				sb.WriteString("%25")
+				fixedPercent = true
+			}
+		case isAllowed(ch):
+			sb.WriteByte(ch)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", ch)
+			encodedChars = true
+		}
+	}
+
+	var actions []string
+	if encodedChars {
+		actions = append(actions, ActionPercentEncode)
+	}
+	if fixedPercent {
+		actions = append(actions, ActionFixPercent)
+	}
+	return sb.String(), actions
+}
+
+// Unreserved, reserved (gen-delims and sub-delims) characters
+func isAllowed(ch byte) bool {
+	switch {
+	case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("-._~:/?#[]@!$&'()*+,;=", ch) >= 0
+}
+
+func isHex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/user/gocrawler/pkg/crawler"
+	"github.com/user/gocrawler/pkg/estimate"
+	"github.com/user/gocrawler/pkg/frontier"
+)
+
+// URL patterns listed in a plan before the rest are summed up as "other"
+const planPatterns = 15
+
+// Previews a crawl before running it: fetches only the seed pages and the
+// seed hosts' /sitemap.xml, runs the URLs found there (and those of any
+// -estimate-from sources) through the crawl's scope checks, and prints the
+// hosts and URL patterns in scope with the cost estimate. Drains the
+// crawler's frontier, so it can't be started after.
+func printPlan(w io.Writer, c *crawler.Crawler, plan estimate.Plan, sources []string, userAgent string) error {
+	previews := c.PreviewSeeds(plan.SeedURLs)
+
+	var in estimate.Input
+	var known []string
+	fmt.Fprintln(w, "Seeds:")
+	for _, preview := range previews {
+		if preview.Reason != "" {
+			fmt.Fprintf(w, "  skip (%s)\t%s\n", preview.Reason, preview.URL)
+			continue
+		}
+		fmt.Fprintf(w, "  fetched\t%s (%d links, %d bytes in %v)\n",
+			preview.URL, len(preview.Links), preview.Size, preview.FetchTime.Round(time.Millisecond))
+		known = append(known, preview.Links...)
+		in.PageSizes = append(in.PageSizes, preview.Size)
+		in.FetchTimes = append(in.FetchTimes, preview.FetchTime)
+	}
+
+	client := &http.Client{Timeout: plan.Timeout}
+	fmt.Fprintln(w, "Sitemaps:")
+	for _, sitemap := range seedSitemaps(plan.SeedURLs) {
+		urls, err := estimate.LoadSitemap(sitemap, client, userAgent)
+		if err != nil {
+			fmt.Fprintf(w, "  none\t%s (%v)\n", sitemap, err)
+			continue
+		}
+		fmt.Fprintf(w, "  read\t%s (%d URLs)\n", sitemap, len(urls))
+		known = append(known, urls...)
+	}
+
+	sourceURLs, sizes, err := loadKnownURLs(sources, plan.Timeout, userAgent)
+	if err != nil {
+		return err
+	}
+	known = append(known, sourceURLs...)
+	in.PageSizes = append(in.PageSizes, sizes...)
+
+	skipped := make(map[string]int)
+	discovered := 0
+	for _, decision := range c.DryRun(known) {
+		if decision.Reason == string(frontier.RejectDuplicate) || decision.Reason == string(frontier.RejectNormalizedDupe) {
+			continue
+		}
+		discovered++
+		if decision.Reason != "" {
+			skipped[decision.Reason]++
+			continue
+		}
+		in.URLs = append(in.URLs, decision.URL)
+	}
+
+	fmt.Fprintf(w, "\nIn scope: %d of %d URLs found", len(in.URLs), discovered)
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for i, reason := range reasons {
+		separator := ", "
+		if i == 0 {
+			separator = "; skipped: "
+		}
+		fmt.Fprintf(w, "%s%d %s", separator, skipped[reason], reason)
+	}
+	fmt.Fprintln(w)
+
+	hosts := make(map[string]int)
+	for _, rawURL := range in.URLs {
+		hosts[plan.Sites.KeyOfURL(rawURL)]++
+	}
+	hostNames := make([]string, 0, len(hosts))
+	for host := range hosts {
+		hostNames = append(hostNames, host)
+	}
+	sort.Slice(hostNames, func(i, j int) bool {
+		if hosts[hostNames[i]] != hosts[hostNames[j]] {
+			return hosts[hostNames[i]] > hosts[hostNames[j]]
+		}
+		return hostNames[i] < hostNames[j]
+	})
+	fmt.Fprintln(w, "Hosts:")
+	for _, host := range hostNames {
+		fmt.Fprintf(w, "  %6d  %s\n", hosts[host], host)
+	}
+	fmt.Fprintln(w, "URL patterns:")
+	for _, pattern := range estimate.Patterns(in.URLs, planPatterns) {
+		fmt.Fprintf(w, "  %6d  %s\n", pattern.URLs, pattern.Pattern)
+	}
+	fmt.Fprintln(w)
+
+	est := estimate.Compute(plan, in)
+	if len(in.URLs) > 0 && (plan.MaxPages <= 0 || len(in.URLs) < plan.MaxPages) {
+		est.Assumptions = append(est.Assumptions, "only URLs linked from the seeds or listed in sitemaps are counted; deeper pages may add more")
+	}
+	est.Print(w)
+	return nil
+}
+
+// The /sitemap.xml of each seed's origin
+func seedSitemaps(seedURLs []string) []string {
+	var sitemaps []string
+	seen := make(map[string]bool)
+	for _, seedURL := range seedURLs {
+		parsedURL, err := url.Parse(seedURL)
+		if err != nil || parsedURL.Host == "" {
+			continue
+		}
+		sitemap := parsedURL.Scheme + "://" + parsedURL.Host + "/sitemap.xml"
+		if !seen[sitemap] {
+			seen[sitemap] = true
+			sitemaps = append(sitemaps, sitemap)
+		}
+	}
+	return sitemaps
+}
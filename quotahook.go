@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/user/gocrawler/pkg/crawler"
+)
+
+// POSTs a JSON notice to a URL whenever a host uses up its quota
+type quotaHook struct {
+	url    string
+	runID  string
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+type quotaNotice struct {
+	Host  string    `json:"host"`
+	Quota string    `json:"quota"`
+	URL   string    `json:"url"`
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id,omitempty"`
+}
+
+func newQuotaHook(url, runID string) *quotaHook {
+	return &quotaHook{url: url, runID: runID, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Config.OnEvent handler; sends in the background so workers don't wait
+func (h *quotaHook) onEvent(event crawler.Event) {
+	if event.Type != crawler.EventQuotaReached {
+		return
+	}
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.send(quotaNotice{Host: event.Host, Quota: event.Quota, URL: event.URL, Time: event.Time, RunID: h.runID}); err != nil {
+			log.Printf("Failed to send quota notice for %s: %v", event.Host, err)
+		}
+	}()
+}
+
+func (h *quotaHook) send(notice quotaNotice) error {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("failed to encode quota notice: %w", err)
+	}
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Waits for the notices still being sent
+func (h *quotaHook) wait() {
+	h.wg.Wait()
+}
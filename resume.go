@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+// Settings of the earlier run that a resumed crawl doesn't take over
+var notResumed = map[string]bool{
+	"output": true,
+	"run-id": true,
+}
+
+// Prepares a resumed crawl from the outputs of the earlier run. Flags not
+// given on the command line take the values recorded in the first output's
+// metadata, the output goes to a new "-resumed" file named after the last
+// output, and the records of all the outputs are returned.
+func loadPreviousRun(fs *flag.FlagSet, outputs []string) ([]storage.PageData, error) {
+	meta, err := storage.ReadRunMetadata(outputs[0])
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var skipped []string
+	for name, value := range meta.Config {
+		f := fs.Lookup(name)
+		if f == nil || explicit[name] || notResumed[name] || value == f.DefValue {
+			continue
+		}
+		// Repeated flags are recorded joined with commas, which values
+		// like User-Agents contain, and secrets are redacted
+		if _, repeatable := f.Value.(*stringList); repeatable || value == "<redacted>" {
+			skipped = append(skipped, "-"+name)
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, fmt.Errorf("-%s from the earlier run: %w", name, err)
+		}
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		log.Printf("Not restored from the earlier run, give them again if needed: %v", skipped)
+	}
+
+	if !explicit["output"] {
+		fs.Set("output", taggedFilename(outputs[len(outputs)-1], "resumed"))
+	}
+
+	var records []storage.PageData
+	for _, output := range outputs {
+		outputRecords, err := storage.ReadRecords(output)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, outputRecords...)
+	}
+	return records, nil
+}
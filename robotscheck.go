@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/user/gocrawler/pkg/robotstxt"
+)
+
+// Checks URLs against a robots.txt the way the crawler does, printing each
+// decision with the rule that made it
+func runRobotsCheck(args []string) {
+	fs := flag.NewFlagSet("robots-check", flag.ExitOnError)
+	userAgent := fs.String("agent", "GoCrawler/1.0", "User-Agent whose rules to apply, and to send when fetching robots.txt")
+	urlsFile := fs.String("urls", "", "File of URLs or paths to check, one per line")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler robots-check [flags] <robots.txt URL or file> [url or path]...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	urls := fs.Args()[1:]
+	if *urlsFile != "" {
+		data, err := os.ReadFile(*urlsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no URLs to check")
+		os.Exit(1)
+	}
+
+	rules, err := loadRobots(fs.Arg(0), *userAgent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Crawl-delay: %v\n", rules.CrawlDelay())
+
+	for _, rawURL := range urls {
+		path := rawURL
+		if parsed, err := url.Parse(rawURL); err == nil && parsed.IsAbs() {
+			path = parsed.Path
+		}
+		if path == "" {
+			path = "/"
+		}
+
+		allowed, rule := rules.Match(path, *userAgent)
+		decision := "deny"
+		if allowed {
+			decision = "allow"
+		}
+		reason := "no matching rule"
+		if rule != nil {
+			reason = rule.String()
+		}
+		fmt.Printf("%-5s  %s  %s\n", decision, rawURL, reason)
+	}
+}
+
+// Reads robots.txt from a file or fetches it. Like the crawler, a robots.txt
+// that is missing or can't be fetched allows everything.
+func loadRobots(source, userAgent string) (*robotstxt.RobotsData, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+		}
+		return robotstxt.Parse(string(data)), nil
+	}
+
+	req, err := http.NewRequest("GET", source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Printf("robots.txt answered %d: everything is allowed\n", resp.StatusCode)
+		return robotstxt.Parse(""), nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+	return robotstxt.Parse(string(body)), nil
+}
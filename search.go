@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/user/gocrawler/pkg/search"
+)
+
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	indexPath := fs.String("index", "results.bleve", "Search index written with -format bleve")
+	limit := fs.Int("limit", 10, "Maximum number of results")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler search [-index results.bleve] [-limit 10] <query>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	index, err := search.Open(*indexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer index.Close()
+
+	hits, total, err := index.Search(strings.Join(fs.Args(), " "), *limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d matching pages\n", total)
+	for i, hit := range hits {
+		fmt.Printf("\n%d. %s (score %.3f)\n   %s\n", i+1, hit.Title, hit.Score, hit.URL)
+		if hit.Fragment != "" {
+			fmt.Printf("   %s\n", hit.Fragment)
+		}
+	}
+}
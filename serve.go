@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/user/gocrawler/pkg/crawlpb"
+	"github.com/user/gocrawler/pkg/daemon"
+	"github.com/user/gocrawler/pkg/robotstxt"
+)
+
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8090", "Address for the job API, which has no authentication; local-only by default")
+	outputDir := fs.String("output-dir", "jobs-output", "Directory for job output files")
+	jobsDir := fs.String("jobs-dir", "", "Directory polled for *.json job specs (disabled when empty)")
+	pollInterval := durationFlag(fs, "poll-interval", 5*time.Second, "How often to poll the jobs directory")
+	concurrency := fs.Int("concurrency", 2, "Number of jobs to run at the same time")
+	grpcListen := fs.String("grpc-listen", "", "Address for the gRPC job API (disabled when empty)")
+	robotsCacheDir := fs.String("robots-cache", "", "Directory to keep robots.txt rules in, shared by all jobs and kept across restarts")
+	robotsCacheTTL := durationFlag(fs, "robots-cache-ttl", 24*time.Hour, "How long robots.txt rules are used before being fetched again")
+	robotsCacheSize := fs.Int("robots-cache-size", robotstxt.DefaultMaxEntries, "With -robots-cache, the maximum number of hosts kept in memory, least recently used evicted first (0 = no limit)")
+	aboutListen := fs.String("about-listen", "", "Address for the public /about page, kept apart from the job API (disabled when empty)")
+	operator := fs.String("operator", "", "Name of the organization running the crawls, shown on /about")
+	from := fs.String("from", "", "Contact e-mail sent as the From header with every request and shown on /about")
+	crawlInfoURL := fs.String("crawl-info-url", "", "URL about the crawls added to every User-Agent as \"(+URL)\", e.g. this server's public /about page")
+	reloadFile := fs.String("reload-file", "", "JSON file of settings (delay, workers, filter, verbose) applied to all running jobs on SIGHUP")
+	fs.Parse(args)
+
+	manager, err := daemon.NewManager(*outputDir, *concurrency)
+	if err != nil {
+		log.Fatalf("Failed to start daemon: %v", err)
+	}
+
+	if *robotsCacheDir != "" {
+		robots, err := robotstxt.NewDiskCache(*robotsCacheDir, *robotsCacheTTL)
+		if err != nil {
+			log.Fatalf("Failed to open robots cache: %v", err)
+		}
+		robots.SetMaxEntries(*robotsCacheSize)
+		manager.SetRobotsCache(robots)
+	}
+
+	if err := validateIdentity(*from, *crawlInfoURL); err != nil {
+		log.Fatalf("Invalid %v", err)
+	}
+	manager.SetIdentity(daemon.Identity{Operator: *operator, From: *from, InfoURL: *crawlInfoURL})
+
+	if *jobsDir != "" {
+		go manager.WatchDir(*jobsDir, *pollInterval)
+	}
+
+	server := &http.Server{
+		Addr:    *listen,
+		Handler: manager.Handler(),
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Job API error: %v", err)
+		}
+	}()
+	fmt.Printf("Crawl daemon listening on %s, writing output to %s\n", *listen, *outputDir)
+
+	var aboutServer *http.Server
+	if *aboutListen != "" {
+		aboutServer = &http.Server{
+			Addr:    *aboutListen,
+			Handler: manager.AboutHandler(),
+		}
+		go func() {
+			if err := aboutServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("About page error: %v", err)
+			}
+		}()
+		fmt.Printf("About page listening on %s\n", *aboutListen)
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcListen != "" {
+		listener, err := net.Listen("tcp", *grpcListen)
+		if err != nil {
+			log.Fatalf("Failed to listen for gRPC: %v", err)
+		}
+
+		grpcServer = grpc.NewServer()
+		crawlpb.RegisterCrawlerServer(grpcServer, manager.GRPCServer())
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("gRPC job API error: %v", err)
+			}
+		}()
+		fmt.Printf("gRPC job API listening on %s\n", *grpcListen)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	sig := <-sigChan
+	for sig == syscall.SIGHUP {
+		if *reloadFile == "" {
+			fmt.Println("Received SIGHUP but no -reload-file is set, ignoring")
+		} else if reload, err := readReloadFile(*reloadFile); err != nil {
+			log.Printf("Reload failed: %v", err)
+		} else {
+			count := manager.ReloadAll(reload)
+			fmt.Printf("Reloaded settings for %d running jobs: %v\n", count, reload)
+		}
+		sig = <-sigChan
+	}
+	fmt.Printf("\nReceived signal %v, shutting down...\n", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	if aboutServer != nil {
+		aboutServer.Shutdown(ctx)
+	}
+	// Stopping the jobs first ends any open result streams
+	manager.Shutdown()
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Crawls a site and writes its pages as an XML sitemap
+func runSitemap(args []string) {
+	fs := flag.NewFlagSet("sitemap", flag.ExitOnError)
+	depth := fs.Int("depth", 5, "Maximum crawl depth")
+	maxPages := fs.Int("max", 5000, "Maximum number of pages to crawl")
+	workers := fs.Int("workers", 2, "Number of concurrent workers")
+	delay := durationFlag(fs, "delay", time.Second, "Delay between requests to the same host (e.g. 500ms, 2s; bare numbers are seconds)")
+	output := fs.String("output", "sitemap.xml", "Sitemap file; large sites get numbered files and a sitemap index")
+	baseURL := fs.String("base-url", "", "Public URL the sitemap files will be served from, used in sitemap indexes")
+	userAgent := fs.String("agent", "GoCrawler/1.0", "User-Agent string")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler sitemap [flags] <url>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	runCrawl("crawl", []string{
+		"-seed", fs.Arg(0),
+		"-depth", strconv.Itoa(*depth),
+		"-max", strconv.Itoa(*maxPages),
+		"-workers", strconv.Itoa(*workers),
+		"-delay", delay.String(),
+		"-agent", *userAgent,
+		"-extract-links",
+		"-format", "sitemap",
+		"-output", *output,
+		"-sitemap-base-url", *baseURL,
+	})
+}
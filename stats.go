@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/user/gocrawler/pkg/report"
+	"github.com/user/gocrawler/pkg/storage"
+)
+
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gocrawler stats <results.json>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	pages, err := storage.ReadRecords(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The metadata is missing for outputs of older versions
+	if meta, err := storage.ReadRunMetadata(fs.Arg(0)); err == nil {
+		fmt.Printf("Run %s: %s to %s (%v)\n", meta.RunID,
+			meta.StartTime.Format("2006-01-02 15:04:05"), meta.EndTime.Format("2006-01-02 15:04:05"),
+			meta.EndTime.Sub(meta.StartTime).Round(time.Second))
+	}
+
+	if err := report.BuildStats(pages).Write(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}